@@ -0,0 +1,155 @@
+// Package character keeps the player character sheets stored in the characters table.
+package character
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrNotFound is returned by a Store when a player has no character in a game
+var ErrNotFound = errors.New("character not found")
+
+// Validation limits
+const (
+	MinAttribute  = 1
+	MaxAttribute  = 30
+	MinLevel      = 1
+	MaxLevel      = 20
+	MaxNameLength = 64
+	MaxItems      = 50
+	MaxItemLength = 100
+
+	// maxHitDie is the largest hit die a class can have, it bounds MaxHP per level
+	maxHitDie = 12
+)
+
+// Attributes are the six ability scores of a character
+type Attributes struct {
+	Strength     int `json:"str"`
+	Dexterity    int `json:"dex"`
+	Constitution int `json:"con"`
+	Intelligence int `json:"int"`
+	Wisdom       int `json:"wis"`
+	Charisma     int `json:"cha"`
+}
+
+// DefaultAttributes returns an average stat block
+func DefaultAttributes() Attributes {
+	return Attributes{10, 10, 10, 10, 10, 10}
+}
+
+// Modifier returns the ability modifier of a score
+func Modifier(score int) int {
+	// Floor division, so 9 gives -1 and not 0
+	if score < 10 {
+		return (score - 11) / 2
+	}
+	return (score - 10) / 2
+}
+
+// Stats is the part of a character stored in the stats JSONB column
+type Stats struct {
+	Level      int        `json:"level"`
+	HP         int        `json:"hp"`
+	MaxHP      int        `json:"max_hp"`
+	Attributes Attributes `json:"attributes"`
+	Inventory  []string   `json:"inventory,omitempty"`
+	Conditions []string   `json:"conditions,omitempty"`
+}
+
+// Character is a player character of a game
+type Character struct {
+	ID     string
+	GameID string
+	UserID int64
+	Name   string
+	Stats
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// New creates a level 1 character with full hit points
+func New(gameID string, userID int64, name string, attributes Attributes) *Character {
+	maxHP := max(10+Modifier(attributes.Constitution), 1)
+	return &Character{
+		GameID: gameID,
+		UserID: userID,
+		Name:   strings.TrimSpace(name),
+		Stats: Stats{
+			Level:      1,
+			HP:         maxHP,
+			MaxHP:      maxHP,
+			Attributes: attributes,
+		},
+	}
+}
+
+// Validate rejects stat blocks that cannot exist in the game
+func (c *Character) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("character name is required")
+	}
+	if utf8.RuneCountInString(c.Name) > MaxNameLength {
+		return fmt.Errorf("character name is longer than %d characters", MaxNameLength)
+	}
+	if c.Level < MinLevel || c.Level > MaxLevel {
+		return fmt.Errorf("level must be between %d and %d", MinLevel, MaxLevel)
+	}
+
+	for _, attr := range c.Attributes.list() {
+		if attr.value < MinAttribute || attr.value > MaxAttribute {
+			return fmt.Errorf("%s must be between %d and %d", attr.name, MinAttribute, MaxAttribute)
+		}
+	}
+
+	// Every level adds at most one maximum hit die plus the constitution modifier
+	limit := c.Level * max(maxHitDie+Modifier(c.Attributes.Constitution), 1)
+	if c.MaxHP < 1 || c.MaxHP > limit {
+		return fmt.Errorf("max HP must be between 1 and %d at level %d", limit, c.Level)
+	}
+	if c.HP < 0 || c.HP > c.MaxHP {
+		return fmt.Errorf("HP must be between 0 and max HP %d", c.MaxHP)
+	}
+
+	if len(c.Inventory) > MaxItems {
+		return fmt.Errorf("inventory holds at most %d items", MaxItems)
+	}
+	for _, list := range [][]string{c.Inventory, c.Conditions} {
+		for _, item := range list {
+			if strings.TrimSpace(item) == "" || utf8.RuneCountInString(item) > MaxItemLength {
+				return fmt.Errorf("item %q must be 1 to %d characters long", item, MaxItemLength)
+			}
+		}
+	}
+	return nil
+}
+
+type namedAttribute struct {
+	name  string
+	value int
+}
+
+func (a Attributes) list() []namedAttribute {
+	return []namedAttribute{
+		{"str", a.Strength},
+		{"dex", a.Dexterity},
+		{"con", a.Constitution},
+		{"int", a.Intelligence},
+		{"wis", a.Wisdom},
+		{"cha", a.Charisma},
+	}
+}
+
+// Store persists characters, one per player in a game
+type Store interface {
+	// Get returns the character of a player or ErrNotFound
+	Get(ctx context.Context, gameID string, userID int64) (*Character, error)
+
+	// Save creates or replaces the character of a player and assigns its ID
+	Save(ctx context.Context, c *Character) error
+}
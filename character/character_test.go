@@ -0,0 +1,212 @@
+package character
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/tools"
+)
+
+func TestModifier(t *testing.T) {
+	cases := map[int]int{1: -5, 8: -1, 9: -1, 10: 0, 11: 0, 12: 1, 15: 2, 20: 5, 30: 10}
+	for score, want := range cases {
+		if got := Modifier(score); got != want {
+			t.Errorf("Modifier(%d) = %d, want %d", score, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Character {
+		return New("game", 1, "Арагорн", Attributes{15, 13, 14, 10, 12, 8})
+	}
+
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("expected a new character to be valid: %v", err)
+	}
+
+	invalid := map[string]func(c *Character){
+		"empty name":         func(c *Character) { c.Name = "" },
+		"long name":          func(c *Character) { c.Name = strings.Repeat("я", MaxNameLength+1) },
+		"level 0":            func(c *Character) { c.Level = 0 },
+		"level 21":           func(c *Character) { c.Level = 21 },
+		"strength 0":         func(c *Character) { c.Attributes.Strength = 0 },
+		"charisma 31":        func(c *Character) { c.Attributes.Charisma = 31 },
+		"hp above max":       func(c *Character) { c.HP = c.MaxHP + 1 },
+		"negative hp":        func(c *Character) { c.HP = -1 },
+		"max hp above level": func(c *Character) { c.MaxHP = 100 },
+		"blank item":         func(c *Character) { c.Inventory = []string{" "} },
+		"too many items":     func(c *Character) { c.Inventory = make([]string, MaxItems+1) },
+	}
+	for name, mutate := range invalid {
+		t.Run(name, func(t *testing.T) {
+			c := valid()
+			mutate(c)
+			if err := c.Validate(); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+}
+
+func TestParseNew(t *testing.T) {
+	t.Run("name and scores", func(t *testing.T) {
+		name, attrs, err := ParseNew("Гэндальф Серый int=18 WIS=16")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if name != "Гэндальф Серый" {
+			t.Errorf("unexpected name %q", name)
+		}
+		if attrs.Intelligence != 18 || attrs.Wisdom != 16 || attrs.Strength != 10 {
+			t.Errorf("unexpected attributes %+v", attrs)
+		}
+	})
+
+	t.Run("unknown attribute", func(t *testing.T) {
+		if _, _, err := ParseNew("Гимли luck=3"); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("non-numeric score", func(t *testing.T) {
+		if _, _, err := ParseNew("Гимли str=много"); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestEdit(t *testing.T) {
+	c := New("game", 1, "Леголас", DefaultAttributes())
+
+	if err := Edit(c, "hp", "-4"); err != nil || c.HP != 6 {
+		t.Errorf("expected relative damage, got HP %d, err %v", c.HP, err)
+	}
+	if err := Edit(c, "hp", "-40"); err != nil || c.HP != 0 {
+		t.Errorf("expected damage to stop at 0, got HP %d, err %v", c.HP, err)
+	}
+	if err := Edit(c, "hp", "+100"); err != nil || c.HP != c.MaxHP {
+		t.Errorf("expected healing to stop at max HP, got HP %d, err %v", c.HP, err)
+	}
+	if err := Edit(c, "dex", "18"); err != nil || c.Attributes.Dexterity != 18 {
+		t.Errorf("expected dex 18, got %d, err %v", c.Attributes.Dexterity, err)
+	}
+	if err := Edit(c, "+item", "лук"); err != nil || len(c.Inventory) != 1 {
+		t.Errorf("expected item added, got %v, err %v", c.Inventory, err)
+	}
+	if err := Edit(c, "-item", "Лук"); err != nil || len(c.Inventory) != 0 {
+		t.Errorf("expected item removed, got %v, err %v", c.Inventory, err)
+	}
+	if err := Edit(c, "-cond", "отравлен"); err == nil {
+		t.Error("expected error for a missing condition")
+	}
+	if err := Edit(c, "hp", "50"); err == nil {
+		t.Error("expected absolute HP above max to be rejected")
+	}
+	if err := Edit(c, "luck", "3"); err == nil {
+		t.Error("expected error for an unknown field")
+	}
+}
+
+func TestSheet(t *testing.T) {
+	c := New("game", 1, "Боромир", Attributes{16, 10, 14, 9, 10, 12})
+	c.Inventory = []string{"меч", "рог"}
+
+	sheet := Sheet(c)
+	for _, want := range []string{"Боромир, уровень 1", "HP: 12/12", "СИЛ 16 (+3)", "ИНТ 9 (-1)", "меч, рог"} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("expected %q in sheet:\n%s", want, sheet)
+		}
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	if _, err := store.Get(ctx, "game", 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	first := New("game", 1, "Фродо", DefaultAttributes())
+	if err := store.Save(ctx, first); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if first.ID == "" {
+		t.Fatal("expected an ID to be assigned")
+	}
+
+	second := New("game", 1, "Сэм", DefaultAttributes())
+	_ = store.Save(ctx, second)
+	if second.ID != first.ID {
+		t.Error("expected the player's character to be replaced in place")
+	}
+
+	loaded, _ := store.Get(ctx, "game", 1)
+	loaded.Inventory = append(loaded.Inventory, "кольцо")
+	reloaded, _ := store.Get(ctx, "game", 1)
+	if reloaded.Name != "Сэм" || len(reloaded.Inventory) != 0 {
+		t.Errorf("expected an unmodified copy, got %+v", reloaded)
+	}
+}
+
+func TestContextSource(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	source := NewContextSource(store)
+
+	text, err := source.TurnContext(ctx, "game", 1)
+	if err != nil || text != "" {
+		t.Errorf("expected no context without a character, got %q, %v", text, err)
+	}
+
+	c := New("game", 1, "Пиппин", DefaultAttributes())
+	_ = store.Save(ctx, c)
+
+	text, _ = source.TurnContext(ctx, "game", 1)
+	if !strings.Contains(text, "Пиппин") {
+		t.Errorf("expected the sheet in context, got %q", text)
+	}
+	if id := source.CharacterID(ctx, "game", 1); id != c.ID {
+		t.Errorf("expected character ID %q, got %q", c.ID, id)
+	}
+}
+
+func TestInventoryTool(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	c := New("g1", 1, "Арагорн", DefaultAttributes())
+	c.Inventory = []string{"Верёвка 15 м", "Факел"}
+	if err := store.Save(ctx, c); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	registry := tools.NewRegistry()
+	if err := registry.Register(NewInventoryTool(store)); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	call := func(userID int64, arguments string) string {
+		t.Helper()
+		result, err := registry.Call(ctx, tools.Invocation{GameID: "g1", UserID: userID},
+			interfaces.ToolCall{ID: "call_1", Name: InventoryToolName, Arguments: arguments})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result
+	}
+
+	if result := call(1, `{}`); !strings.Contains(result, "Верёвка 15 м, Факел") {
+		t.Errorf("expected the whole inventory, got %q", result)
+	}
+	if result := call(1, `{"item":"верёвка"}`); !strings.Contains(result, "есть: Верёвка 15 м") {
+		t.Errorf("expected the rope found, got %q", result)
+	}
+	if result := call(1, `{"item":"меч"}`); !strings.Contains(result, "нет предмета «меч»") {
+		t.Errorf("expected no sword, got %q", result)
+	}
+	if result := call(2, `{}`); !strings.Contains(result, "нет персонажа") {
+		t.Errorf("expected no character for another player, got %q", result)
+	}
+}
@@ -0,0 +1,69 @@
+package character
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-llm-rpggamemaster/internal/uuid"
+)
+
+// MemoryStore keeps characters in process memory. Characters are lost on restart.
+type MemoryStore struct {
+	mu         sync.RWMutex
+	characters map[memoryKey]*Character
+}
+
+type memoryKey struct {
+	gameID string
+	userID int64
+}
+
+// Compile-time interface check
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty in-memory character store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		characters: make(map[memoryKey]*Character),
+	}
+}
+
+// Get returns a copy of the stored character
+func (s *MemoryStore) Get(ctx context.Context, gameID string, userID int64) (*Character, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, ok := s.characters[memoryKey{gameID, userID}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(stored), nil
+}
+
+// Save stores a copy of the character, keeping the ID of the player's previous character
+func (s *MemoryStore) Save(ctx context.Context, c *Character) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := memoryKey{c.GameID, c.UserID}
+	now := time.Now()
+	if stored, ok := s.characters[key]; ok {
+		c.ID = stored.ID
+		c.CreatedAt = stored.CreatedAt
+	} else {
+		c.ID = uuid.New()
+		c.CreatedAt = now
+	}
+	c.UpdatedAt = now
+
+	s.characters[key] = clone(c)
+	return nil
+}
+
+func clone(c *Character) *Character {
+	copied := *c
+	copied.Inventory = append([]string(nil), c.Inventory...)
+	copied.Conditions = append([]string(nil), c.Conditions...)
+	return &copied
+}
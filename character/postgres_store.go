@@ -0,0 +1,72 @@
+package character
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresStore persists characters in the characters table
+type PostgresStore struct {
+	db *pgxpool.Pool
+}
+
+// Compile-time interface check
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore creates a character store backed by PostgreSQL
+func NewPostgresStore(db *pgxpool.Pool) (*PostgresStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database pool cannot be nil")
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// Get reads the character of a player
+func (s *PostgresStore) Get(ctx context.Context, gameID string, userID int64) (*Character, error) {
+	c := &Character{GameID: gameID, UserID: userID}
+	var stats []byte
+
+	err := s.db.QueryRow(ctx, `
+		SELECT id, name, stats, created_at, updated_at
+		FROM characters
+		WHERE game_id = $1 AND user_id = $2
+	`, gameID, userID).Scan(&c.ID, &c.Name, &stats, &c.CreatedAt, &c.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading character: %w", err)
+	}
+
+	if len(stats) > 0 {
+		if err := json.Unmarshal(stats, &c.Stats); err != nil {
+			return nil, fmt.Errorf("decoding character stats: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Save inserts the character or replaces the player's existing one
+func (s *PostgresStore) Save(ctx context.Context, c *Character) error {
+	stats, err := json.Marshal(c.Stats)
+	if err != nil {
+		return fmt.Errorf("encoding character stats: %w", err)
+	}
+
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO characters (game_id, user_id, name, stats)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (game_id, user_id) DO UPDATE
+		SET name = EXCLUDED.name, stats = EXCLUDED.stats, updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`, c.GameID, c.UserID, c.Name, stats).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("saving character: %w", err)
+	}
+	return nil
+}
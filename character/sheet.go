@@ -0,0 +1,183 @@
+package character
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sheet renders the character sheet shown to players and to the game master
+func Sheet(c *Character) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🧙 %s, уровень %d\n", c.Name, c.Level)
+	fmt.Fprintf(&b, "❤️ HP: %d/%d\n", c.HP, c.MaxHP)
+
+	attrs := c.Attributes.list()
+	for i, attr := range attrs {
+		switch {
+		case i == 3:
+			b.WriteString("\n")
+		case i > 0:
+			b.WriteString(" · ")
+		}
+		fmt.Fprintf(&b, "%s %d (%+d)", attributeLabels[attr.name], attr.value, Modifier(attr.value))
+	}
+
+	if len(c.Inventory) > 0 {
+		fmt.Fprintf(&b, "\n🎒 Инвентарь: %s", strings.Join(c.Inventory, ", "))
+	}
+	if len(c.Conditions) > 0 {
+		fmt.Fprintf(&b, "\n⚠️ Состояния: %s", strings.Join(c.Conditions, ", "))
+	}
+	return b.String()
+}
+
+var attributeLabels = map[string]string{
+	"str": "СИЛ",
+	"dex": "ЛОВ",
+	"con": "ТЕЛ",
+	"int": "ИНТ",
+	"wis": "МДР",
+	"cha": "ХАР",
+}
+
+// attribute returns a pointer to the score named by its short key
+func (a *Attributes) attribute(key string) *int {
+	switch key {
+	case "str":
+		return &a.Strength
+	case "dex":
+		return &a.Dexterity
+	case "con":
+		return &a.Constitution
+	case "int":
+		return &a.Intelligence
+	case "wis":
+		return &a.Wisdom
+	case "cha":
+		return &a.Charisma
+	}
+	return nil
+}
+
+// ParseNew parses the arguments of /newchar: a name followed by optional scores, e.g. "Арагорн str=15 dex=13"
+func ParseNew(args string) (string, Attributes, error) {
+	attributes := DefaultAttributes()
+	var name []string
+	for _, field := range strings.Fields(args) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			name = append(name, field)
+			continue
+		}
+
+		score := attributes.attribute(strings.ToLower(key))
+		if score == nil {
+			return "", Attributes{}, fmt.Errorf("unknown attribute %q", key)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return "", Attributes{}, fmt.Errorf("attribute %s must be a number", key)
+		}
+		*score = n
+	}
+	return strings.Join(name, " "), attributes, nil
+}
+
+// Edit changes a single field of the sheet and validates the result.
+//
+// Supported fields: name, level, hp, maxhp, str, dex, con, int, wis, cha,
+// +item/-item to add or remove an inventory item and +cond/-cond for conditions.
+// A signed hp value such as -3 or +2 is applied relative to the current HP.
+func Edit(c *Character, field, value string) error {
+	field = strings.ToLower(field)
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fmt.Errorf("value for %q is required", field)
+	}
+
+	switch field {
+	case "name":
+		c.Name = value
+	case "+item":
+		c.Inventory = append(c.Inventory, value)
+	case "-item":
+		if !remove(&c.Inventory, value) {
+			return fmt.Errorf("no %q in inventory", value)
+		}
+	case "+cond":
+		c.Conditions = append(c.Conditions, value)
+	case "-cond":
+		if !remove(&c.Conditions, value) {
+			return fmt.Errorf("no condition %q", value)
+		}
+	default:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a number", field)
+		}
+		switch field {
+		case "level":
+			c.Level = n
+		case "maxhp":
+			c.MaxHP = n
+			c.HP = min(c.HP, c.MaxHP)
+		case "hp":
+			if strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-") {
+				// Damage and healing are clamped instead of rejected
+				n = min(max(c.HP+n, 0), c.MaxHP)
+			}
+			c.HP = n
+		default:
+			score := c.Attributes.attribute(field)
+			if score == nil {
+				return fmt.Errorf("unknown field %q", field)
+			}
+			*score = n
+		}
+	}
+	return c.Validate()
+}
+
+func remove(list *[]string, value string) bool {
+	for i, item := range *list {
+		if strings.EqualFold(item, value) {
+			*list = append((*list)[:i], (*list)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ContextSource adds the acting player's character sheet to game-master turns
+type ContextSource struct {
+	store Store
+}
+
+// NewContextSource creates a context source over a character store
+func NewContextSource(store Store) *ContextSource {
+	return &ContextSource{store: store}
+}
+
+// TurnContext returns the sheet of the player's character or an empty string when there is none
+func (s *ContextSource) TurnContext(ctx context.Context, gameID string, userID int64) (string, error) {
+	c, err := s.store.Get(ctx, gameID, userID)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return "Лист персонажа игрока, который сейчас действует:\n" + Sheet(c), nil
+}
+
+// CharacterID returns the ID of the player's character, it is used to tag campaign memory
+func (s *ContextSource) CharacterID(ctx context.Context, gameID string, userID int64) string {
+	c, err := s.store.Get(ctx, gameID, userID)
+	if err != nil {
+		return ""
+	}
+	return c.ID
+}
@@ -0,0 +1,72 @@
+package character
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/tools"
+)
+
+// InventoryToolName is the name of the inventory tool offered to the game master
+const InventoryToolName = "inventory_lookup"
+
+type inventoryArguments struct {
+	Item string `json:"item"`
+}
+
+// NewInventoryTool lets the game master check the acting player's inventory instead of guessing what they carry
+func NewInventoryTool(store Store) tools.Tool {
+	definition := interfaces.ToolDefinition{
+		Name:        InventoryToolName,
+		Description: "Посмотреть инвентарь персонажа игрока, который сейчас действует. Используй, когда игрок достаёт, применяет или отдаёт предмет, чтобы не выдумывать, что у него есть.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"item": map[string]interface{}{
+					"type":        "string",
+					"description": "Предмет, который нужно найти, например «верёвка». Без него возвращается весь инвентарь",
+				},
+			},
+		},
+	}
+
+	return tools.NewFunc(definition, func(ctx context.Context, inv tools.Invocation, arguments json.RawMessage) (string, error) {
+		var args inventoryArguments
+		if len(arguments) > 0 {
+			if err := json.Unmarshal(arguments, &args); err != nil {
+				return "", fmt.Errorf("decoding arguments: %w", err)
+			}
+		}
+
+		c, err := store.Get(ctx, inv.GameID, inv.UserID)
+		if errors.Is(err, ErrNotFound) {
+			return "У игрока нет персонажа, инвентарь неизвестен", nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		item := strings.TrimSpace(args.Item)
+		if item == "" {
+			if len(c.Inventory) == 0 {
+				return fmt.Sprintf("Инвентарь %s пуст", c.Name), nil
+			}
+			return fmt.Sprintf("Инвентарь %s: %s", c.Name, strings.Join(c.Inventory, ", ")), nil
+		}
+
+		var found []string
+		for _, owned := range c.Inventory {
+			if strings.Contains(strings.ToLower(owned), strings.ToLower(item)) {
+				found = append(found, owned)
+			}
+		}
+		if len(found) == 0 {
+			return fmt.Sprintf("У %s нет предмета «%s»", c.Name, item), nil
+		}
+		return fmt.Sprintf("У %s есть: %s", c.Name, strings.Join(found, ", ")), nil
+	})
+}
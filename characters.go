@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-llm-rpggamemaster/character"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+)
+
+const sheetUsage = `Использование:
+/sheet — показать лист персонажа
+/sheet hp -3 — урон или лечение (+2)
+/sheet <поле> <значение> — поля: name, level, hp, maxhp, str, dex, con, int, wis, cha
+/sheet +item <предмет>, /sheet -item <предмет>
+/sheet +cond <состояние>, /sheet -cond <состояние>`
+
+// newCharacterStore keeps characters next to the game sessions
+func newCharacterStore() (character.Store, error) {
+	if dbPool == nil {
+		return character.NewMemoryStore(), nil
+	}
+	return character.NewPostgresStore(dbPool)
+}
+
+// newCharacterHandler creates or replaces the player's character: /newchar Имя str=15 dex=13 ...
+func newCharacterHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	name, attributes, err := character.ParseNew(strings.TrimPrefix(update.Message.Text, "/newchar"))
+	if err == nil && name == "" {
+		err = errors.New("character name is required")
+	}
+	if err != nil {
+		reply(ctx, b, chatID, fmt.Sprintf("Не удалось создать персонажа: %s\nПример: /newchar Арагорн str=15 dex=13 con=14 int=10 wis=12 cha=8", err.Error()))
+		return
+	}
+
+	gameID, ok := chatGame(ctx, b, chatID)
+	if !ok {
+		return
+	}
+
+	c := character.New(gameID, update.Message.From.ID, name, attributes)
+	if err := c.Validate(); err != nil {
+		reply(ctx, b, chatID, fmt.Sprintf("Такой персонаж невозможен: %s", err.Error()))
+		return
+	}
+	if err := characters.Save(ctx, c); err != nil {
+		log.Err(err).Int64("chat_id", chatID).Msg("failed to save character")
+		reply(ctx, b, chatID, "Не удалось сохранить персонажа, попробуйте позже")
+		return
+	}
+
+	reply(ctx, b, chatID, "Персонаж создан!\n\n"+character.Sheet(c))
+}
+
+// sheetHandler shows or edits the player's character sheet
+func sheetHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	gameID, ok := chatGame(ctx, b, chatID)
+	if !ok {
+		return
+	}
+
+	c, err := characters.Get(ctx, gameID, update.Message.From.ID)
+	if errors.Is(err, character.ErrNotFound) {
+		reply(ctx, b, chatID, "У вас ещё нет персонажа. Создайте его командой /newchar Имя")
+		return
+	}
+	if err != nil {
+		log.Err(err).Int64("chat_id", chatID).Msg("failed to load character")
+		reply(ctx, b, chatID, "Не удалось загрузить персонажа, попробуйте позже")
+		return
+	}
+
+	args := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/sheet"))
+	if args == "" {
+		reply(ctx, b, chatID, character.Sheet(c))
+		return
+	}
+
+	field, value, _ := strings.Cut(args, " ")
+	if err := character.Edit(c, field, value); err != nil {
+		reply(ctx, b, chatID, fmt.Sprintf("Не удалось изменить лист: %s\n\n%s", err.Error(), sheetUsage))
+		return
+	}
+	if err := characters.Save(ctx, c); err != nil {
+		log.Err(err).Int64("chat_id", chatID).Msg("failed to save character")
+		reply(ctx, b, chatID, "Не удалось сохранить персонажа, попробуйте позже")
+		return
+	}
+
+	reply(ctx, b, chatID, character.Sheet(c))
+}
+
+// reply sends a plain text message to a chat
+func reply(ctx context.Context, b *bot.Bot, chatID int64, text string) {
+	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	})
+	if err != nil {
+		log.Err(err).Msg("failed to send message")
+	}
+}
@@ -10,11 +10,30 @@ import (
 )
 
 type Config struct {
-	Profile           string          `mapstructure:"profile"`
-	InferenceModel    LLModel         `mapstructure:"inference_model"`
-	EmbeddingModel    LLModel         `mapstructure:"embedding_model"`
-	VectorRetriever   VectorRetriever `mapstructure:"vector_retriever"`
-	TelegramBotApiKey string          `mapstructure:"telegram_bot_api_key"`
+	Profile        string  `mapstructure:"profile"`
+	InferenceModel LLModel `mapstructure:"inference_model"`
+	// InferenceFallbacks are tried in order when inference_model fails with a transient error
+	InferenceFallbacks []LLModel       `mapstructure:"inference_fallbacks"`
+	EmbeddingModel     LLModel         `mapstructure:"embedding_model"`
+	VectorRetriever    VectorRetriever `mapstructure:"vector_retriever"`
+	TelegramBotApiKey  string          `mapstructure:"telegram_bot_api_key"`
+	Usage              UsageConfig     `mapstructure:"usage"`
+	Limits             LimitsConfig    `mapstructure:"limits"`
+	Prompts            PromptsConfig   `mapstructure:"prompts"`
+	// Admins are the Telegram user IDs allowed to run bot-wide commands such as /setting reload
+	Admins []int64 `mapstructure:"admins"`
+}
+
+// InferenceContextWindow returns the smallest context limit of the inference chain,
+// so that a prompt fits whichever model ends up answering. 0 means none is configured.
+func (c *Config) InferenceContextWindow() int {
+	limit := c.InferenceModel.ContextWindow
+	for _, model := range c.InferenceFallbacks {
+		if model.ContextWindow > 0 && (limit == 0 || model.ContextWindow < limit) {
+			limit = model.ContextWindow
+		}
+	}
+	return limit
 }
 
 func decodeHook(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
@@ -33,6 +33,12 @@ vector_retriever:
   name: "qdrant"
   url: "${QDRANT_URL}"
   type: "qdrant"
+  min_scores:
+    qdrant: 0.75
+    rerank: 0.3
+  ranking:
+    keyword_weight: 0
+    recency_half_life_days: 14
 
 telegram_bot_api_key: "${RPG_TELEGRAM_BOT_API_KEY}"`
 
@@ -68,11 +74,45 @@ telegram_bot_api_key: "${RPG_TELEGRAM_BOT_API_KEY}"`
 		t.Errorf("Expected retriever name 'qdrant', got '%s'", cfg.VectorRetriever.Name)
 	}
 
+	if scores := cfg.VectorRetriever.MinScores; scores["qdrant"] != 0.75 || scores["rerank"] != 0.3 {
+		t.Errorf("Expected per-source score thresholds, got %v", scores)
+	}
+
+	if ranking := cfg.VectorRetriever.Ranking; ranking.SemanticWeight != nil || ranking.KeywordWeight == nil || *ranking.KeywordWeight != 0 || ranking.RecencyHalfLifeDays != 14 {
+		t.Errorf("Expected an explicit zero keyword weight and an unset semantic weight, got %+v", ranking)
+	}
+
 	if cfg.TelegramBotApiKey != "${RPG_TELEGRAM_BOT_API_KEY}" {
 		t.Errorf("Expected telegram bot api key from config, got '%s'", cfg.TelegramBotApiKey)
 	}
 }
 
+func TestInferenceContextWindow(t *testing.T) {
+	tests := []struct {
+		name      string
+		primary   int
+		fallbacks []int
+		expected  int
+	}{
+		{"not configured", 0, nil, 0},
+		{"primary only", 128000, nil, 128000},
+		{"smallest fallback", 128000, []int{32000, 0, 64000}, 32000},
+		{"fallback without primary", 0, []int{8000}, 8000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{InferenceModel: LLModel{ContextWindow: tt.primary}}
+			for _, limit := range tt.fallbacks {
+				cfg.InferenceFallbacks = append(cfg.InferenceFallbacks, LLModel{ContextWindow: limit})
+			}
+			if got := cfg.InferenceContextWindow(); got != tt.expected {
+				t.Errorf("InferenceContextWindow() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestModelTypeString(t *testing.T) {
 	tests := []struct {
 		modelType ModelType
@@ -102,6 +142,8 @@ func TestModelTypeUnmarshalText(t *testing.T) {
 		{"openai", ModelTypeOpenAI, false},
 		{"ollama", ModelTypeOllama, false},
 		{"routerai", ModelTypeRouterAI, false},
+		{"fake", ModelTypeFake, false},
+		{"local", ModelTypeFake, false},
 		{"invalid", ModelTypeUnknown, true},
 		{"", ModelTypeUnknown, true},
 	}
@@ -126,3 +168,37 @@ func TestModelTypeUnmarshalText(t *testing.T) {
 		})
 	}
 }
+
+func TestRetrieverTypeUnmarshalText(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  RetrieverType
+		wantError bool
+	}{
+		{"sqlite", RetrieverTypeSqlite, false},
+		{"qdrant", RetrieverTypeQdrant, false},
+		{"postgres", RetrieverTypePostgres, false},
+		{"Memory", RetrieverTypeMemory, false},
+		{"invalid", RetrieverTypeUnknown, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var rt RetrieverType
+			err := rt.UnmarshalText([]byte(tt.input))
+			if tt.wantError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("UnmarshalText() error = %v, wantError = false", err)
+				return
+			}
+			if rt != tt.expected {
+				t.Errorf("UnmarshalText() = %v, want %v", rt, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package config
+
+// LimitsConfig protects the LLM budget from users and chats. Zero values disable a limit.
+type LimitsConfig struct {
+	UserRequestsPerMinute int     `mapstructure:"user_requests_per_minute"`
+	ChatRequestsPerMinute int     `mapstructure:"chat_requests_per_minute"`
+	UserDailyTokens       int     `mapstructure:"user_daily_tokens"`
+	ChatDailyTokens       int     `mapstructure:"chat_daily_tokens"`
+	AllowChats            []int64 `mapstructure:"allow_chats"`
+	AllowUsers            []int64 `mapstructure:"allow_users"`
+	DenyChats             []int64 `mapstructure:"deny_chats"`
+	DenyUsers             []int64 `mapstructure:"deny_users"`
+}
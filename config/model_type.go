@@ -12,6 +12,7 @@ const (
 	ModelTypeOpenAI
 	ModelTypeOllama
 	ModelTypeRouterAI
+	ModelTypeFake
 )
 
 func (t ModelType) String() string {
@@ -22,6 +23,8 @@ func (t ModelType) String() string {
 		return "Ollama"
 	case ModelTypeRouterAI:
 		return "RouterAI"
+	case ModelTypeFake:
+		return "Fake"
 	default:
 		return "Unknown"
 	}
@@ -38,6 +41,8 @@ func (t *ModelType) UnmarshalText(text []byte) error {
 		*t = ModelTypeOllama
 	case "routerai":
 		*t = ModelTypeRouterAI
+	case "fake", "local":
+		*t = ModelTypeFake
 	default:
 		*t = ModelTypeUnknown
 		return fmt.Errorf("invalid model type: %s", text)
@@ -54,4 +59,14 @@ type LLModel struct {
 	Url    string    `mapstructure:"url"`
 	Type   ModelType `mapstructure:"type"`
 	ApiKey string    `mapstructure:"api_key"`
+
+	// ContextWindow is the context limit of the model in tokens, 0 uses the session default
+	ContextWindow int `mapstructure:"context_window"`
+
+	// Cassette records and replays the provider HTTP traffic, mode is set by RPG_CASSETTE_MODE
+	Cassette string `mapstructure:"cassette"`
+
+	// Offline fake provider: YAML script of replies and embedding size
+	Script     string `mapstructure:"script"`
+	Dimensions int    `mapstructure:"dimensions"`
 }
@@ -0,0 +1,7 @@
+package config
+
+// PromptsConfig locates the game-master prompt templates
+type PromptsConfig struct {
+	Dir     string `mapstructure:"dir"`     // Template directory, "templates" when empty
+	Default string `mapstructure:"default"` // Preset of games without a setting, "fantasy" when empty
+}
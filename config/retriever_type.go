@@ -12,6 +12,7 @@ const (
 	RetrieverTypeSqlite
 	RetrieverTypeQdrant
 	RetrieverTypePostgres
+	RetrieverTypeMemory
 )
 
 func (t RetrieverType) String() string {
@@ -22,6 +23,8 @@ func (t RetrieverType) String() string {
 		return "Qdrant"
 	case RetrieverTypePostgres:
 		return "Postgres"
+	case RetrieverTypeMemory:
+		return "Memory"
 	default:
 		return "Unknown"
 	}
@@ -35,6 +38,8 @@ func (t *RetrieverType) UnmarshalText(text []byte) error {
 		*t = RetrieverTypeQdrant
 	case "postgres":
 		*t = RetrieverTypePostgres
+	case "memory":
+		*t = RetrieverTypeMemory
 	default:
 		*t = RetrieverTypeUnknown
 		return fmt.Errorf("invalid retriever type: %s", text)
@@ -50,4 +55,27 @@ type VectorRetriever struct {
 	Name string        `mapstructure:"name"`
 	Url  string        `mapstructure:"url"`
 	Type RetrieverType `mapstructure:"type"`
+	Path string        `mapstructure:"path"` // Database file of sqlite, snapshot file of memory
+	// MinScores leaves documents scored lower out of the prompt, keyed by score source: postgres, sqlite,
+	// memory, qdrant or rerank. Scales differ: RRF of hybrid search is at most about 0.05, Qdrant cosine
+	// similarity and reranker scores up to 1. A source without a threshold keeps every document.
+	MinScores map[string]float64 `mapstructure:"min_scores"`
+	Ranking   RankingConfig      `mapstructure:"ranking"`
+	Rerank    RerankConfig       `mapstructure:"rerank"`
+}
+
+// RerankConfig enables rescoring of the top retrieval candidates. An empty type disables it.
+type RerankConfig struct {
+	Type string `mapstructure:"type"`  // llm | lexical
+	TopN int    `mapstructure:"top_n"` // Candidates passed to the reranker, 10 by default
+}
+
+// RankingConfig tunes hybrid search of the postgres retriever. Unset weights keep their defaults,
+// an explicit 0 turns a channel or the recency decay off. MMR and the decay are off unless set.
+type RankingConfig struct {
+	SemanticWeight      *float64 `mapstructure:"semantic_weight"`
+	KeywordWeight       *float64 `mapstructure:"keyword_weight"`
+	MMRLambda           float64  `mapstructure:"mmr_lambda"`
+	RecencyHalfLifeDays float64  `mapstructure:"recency_half_life_days"`
+	RecencyWeight       *float64 `mapstructure:"recency_weight"`
 }
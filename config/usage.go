@@ -0,0 +1,14 @@
+package config
+
+// UsageConfig converts token usage into costs
+type UsageConfig struct {
+	Currency string       `mapstructure:"currency"`
+	Prices   []ModelPrice `mapstructure:"prices"`
+}
+
+// ModelPrice is the cost of a model per million tokens
+type ModelPrice struct {
+	Model      string  `mapstructure:"model"`
+	Prompt     float64 `mapstructure:"prompt"`
+	Completion float64 `mapstructure:"completion"`
+}
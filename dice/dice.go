@@ -0,0 +1,299 @@
+// Package dice parses and rolls dice expressions in standard RPG notation.
+//
+// Supported notation, combined with + and -:
+//
+//	2d6+3     dice and constants
+//	d%        percentile die (d100)
+//	4d6kh3    keep highest, also kl (keep lowest), dh and dl (drop highest/lowest)
+//	3d6!      exploding dice: a die showing its maximum is rolled again and added
+//	d20adv    advantage: roll twice and keep the highest, dis for disadvantage
+package dice
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Limits keep a single expression cheap to roll and readable in chat
+const (
+	MaxTerms      = 20
+	MaxDice       = 100
+	MaxSides      = 1000
+	MaxExplosions = 100
+)
+
+// KeepMode selects which dice of a term count towards the total
+type KeepMode uint8
+
+const (
+	KeepAll KeepMode = iota
+	KeepHighest
+	KeepLowest
+	DropHighest
+	DropLowest
+)
+
+// Term is a single dice group or constant of an expression
+type Term struct {
+	Sign     int // +1 or -1
+	Count    int // Number of dice, 0 for a constant
+	Sides    int
+	Constant int
+	Keep     KeepMode
+	KeepN    int
+	Explode  bool
+}
+
+// IsConstant reports whether the term is a plain number
+func (t Term) IsConstant() bool {
+	return t.Count == 0
+}
+
+// String returns the canonical notation of the term without its sign
+func (t Term) String() string {
+	if t.IsConstant() {
+		return strconv.Itoa(t.Constant)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%dd", t.Count)
+	if t.Sides == 100 {
+		b.WriteString("%")
+	} else {
+		b.WriteString(strconv.Itoa(t.Sides))
+	}
+	if t.Explode {
+		b.WriteString("!")
+	}
+	switch t.Keep {
+	case KeepHighest:
+		fmt.Fprintf(&b, "kh%d", t.KeepN)
+	case KeepLowest:
+		fmt.Fprintf(&b, "kl%d", t.KeepN)
+	case DropHighest:
+		fmt.Fprintf(&b, "dh%d", t.KeepN)
+	case DropLowest:
+		fmt.Fprintf(&b, "dl%d", t.KeepN)
+	}
+	return b.String()
+}
+
+// Expression is a parsed dice expression
+type Expression struct {
+	Terms []Term
+}
+
+// String returns the canonical notation of the expression
+func (e *Expression) String() string {
+	var b strings.Builder
+	for i, term := range e.Terms {
+		switch {
+		case term.Sign < 0:
+			b.WriteString("-")
+		case i > 0:
+			b.WriteString("+")
+		}
+		b.WriteString(term.String())
+	}
+	return b.String()
+}
+
+// Parse parses a dice expression. Whitespace and letter case are ignored.
+func Parse(notation string) (*Expression, error) {
+	input := strings.ToLower(strings.Join(strings.Fields(notation), ""))
+	if input == "" {
+		return nil, fmt.Errorf("empty dice expression")
+	}
+
+	p := &parser{input: input}
+	expr := &Expression{}
+	sign := 1
+	if p.peek() == '-' || p.peek() == '+' {
+		if p.next() == '-' {
+			sign = -1
+		}
+	}
+
+	for {
+		term, err := p.term()
+		if err != nil {
+			return nil, fmt.Errorf("invalid dice expression %q: %w", notation, err)
+		}
+		term.Sign = sign
+		expr.Terms = append(expr.Terms, term)
+		if len(expr.Terms) > MaxTerms {
+			return nil, fmt.Errorf("invalid dice expression %q: more than %d terms", notation, MaxTerms)
+		}
+
+		if p.done() {
+			return expr, nil
+		}
+		switch p.next() {
+		case '+':
+			sign = 1
+		case '-':
+			sign = -1
+		default:
+			return nil, fmt.Errorf("invalid dice expression %q: unexpected %q at position %d", notation, p.input[p.pos-1], p.pos)
+		}
+	}
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *parser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *parser) next() byte {
+	c := p.peek()
+	p.pos++
+	return c
+}
+
+func (p *parser) consume(prefix string) bool {
+	if strings.HasPrefix(p.input[p.pos:], prefix) {
+		p.pos += len(prefix)
+		return true
+	}
+	return false
+}
+
+// number reads an unsigned integer, returning ok=false when there are no digits
+func (p *parser) number() (int, bool, error) {
+	start := p.pos
+	for !p.done() && p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(p.input[start:p.pos])
+	if err != nil {
+		return 0, false, fmt.Errorf("number too large")
+	}
+	return n, true, nil
+}
+
+func (p *parser) term() (Term, error) {
+	count, hasCount, err := p.number()
+	if err != nil {
+		return Term{}, err
+	}
+
+	if p.peek() != 'd' || strings.HasPrefix(p.input[p.pos:], "dh") || strings.HasPrefix(p.input[p.pos:], "dl") {
+		if !hasCount {
+			return Term{}, fmt.Errorf("expected a number or dice at position %d", p.pos+1)
+		}
+		return Term{Constant: count}, nil
+	}
+	p.pos++ // 'd'
+
+	if !hasCount {
+		count = 1
+	}
+	if count < 1 || count > MaxDice {
+		return Term{}, fmt.Errorf("dice count must be between 1 and %d", MaxDice)
+	}
+
+	term := Term{Count: count}
+	if p.consume("%") {
+		term.Sides = 100
+	} else {
+		sides, ok, err := p.number()
+		if err != nil {
+			return Term{}, err
+		}
+		if !ok {
+			return Term{}, fmt.Errorf("expected number of sides at position %d", p.pos+1)
+		}
+		if sides < 2 || sides > MaxSides {
+			return Term{}, fmt.Errorf("dice sides must be between 2 and %d", MaxSides)
+		}
+		term.Sides = sides
+	}
+
+	if err := p.modifiers(&term); err != nil {
+		return Term{}, err
+	}
+	return term, nil
+}
+
+func (p *parser) modifiers(term *Term) error {
+	for !p.done() {
+		switch {
+		case p.consume("!"):
+			if term.Sides < 3 {
+				// Every roll of a d2 would have a fair chance to explode forever
+				return fmt.Errorf("exploding dice need at least 3 sides")
+			}
+			term.Explode = true
+		case p.consume("adv"):
+			if err := p.setKeep(term, KeepHighest, 1); err != nil {
+				return err
+			}
+			term.Count = max(term.Count, 2)
+		case p.consume("dis"):
+			if err := p.setKeep(term, KeepLowest, 1); err != nil {
+				return err
+			}
+			term.Count = max(term.Count, 2)
+		case p.consume("kl"):
+			if err := p.keepN(term, KeepLowest); err != nil {
+				return err
+			}
+		case p.consume("kh"), p.consume("k"):
+			if err := p.keepN(term, KeepHighest); err != nil {
+				return err
+			}
+		case p.consume("dh"):
+			if err := p.keepN(term, DropHighest); err != nil {
+				return err
+			}
+		case p.consume("dl"):
+			if err := p.keepN(term, DropLowest); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *parser) keepN(term *Term, mode KeepMode) error {
+	n, ok, err := p.number()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		n = 1
+	}
+	return p.setKeep(term, mode, n)
+}
+
+func (p *parser) setKeep(term *Term, mode KeepMode, n int) error {
+	if term.Keep != KeepAll {
+		return fmt.Errorf("only one keep or drop modifier is allowed per dice group")
+	}
+	if n < 1 || n > term.Count {
+		return fmt.Errorf("cannot keep or drop %d of %d dice", n, term.Count)
+	}
+	if (mode == DropHighest || mode == DropLowest) && n == term.Count {
+		return fmt.Errorf("cannot drop all %d dice", n)
+	}
+	term.Keep = mode
+	term.KeepN = n
+	return nil
+}
@@ -0,0 +1,202 @@
+package dice
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/tools"
+)
+
+func TestParse(t *testing.T) {
+	valid := map[string]string{
+		"2d6+3":        "2d6+3",
+		"d20":          "1d20",
+		"D%":           "1d%",
+		"4d6kh3":       "4d6kh3",
+		"4d6k3":        "4d6kh3",
+		"2d20kl1":      "2d20kl1",
+		"4d6dl":        "4d6dl1",
+		"3d6!":         "3d6!",
+		"d20adv":       "2d20kh1",
+		"d20dis+2":     "2d20kl1+2",
+		"-1 + 2d8 - 1": "-1+2d8-1",
+	}
+	for notation, want := range valid {
+		t.Run(notation, func(t *testing.T) {
+			expr, err := Parse(notation)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := expr.String(); got != want {
+				t.Errorf("expected %q, got %q", want, got)
+			}
+		})
+	}
+
+	invalid := []string{
+		"",
+		"d",
+		"2d",
+		"d1",
+		"abc",
+		"2d6+",
+		"2d6*2",
+		"0d6",
+		"101d6",
+		"d1001",
+		"2d6kh3",
+		"2d6dl2",
+		"4d6kh3dl1",
+		"d2!",
+		"99999999999999999999d6",
+		strings.Repeat("1+", MaxTerms) + "1",
+	}
+	for _, notation := range invalid {
+		t.Run("invalid "+notation, func(t *testing.T) {
+			if _, err := Parse(notation); err == nil {
+				t.Errorf("expected error for %q", notation)
+			}
+		})
+	}
+}
+
+func TestRoller(t *testing.T) {
+	t.Run("same seed gives same rolls", func(t *testing.T) {
+		a, b := NewRoller(42), NewRoller(42)
+		for i := 0; i < 20; i++ {
+			ra, _ := a.Roll("3d6!+4d6kh3")
+			rb, _ := b.Roll("3d6!+4d6kh3")
+			if ra.String() != rb.String() {
+				t.Fatalf("rolls diverged: %q vs %q", ra, rb)
+			}
+		}
+	})
+
+	t.Run("total stays in range", func(t *testing.T) {
+		r := NewRoller(1)
+		for i := 0; i < 1000; i++ {
+			result, err := r.Roll("2d6+3")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Total < 5 || result.Total > 15 {
+				t.Fatalf("total %d out of range: %s", result.Total, result)
+			}
+		}
+	})
+
+	t.Run("keep highest drops the lowest dice", func(t *testing.T) {
+		r := NewRoller(7)
+		for i := 0; i < 100; i++ {
+			result, _ := r.Roll("4d6kh3")
+			term := result.Terms[0]
+
+			kept, lowestKept, highestDropped := 0, 7, 0
+			for _, die := range term.Dice {
+				if die.Kept {
+					kept++
+					lowestKept = min(lowestKept, die.Value)
+				} else {
+					highestDropped = max(highestDropped, die.Value)
+				}
+			}
+			if kept != 3 {
+				t.Fatalf("expected 3 kept dice, got %d: %s", kept, result)
+			}
+			if highestDropped > lowestKept {
+				t.Fatalf("dropped a die higher than a kept one: %s", result)
+			}
+		}
+	})
+
+	t.Run("advantage keeps one of two", func(t *testing.T) {
+		r := NewRoller(3)
+		result, _ := r.Roll("d20adv")
+		term := result.Terms[0]
+		if len(term.Dice) != 2 {
+			t.Fatalf("expected 2 dice, got %d", len(term.Dice))
+		}
+		if result.Total != max(term.Dice[0].Value, term.Dice[1].Value) {
+			t.Errorf("expected the highest die as total: %s", result)
+		}
+	})
+
+	t.Run("exploding dice roll again on maximum", func(t *testing.T) {
+		r := NewRoller(5)
+		exploded := false
+		for i := 0; i < 200; i++ {
+			result, _ := r.Roll("1d3!")
+			dice := result.Terms[0].Dice
+			for j := 0; j < len(dice)-1; j++ {
+				if dice[j].Value != 3 {
+					t.Fatalf("only a maximum may explode: %s", result)
+				}
+				exploded = true
+			}
+			if dice[len(dice)-1].Value == 3 {
+				t.Fatalf("a maximum must explode: %s", result)
+			}
+		}
+		if !exploded {
+			t.Error("expected at least one explosion in 200 rolls")
+		}
+	})
+
+	t.Run("negative terms are subtracted", func(t *testing.T) {
+		r := NewRoller(9)
+		result, _ := r.Roll("1d4-10")
+		if result.Total > -6 || result.Total < -9 {
+			t.Errorf("unexpected total %d", result.Total)
+		}
+	})
+}
+
+func TestResult_String(t *testing.T) {
+	expr, _ := Parse("4d6dl1+2-1d4")
+	result := &Result{
+		Expression: expr,
+		Terms: []TermResult{
+			{Term: expr.Terms[0], Dice: []Die{{Value: 5, Kept: true}, {Value: 1}, {Value: 3, Kept: true}, {Value: 6, Kept: true}}, Subtotal: 14},
+			{Term: expr.Terms[1], Subtotal: 2},
+			{Term: expr.Terms[2], Dice: []Die{{Value: 2, Kept: true}}, Subtotal: -2},
+		},
+		Total: 14,
+	}
+
+	want := "4d6dl1+2-1d4: [5, ~1, 3, 6] + 2 - [2] = 14"
+	if got := result.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestTool(t *testing.T) {
+	registry := tools.NewRegistry()
+	if err := registry.Register(NewTool(NewRoller(1))); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	t.Run("rolls the requested notation", func(t *testing.T) {
+		args, _ := json.Marshal(map[string]string{"notation": "1d20+5", "reason": "атака"})
+		result, err := registry.Call(context.Background(), tools.Invocation{}, toolCall(string(args)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(result, "1d20+5: [") || !strings.HasSuffix(result, "(атака)") {
+			t.Errorf("unexpected result %q", result)
+		}
+	})
+
+	t.Run("invalid notation is an error", func(t *testing.T) {
+		_, err := registry.Call(context.Background(), tools.Invocation{}, toolCall(`{"notation":"fireball"}`))
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func toolCall(arguments string) interfaces.ToolCall {
+	return interfaces.ToolCall{ID: "call_1", Name: ToolName, Arguments: arguments}
+}
@@ -0,0 +1,171 @@
+package dice
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Die is a single rolled die
+type Die struct {
+	Value    int
+	Kept     bool
+	Exploded bool // Rolled because the previous die exploded
+}
+
+// TermResult is the outcome of a single term
+type TermResult struct {
+	Term     Term
+	Dice     []Die
+	Subtotal int // Signed contribution to the total
+}
+
+// String returns the breakdown of the term, e.g. "[6!, 3]" or "[5, ~2]"
+func (r TermResult) String() string {
+	if r.Term.IsConstant() {
+		return strconv.Itoa(r.Term.Constant)
+	}
+
+	values := make([]string, len(r.Dice))
+	for i, die := range r.Dice {
+		value := strconv.Itoa(die.Value)
+		if r.Term.Explode && die.Value == r.Term.Sides {
+			value += "!"
+		}
+		if !die.Kept {
+			value = "~" + value
+		}
+		values[i] = value
+	}
+	return "[" + strings.Join(values, ", ") + "]"
+}
+
+// Result is the outcome of a rolled expression
+type Result struct {
+	Expression *Expression
+	Terms      []TermResult
+	Total      int
+}
+
+// String returns a one-line breakdown, e.g. "2d6+3: [4, 2] + 3 = 9".
+// Dropped dice are prefixed with ~, exploded maximums are marked with !.
+func (r *Result) String() string {
+	var b strings.Builder
+	b.WriteString(r.Expression.String())
+	b.WriteString(": ")
+	for i, term := range r.Terms {
+		switch {
+		case term.Term.Sign < 0 && i == 0:
+			b.WriteString("-")
+		case term.Term.Sign < 0:
+			b.WriteString(" - ")
+		case i > 0:
+			b.WriteString(" + ")
+		}
+		b.WriteString(term.String())
+	}
+	fmt.Fprintf(&b, " = %d", r.Total)
+	return b.String()
+}
+
+// Roller rolls dice expressions. It is safe for concurrent use.
+type Roller struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewRoller creates a roller with a fixed seed, so the same seed yields the same rolls
+func NewRoller(seed int64) *Roller {
+	return &Roller{rng: rand.New(rand.NewSource(seed))}
+}
+
+// NewRandomRoller creates a roller seeded from the current time
+func NewRandomRoller() *Roller {
+	return NewRoller(time.Now().UnixNano())
+}
+
+// Roll parses and rolls a dice expression
+func (r *Roller) Roll(notation string) (*Result, error) {
+	expr, err := Parse(notation)
+	if err != nil {
+		return nil, err
+	}
+	return r.Eval(expr), nil
+}
+
+// Eval rolls a parsed expression
+func (r *Roller) Eval(expr *Expression) *Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := &Result{Expression: expr, Terms: make([]TermResult, len(expr.Terms))}
+	for i, term := range expr.Terms {
+		result.Terms[i] = r.rollTerm(term)
+		result.Total += result.Terms[i].Subtotal
+	}
+	return result
+}
+
+func (r *Roller) rollTerm(term Term) TermResult {
+	if term.IsConstant() {
+		return TermResult{Term: term, Subtotal: term.Sign * term.Constant}
+	}
+
+	dice := make([]Die, 0, term.Count)
+	explosions := 0
+	for i := 0; i < term.Count; i++ {
+		die := Die{Value: r.rng.Intn(term.Sides) + 1, Kept: true}
+		dice = append(dice, die)
+		for term.Explode && die.Value == term.Sides && explosions < MaxExplosions {
+			explosions++
+			die = Die{Value: r.rng.Intn(term.Sides) + 1, Kept: true, Exploded: true}
+			dice = append(dice, die)
+		}
+	}
+
+	markKept(dice, term.Keep, term.KeepN)
+
+	sum := 0
+	for _, die := range dice {
+		if die.Kept {
+			sum += die.Value
+		}
+	}
+	return TermResult{Term: term, Dice: dice, Subtotal: term.Sign * sum}
+}
+
+// markKept clears Kept on the dice that do not count towards the total.
+// Exploded dice take part in keep and drop like any other die.
+func markKept(dice []Die, mode KeepMode, n int) {
+	if mode == KeepAll {
+		return
+	}
+
+	// Indexes ordered from the lowest to the highest value, ties keep roll order
+	order := make([]int, len(dice))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return dice[order[a]].Value < dice[order[b]].Value
+	})
+
+	var dropped []int
+	switch mode {
+	case KeepHighest:
+		dropped = order[:max(len(order)-n, 0)]
+	case KeepLowest:
+		dropped = order[min(n, len(order)):]
+	case DropHighest:
+		dropped = order[max(len(order)-n, 0):]
+	case DropLowest:
+		dropped = order[:min(n, len(order))]
+	}
+	for _, i := range dropped {
+		dice[i].Kept = false
+	}
+}
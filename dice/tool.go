@@ -0,0 +1,56 @@
+package dice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/tools"
+)
+
+// ToolName is the name of the dice tool offered to the game master
+const ToolName = "roll_dice"
+
+type toolArguments struct {
+	Notation string `json:"notation"`
+	Reason   string `json:"reason"`
+}
+
+// NewTool exposes the roller as a game-master tool, so roll outcomes come from the engine and not the model
+func NewTool(roller *Roller) tools.Tool {
+	definition := interfaces.ToolDefinition{
+		Name:        ToolName,
+		Description: "Бросить кубики для проверки, атаки, урона или любого случайного исхода. Всегда используй этот инструмент вместо того, чтобы придумывать результат броска.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"notation": map[string]interface{}{
+					"type":        "string",
+					"description": "Выражение в стандартной нотации: 1d20+5, 2d6, 4d6kh3, d20adv, d20dis, 3d6!, d%",
+				},
+				"reason": map[string]interface{}{
+					"type":        "string",
+					"description": "Зачем нужен бросок, например «проверка ловкости»",
+				},
+			},
+			"required": []string{"notation"},
+		},
+	}
+
+	return tools.NewFunc(definition, func(ctx context.Context, inv tools.Invocation, arguments json.RawMessage) (string, error) {
+		var args toolArguments
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return "", fmt.Errorf("decoding arguments: %w", err)
+		}
+
+		result, err := roller.Roll(args.Notation)
+		if err != nil {
+			return "", err
+		}
+		if args.Reason != "" {
+			return fmt.Sprintf("%s (%s)", result, args.Reason), nil
+		}
+		return result.String(), nil
+	})
+}
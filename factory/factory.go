@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	config "go-llm-rpggamemaster/config"
 	factoryinterface "go-llm-rpggamemaster/factory/interface"
 	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/providers/cassette"
+	"go-llm-rpggamemaster/providers/fake"
+	"go-llm-rpggamemaster/providers/fallback"
 	"go-llm-rpggamemaster/providers/routerai"
 	"go-llm-rpggamemaster/retrievers"
+	memoryretriever "go-llm-rpggamemaster/retrievers/memory"
 	postgresretriever "go-llm-rpggamemaster/retrievers/postgres"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -26,16 +31,43 @@ func NewProviderFactory(cfg *config.Config) factoryinterface.ProviderFactory {
 	}
 }
 
+// CreateInferenceProvider creates the inference_model provider, chained with
+// inference_fallbacks when they are configured
 func (f *providerFactory) CreateInferenceProvider() (interfaces.InferenceProvider, error) {
-	inferenceModel := f.cfg.InferenceModel
-	baseURL := inferenceModel.Url
-	apiKey := inferenceModel.ApiKey
-	modelName := inferenceModel.Name
+	if len(f.cfg.InferenceFallbacks) == 0 {
+		return createInferenceProvider(f.cfg.InferenceModel)
+	}
+
+	models := append([]config.LLModel{f.cfg.InferenceModel}, f.cfg.InferenceFallbacks...)
+	members := make([]fallback.Member, 0, len(models))
+	for i, model := range models {
+		provider, err := createInferenceProvider(model)
+		if err != nil {
+			return nil, fmt.Errorf("inference provider %d: %w", i+1, err)
+		}
+		// A member retries once and then hands the request to the next one
+		if routerAI, ok := provider.(*routerai.RouterAIProvider); ok {
+			retry := routerai.DefaultRetryConfig()
+			retry.MaxRetries = 1
+			routerAI.SetRetryConfig(retry)
+		}
+		name := provider.Name()
+		if model.Name != "" {
+			name += "/" + model.Name
+		}
+		members = append(members, fallback.Member{Name: name, Provider: provider})
+	}
+	return fallback.New(members, fallback.DefaultConfig())
+}
+
+func createInferenceProvider(inferenceModel config.LLModel) (interfaces.InferenceProvider, error) {
 	providerType := inferenceModel.Type
 
 	switch inferenceModel.Type {
 	case config.ModelTypeRouterAI:
-		return routerai.NewRouterAIProvider(modelName, apiKey, baseURL)
+		return newRouterAIProvider(inferenceModel)
+	case config.ModelTypeFake:
+		return fake.NewProviderFromFile(inferenceModel.Script, inferenceModel.Dimensions)
 	case config.ModelTypeOpenAI, config.ModelTypeOllama:
 		return nil, fmt.Errorf("provider type %s is deprecated, use routerai", providerType)
 	default:
@@ -45,14 +77,13 @@ func (f *providerFactory) CreateInferenceProvider() (interfaces.InferenceProvide
 
 func (f *providerFactory) CreateEmbeddingProvider() (interfaces.VectorEmbeddingProvider, error) {
 	embeddingModel := f.cfg.EmbeddingModel
-	baseURL := embeddingModel.Url
-	apiKey := embeddingModel.ApiKey
-	modelName := embeddingModel.Name
 	providerType := embeddingModel.Type
 
 	switch providerType {
 	case config.ModelTypeRouterAI:
-		return routerai.NewRouterAIProvider(modelName, apiKey, baseURL)
+		return newRouterAIProvider(embeddingModel)
+	case config.ModelTypeFake:
+		return fake.NewProviderFromFile(embeddingModel.Script, embeddingModel.Dimensions)
 	case config.ModelTypeOpenAI, config.ModelTypeOllama:
 		return nil, fmt.Errorf("provider type %s is deprecated, use routerai", providerType)
 	default:
@@ -60,11 +91,38 @@ func (f *providerFactory) CreateEmbeddingProvider() (interfaces.VectorEmbeddingP
 	}
 }
 
+// newRouterAIProvider creates a RouterAI provider, routed through a cassette when one is configured
+func newRouterAIProvider(model config.LLModel) (*routerai.RouterAIProvider, error) {
+	provider, err := routerai.NewRouterAIProvider(model.Name, model.ApiKey, model.Url)
+	if err != nil {
+		return nil, err
+	}
+	if model.Cassette == "" {
+		return provider, nil
+	}
+
+	recorder, err := cassette.New(model.Cassette, cassette.ModeFromEnv(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("opening cassette: %w", err)
+	}
+	recorder.Scrub(model.ApiKey)
+	provider.SetTransport(recorder)
+	log.Info().Str("cassette", model.Cassette).Stringer("mode", recorder.Mode()).Msg("RouterAI traffic goes through a cassette")
+	return provider, nil
+}
+
 func (f *providerFactory) CreateRetriever(embedder interfaces.VectorEmbeddingProvider, retrieverType string) (retrievers.Retriever, error) {
 	switch retrieverType {
 	case "sqlite":
-		dbPath := "base.db"
+		dbPath := f.cfg.VectorRetriever.Path
+		if dbPath == "" {
+			dbPath = "base.db"
+		}
 		return retrievers.NewSQLiteRetrieverWithPath(embedder, dbPath)
+	case "memory":
+		memoryConfig := memoryretriever.DefaultConfig()
+		memoryConfig.SnapshotPath = f.cfg.VectorRetriever.Path
+		return memoryretriever.NewMemoryRetriever(embedder, memoryConfig)
 	case "qdrant":
 		return retrievers.NewQdrantRetriever(embedder)
 	case "postgres":
@@ -77,7 +135,28 @@ func (f *providerFactory) CreateRetriever(embedder interfaces.VectorEmbeddingPro
 			return nil, fmt.Errorf("creating database pool: %w", err)
 		}
 		log.Info().Msg("PostgreSQL connection pool created")
-		return postgresretriever.NewPostgresRetriever(pool, embedder)
+		retriever, err := postgresretriever.NewPostgresRetriever(pool, embedder)
+		if err != nil {
+			return nil, err
+		}
+		ranking := f.cfg.VectorRetriever.Ranking
+		tuning := postgresretriever.DefaultTuning()
+		if ranking.SemanticWeight != nil {
+			tuning.SemanticWeight = *ranking.SemanticWeight
+		}
+		if ranking.KeywordWeight != nil {
+			tuning.KeywordWeight = *ranking.KeywordWeight
+		}
+		if ranking.RecencyWeight != nil {
+			tuning.RecencyWeight = *ranking.RecencyWeight
+		}
+		tuning.MMRLambda = ranking.MMRLambda
+		tuning.RecencyHalfLife = time.Duration(ranking.RecencyHalfLifeDays * float64(24*time.Hour))
+		if err := retriever.SetTuning(tuning); err != nil {
+			pool.Close()
+			return nil, err
+		}
+		return retriever, nil
 	default:
 		return nil, fmt.Errorf("unsupported retriever type: %s", retrieverType)
 	}
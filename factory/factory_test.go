@@ -1,11 +1,15 @@
 package factory
 
 import (
+	"context"
+	"os"
+	"strings"
 	"testing"
 
 	"go-llm-rpggamemaster/config"
 	factoryinterface "go-llm-rpggamemaster/factory/interface"
 	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/providers/cassette"
 )
 
 func TestProviderFactory(t *testing.T) {
@@ -109,3 +113,94 @@ func TestDeprecatedProviders(t *testing.T) {
 		}
 	})
 }
+
+func TestFakeProviders(t *testing.T) {
+	cfg := &config.Config{
+		InferenceModel: config.LLModel{Type: config.ModelTypeFake},
+		EmbeddingModel: config.LLModel{Type: config.ModelTypeFake, Dimensions: 32},
+	}
+	providerFactory := NewProviderFactory(cfg)
+
+	provider, err := providerFactory.CreateInferenceProvider()
+	if err != nil {
+		t.Fatalf("Failed to create fake inference provider: %v", err)
+	}
+	if provider.Name() != "fake" {
+		t.Errorf("Expected provider name 'fake', got '%s'", provider.Name())
+	}
+
+	embedder, err := providerFactory.CreateEmbeddingProvider()
+	if err != nil {
+		t.Fatalf("Failed to create fake embedding provider: %v", err)
+	}
+	embeddings, err := embedder.EmbedDocuments(context.Background(), []string{"дракон"})
+	if err != nil || len(embeddings[0]) != 32 {
+		t.Errorf("Expected a 32-dimensional embedding, got %v", err)
+	}
+
+	cfg.InferenceModel.Script = "missing.yml"
+	if _, err := NewProviderFactory(cfg).CreateInferenceProvider(); err == nil {
+		t.Error("Expected error for a missing script, got nil")
+	}
+}
+
+// TestCassetteProviders replays testdata/routerai.yml, RPG_CASSETTE_MODE=record with
+// ROUTERAI_API_KEY set re-records it
+func TestCassetteProviders(t *testing.T) {
+	apiKey := "test-key"
+	if cassette.ModeFromEnv() != cassette.ModeReplay {
+		apiKey = os.Getenv("ROUTERAI_API_KEY")
+	}
+	cfg := &config.Config{
+		InferenceModel: config.LLModel{Name: "gpt-4o-mini", Type: config.ModelTypeRouterAI, ApiKey: apiKey, Cassette: "testdata/routerai.yml"},
+		EmbeddingModel: config.LLModel{Name: "text-embedding-3-small", Type: config.ModelTypeRouterAI, ApiKey: apiKey, Cassette: "testdata/routerai.yml"},
+	}
+	providerFactory := NewProviderFactory(cfg)
+	ctx := context.Background()
+
+	provider, err := providerFactory.CreateInferenceProvider()
+	if err != nil {
+		t.Fatalf("Failed to create inference provider: %v", err)
+	}
+	response, err := provider.GenerateResponse(ctx, []interfaces.Message{{Role: "user", Content: "Бросаю кубик."}}, 0.7, 0)
+	if err != nil || !strings.Contains(response, "Кубик") {
+		t.Errorf("Unexpected response %q: %v", response, err)
+	}
+
+	embedder, err := providerFactory.CreateEmbeddingProvider()
+	if err != nil {
+		t.Fatalf("Failed to create embedding provider: %v", err)
+	}
+	embeddings, err := embedder.EmbedDocuments(ctx, []string{"Кубик"})
+	if err != nil || len(embeddings) != 1 || len(embeddings[0]) != 3 {
+		t.Errorf("Unexpected embeddings %v: %v", embeddings, err)
+	}
+
+	cfg.InferenceModel.Cassette = "testdata/missing.yml"
+	if _, err := NewProviderFactory(cfg).CreateInferenceProvider(); err == nil && cassette.ModeFromEnv() == cassette.ModeReplay {
+		t.Error("Expected error for a missing cassette in replay mode, got nil")
+	}
+}
+
+func TestInferenceFallbacks(t *testing.T) {
+	cfg := &config.Config{
+		InferenceModel:     config.LLModel{Name: "gpt-4o-mini", Type: config.ModelTypeRouterAI, ApiKey: "test-key"},
+		InferenceFallbacks: []config.LLModel{{Type: config.ModelTypeFake}},
+	}
+
+	provider, err := NewProviderFactory(cfg).CreateInferenceProvider()
+	if err != nil {
+		t.Fatalf("Failed to create inference chain: %v", err)
+	}
+	if provider.Name() != "routerai/gpt-4o-mini" {
+		t.Errorf("Expected the primary to be active, got '%s'", provider.Name())
+	}
+	if _, ok := provider.(interfaces.ToolCallingProvider); !ok {
+		t.Error("Chain does not implement ToolCallingProvider interface")
+	}
+
+	cfg.InferenceFallbacks = append(cfg.InferenceFallbacks, config.LLModel{Type: config.ModelTypeOllama})
+	if _, err := NewProviderFactory(cfg).CreateInferenceProvider(); err == nil {
+		t.Error("Expected error for an invalid fallback, got nil")
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-llm-rpggamemaster/interfaces"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+)
+
+const forgetUsage = `Использование:
+/forget <что найти> — найти факты в памяти кампании
+/forget <номер> — стереть найденный факт
+Команда доступна администраторам чата.`
+
+const (
+	forgetMatches    = 5
+	forgetPreviewLen = 200
+)
+
+// forgetSearches keeps the last /forget search of each chat, so a fact is erased by its number
+type forgetSearches struct {
+	mu     sync.Mutex
+	byChat map[int64][]interfaces.Document
+}
+
+var forgotten = &forgetSearches{byChat: make(map[int64][]interfaces.Document)}
+
+func (s *forgetSearches) set(chatID int64, docs []interfaces.Document) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byChat[chatID] = docs
+}
+
+// take returns the found document with a 1-based number and removes it from the search
+func (s *forgetSearches) take(chatID int64, number int) (interfaces.Document, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	docs := s.byChat[chatID]
+	if number < 1 || number > len(docs) || docs[number-1].ID == "" {
+		return interfaces.Document{}, false
+	}
+	doc := docs[number-1]
+	docs[number-1] = interfaces.Document{}
+	return doc, true
+}
+
+// forgetHandler lets chat admins find a mistaken fact in campaign memory and erase it
+func forgetHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	if retriever == nil {
+		reply(ctx, b, chatID, "Память кампании не подключена")
+		return
+	}
+	args := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/forget"))
+	if args == "" {
+		reply(ctx, b, chatID, forgetUsage)
+		return
+	}
+	if !isChatAdmin(ctx, b, update.Message.Chat, update.Message.From.ID) {
+		reply(ctx, b, chatID, "Стирать факты из памяти кампании могут только администраторы чата")
+		return
+	}
+
+	if number, err := strconv.Atoi(args); err == nil {
+		forgetFact(ctx, b, chatID, number)
+		return
+	}
+
+	gameID, ok := chatGame(ctx, b, chatID)
+	if !ok {
+		return
+	}
+	found, err := retriever.GetScopedDocuments(ctx, args, interfaces.SearchScope{GameID: gameID})
+	if err != nil {
+		log.Err(err).Int64("chat_id", chatID).Msg("failed to search campaign memory")
+		reply(ctx, b, chatID, "Не удалось найти факты, попробуйте позже")
+		return
+	}
+
+	var docs []interfaces.Document
+	for _, doc := range found {
+		if doc.ID != "" && len(docs) < forgetMatches {
+			docs = append(docs, doc)
+		}
+	}
+	if len(docs) == 0 {
+		reply(ctx, b, chatID, "В памяти кампании ничего не найдено")
+		return
+	}
+	forgotten.set(chatID, docs)
+
+	var text strings.Builder
+	text.WriteString("🔎 Найдено в памяти кампании:")
+	for i, doc := range docs {
+		fmt.Fprintf(&text, "\n\n%d. %s", i+1, preview(doc.PageContent))
+	}
+	text.WriteString("\n\nЧтобы стереть факт, отправьте /forget <номер>")
+	reply(ctx, b, chatID, text.String())
+}
+
+// forgetFact erases a fact found by the last search of the chat
+func forgetFact(ctx context.Context, b *bot.Bot, chatID int64, number int) {
+	doc, ok := forgotten.take(chatID, number)
+	if !ok {
+		reply(ctx, b, chatID, "Факта с таким номером нет. Сначала найдите его: /forget <что найти>")
+		return
+	}
+	if err := retriever.DeleteDocuments(ctx, []string{doc.ID}); err != nil {
+		log.Err(err).Int64("chat_id", chatID).Str("document_id", doc.ID).Msg("failed to erase fact")
+		reply(ctx, b, chatID, "Не удалось стереть факт, попробуйте позже")
+		return
+	}
+
+	log.Info().
+		Int64("chat_id", chatID).
+		Str("document_id", doc.ID).
+		Msg("Fact erased from campaign memory")
+	reply(ctx, b, chatID, "🗑 Забыто: "+preview(doc.PageContent))
+}
+
+// preview shortens a fact for a chat message
+func preview(content string) string {
+	runes := []rune(strings.TrimSpace(content))
+	if len(runes) <= forgetPreviewLen {
+		return string(runes)
+	}
+	return string(runes[:forgetPreviewLen]) + "…"
+}
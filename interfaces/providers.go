@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"context"
+	"errors"
 )
 
 // InferenceProvider defines the interface for LLM providers
@@ -13,6 +14,24 @@ type InferenceProvider interface {
 	Name() string
 }
 
+// StreamingInferenceProvider is implemented by providers that can stream token deltas
+type StreamingInferenceProvider interface {
+	InferenceProvider
+
+	// GenerateResponseStream calls onDelta for every received token delta and returns the full response.
+	// Returning an error from onDelta aborts the stream.
+	GenerateResponseStream(ctx context.Context, messages []Message, temperature float64, maxTokens int, onDelta func(delta string) error) (string, error)
+}
+
+// ToolCallingProvider is implemented by providers that support OpenAI-compatible function calling
+type ToolCallingProvider interface {
+	InferenceProvider
+
+	// GenerateWithTools returns the assistant message, which holds either content or tool calls.
+	// A non-nil onDelta streams content deltas as they arrive.
+	GenerateWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, temperature float64, maxTokens int, onDelta func(delta string) error) (Message, error)
+}
+
 // VectorEmbeddingProvider defines the interface for embedding providers
 type VectorEmbeddingProvider interface {
 	// EmbedDocuments embeds a list of documents
@@ -24,12 +43,77 @@ type VectorEmbeddingProvider interface {
 
 // Message represents a message in a conversation
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// ToolDefinition describes a function the model may call
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{} // JSON Schema of the arguments object
+}
+
+// ToolCall is a function invocation requested by the model
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON encoded arguments object
 }
 
 // Document represents a document with content and metadata for retrievers
 type Document struct {
+	// ID identifies the document across updates. Retrievers assign a UUID to documents added without one.
+	ID          string
 	PageContent string
 	Metadata    map[string]interface{}
 }
+
+// ScoredDocument is a retrieved document with the scores that ranked it
+type ScoredDocument struct {
+	Document
+	Score        float64 // Relevance, higher is better. Scores are comparable only within one source.
+	SemanticRank int     // 1-based rank by vector similarity, 0 when vector search did not return the document
+	KeywordRank  int     // 1-based rank by keyword search, 0 when keyword search did not return the document
+	Source       string  // Backend that returned the document, such as "postgres"
+}
+
+// Documents strips the scores of retrieved documents
+func Documents(scored []ScoredDocument) []Document {
+	docs := make([]Document, len(scored))
+	for i, s := range scored {
+		docs[i] = s.Document
+	}
+	return docs
+}
+
+// DocumentFilter selects documents to delete. Set fields must all match; an empty filter
+// matches nothing, so a zero value can never erase the whole store.
+type DocumentFilter struct {
+	GameID   string
+	UserID   int64
+	Metadata map[string]string // Compared with the metadata values as text
+}
+
+// IsEmpty reports whether the filter sets no conditions
+func (f DocumentFilter) IsEmpty() bool {
+	return f.GameID == "" && f.UserID == 0 && len(f.Metadata) == 0
+}
+
+// ErrEmptyFilter is returned when deleting by an empty filter
+var ErrEmptyFilter = errors.New("document filter is empty")
+
+// ErrEmptyScope is returned when searching without a game, so one campaign never sees another's memory
+var ErrEmptyScope = errors.New("search scope has no game")
+
+// SearchScope limits retrieval to a single game and, optionally, a single player
+type SearchScope struct {
+	GameID string
+	UserID int64 // 0 matches every player of the game
+
+	// LocationID ranks documents tagged with the party location higher without
+	// excluding others. Retrievers that cannot rank by it ignore it.
+	LocationID string
+}
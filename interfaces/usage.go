@@ -0,0 +1,33 @@
+package interfaces
+
+import "context"
+
+// Usage kinds
+const (
+	UsageChat      = "chat"
+	UsageEmbedding = "embedding"
+)
+
+// Usage is the token count of a single provider call
+type Usage struct {
+	Provider         string
+	Model            string
+	Kind             string // UsageChat or UsageEmbedding
+	PromptTokens     int
+	CompletionTokens int
+}
+
+type usageReporterKey struct{}
+
+// WithUsageReporter returns a context whose provider calls report their usage to report.
+// The caller attaches what the provider cannot know, such as the chat and the game.
+func WithUsageReporter(ctx context.Context, report func(Usage)) context.Context {
+	return context.WithValue(ctx, usageReporterKey{}, report)
+}
+
+// ReportUsage passes usage to the reporter of ctx, calls without one are not accounted
+func ReportUsage(ctx context.Context, usage Usage) {
+	if report, ok := ctx.Value(usageReporterKey{}).(func(Usage)); ok && report != nil {
+		report(usage)
+	}
+}
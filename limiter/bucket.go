@@ -0,0 +1,49 @@
+package limiter
+
+import "time"
+
+// bucket is a token bucket that refills perMinute requests per minute up to a burst of perMinute
+type bucket struct {
+	capacity float64
+	rate     float64 // tokens per second
+	tokens   float64
+	updated  time.Time
+}
+
+// unlimited is the bucket of disabled limits
+var unlimited = &bucket{}
+
+func newBucket(perMinute int, now time.Time) *bucket {
+	return &bucket{
+		capacity: float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		tokens:   float64(perMinute),
+		updated:  now,
+	}
+}
+
+// ready refills the bucket and reports whether a request fits
+func (b *bucket) ready(now time.Time) bool {
+	if b == unlimited {
+		return true
+	}
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.rate
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+		b.updated = now
+	}
+	return b.tokens >= 1
+}
+
+func (b *bucket) take() {
+	if b != unlimited {
+		b.tokens--
+	}
+}
+
+// idle reports whether the bucket would be full by now, so forgetting it changes nothing
+func (b *bucket) idle(now time.Time) bool {
+	return b.tokens+now.Sub(b.updated).Seconds()*b.rate >= b.capacity
+}
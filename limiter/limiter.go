@@ -0,0 +1,265 @@
+// Package limiter guards the bot against users and chats that would burn the LLM budget.
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go-llm-rpggamemaster/usage"
+)
+
+// Config limits requests. Zero values disable a limit.
+type Config struct {
+	UserRequestsPerMinute int
+	ChatRequestsPerMinute int
+	UserDailyTokens       int // across all chats of a player
+	ChatDailyTokens       int
+
+	// When an allow list is not empty, only the listed chats or users may play
+	AllowChats []int64
+	AllowUsers []int64
+	DenyChats  []int64
+	DenyUsers  []int64
+
+	// NoticeInterval is how often a rejected chat is told about the limit; rejections in between are silent
+	NoticeInterval time.Duration
+}
+
+// DefaultConfig returns limits for a small public bot
+func DefaultConfig() *Config {
+	return &Config{
+		UserRequestsPerMinute: 10,
+		ChatRequestsPerMinute: 30,
+		NoticeInterval:        time.Minute,
+	}
+}
+
+// Verdict is the outcome of a check
+type Verdict uint8
+
+const (
+	Allowed Verdict = iota
+	Denied
+	RateLimited
+	QuotaExceeded
+	QuotaUnavailable
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Allowed:
+		return "allowed"
+	case Denied:
+		return "denied"
+	case RateLimited:
+		return "rate_limited"
+	case QuotaExceeded:
+		return "quota_exceeded"
+	case QuotaUnavailable:
+		return "quota_unavailable"
+	default:
+		return fmt.Sprintf("Verdict(%d)", v)
+	}
+}
+
+// Decision is the verdict for a request. Notify is false for repeated rejections
+// within the notice interval, so the bot does not flood the chat with refusals.
+type Decision struct {
+	Verdict Verdict
+	Notify  bool
+}
+
+// Allowed reports whether the request may proceed
+func (d Decision) Allowed() bool {
+	return d.Verdict == Allowed
+}
+
+// UsageSource sums up the tokens already spent
+type UsageSource interface {
+	Totals(ctx context.Context, filter usage.Filter) (usage.Totals, error)
+}
+
+// Limiter applies access lists, request rates and daily token quotas.
+// Request rates are kept in memory; daily quotas are counted from the persisted usage,
+// so restarts do not reset them.
+type Limiter struct {
+	config *Config
+	usage  UsageSource
+	now    func() time.Time
+
+	allowChats, allowUsers map[int64]bool
+	denyChats, denyUsers   map[int64]bool
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	notices   map[string]time.Time
+	lastSweep time.Time
+
+	failures atomic.Int64
+}
+
+// New creates a limiter. The usage source is required only for daily quotas.
+func New(config *Config, source UsageSource) (*Limiter, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if (config.UserDailyTokens > 0 || config.ChatDailyTokens > 0) && source == nil {
+		return nil, fmt.Errorf("daily token quotas need a usage source")
+	}
+	return &Limiter{
+		config:     config,
+		usage:      source,
+		now:        time.Now,
+		allowChats: set(config.AllowChats),
+		allowUsers: set(config.AllowUsers),
+		denyChats:  set(config.DenyChats),
+		denyUsers:  set(config.DenyUsers),
+		buckets:    make(map[string]*bucket),
+		notices:    make(map[string]time.Time),
+	}, nil
+}
+
+// Allow checks the access lists and request rates and counts the request
+func (l *Limiter) Allow(chatID, userID int64) Decision {
+	if !l.permitted(chatID, userID) {
+		return l.reject(chatID, Denied)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	user := l.bucket(fmt.Sprintf("user:%d", userID), l.config.UserRequestsPerMinute)
+	chat := l.bucket(fmt.Sprintf("chat:%d", chatID), l.config.ChatRequestsPerMinute)
+	if !user.ready(now) || !chat.ready(now) {
+		return l.rejectLocked(chatID, RateLimited, now)
+	}
+	user.take()
+	chat.take()
+	return Decision{Verdict: Allowed}
+}
+
+// Quota checks the daily token quotas of a player and a chat.
+// Usage lookups that fail reject the request: the budget is not spent while the quota cannot be checked.
+func (l *Limiter) Quota(ctx context.Context, chatID, userID int64) Decision {
+	if l.config.UserDailyTokens <= 0 && l.config.ChatDailyTokens <= 0 {
+		return Decision{Verdict: Allowed}
+	}
+	since := dayStart(l.now())
+
+	// A zero user ID would match the usage of every player, so messages without a sender count for the chat only
+	if l.config.UserDailyTokens > 0 && userID != 0 {
+		if decision := l.check(ctx, chatID, usage.Filter{UserID: userID, Since: since}, l.config.UserDailyTokens); !decision.Allowed() {
+			return decision
+		}
+	}
+	if l.config.ChatDailyTokens > 0 {
+		if decision := l.check(ctx, chatID, usage.Filter{ChatID: chatID, Since: since}, l.config.ChatDailyTokens); !decision.Allowed() {
+			return decision
+		}
+	}
+	return Decision{Verdict: Allowed}
+}
+
+func (l *Limiter) permitted(chatID, userID int64) bool {
+	if l.denyChats[chatID] || l.denyUsers[userID] {
+		return false
+	}
+	if len(l.allowChats) > 0 && !l.allowChats[chatID] {
+		return false
+	}
+	if len(l.allowUsers) > 0 && !l.allowUsers[userID] {
+		return false
+	}
+	return true
+}
+
+// check compares the tokens spent under a filter with a daily quota
+func (l *Limiter) check(ctx context.Context, chatID int64, filter usage.Filter, quota int) Decision {
+	totals, err := l.usage.Totals(ctx, filter)
+	if err != nil {
+		failures := l.failures.Add(1)
+		log.Error().
+			Err(err).
+			Int64("chat_id", filter.ChatID).
+			Int64("user_id", filter.UserID).
+			Int64("failures", failures).
+			Msg("Failed to check token quota")
+		return l.reject(chatID, QuotaUnavailable)
+	}
+	if totals.Tokens() >= quota {
+		return l.reject(chatID, QuotaExceeded)
+	}
+	return Decision{Verdict: Allowed}
+}
+
+// Failures returns how many usage lookups have failed since the limiter was created
+func (l *Limiter) Failures() int64 {
+	return l.failures.Load()
+}
+
+func (l *Limiter) reject(chatID int64, verdict Verdict) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rejectLocked(chatID, verdict, l.now())
+}
+
+// rejectLocked notifies a chat of a verdict at most once per notice interval
+func (l *Limiter) rejectLocked(chatID int64, verdict Verdict, now time.Time) Decision {
+	key := fmt.Sprintf("%d:%s", chatID, verdict)
+	if last, ok := l.notices[key]; ok && now.Sub(last) < l.config.NoticeInterval {
+		return Decision{Verdict: verdict}
+	}
+	l.notices[key] = now
+	return Decision{Verdict: verdict, Notify: true}
+}
+
+func (l *Limiter) bucket(key string, perMinute int) *bucket {
+	if perMinute <= 0 {
+		return unlimited
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = newBucket(perMinute, l.now())
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// sweep forgets idle buckets and old notices once a minute, so memory follows active users only
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.idle(now) {
+			delete(l.buckets, key)
+		}
+	}
+	for key, last := range l.notices {
+		if now.Sub(last) >= l.config.NoticeInterval {
+			delete(l.notices, key)
+		}
+	}
+}
+
+// dayStart returns the start of the UTC day of t, when daily quotas reset
+func dayStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func set(ids []int64) map[int64]bool {
+	m := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		m[id] = true
+	}
+	return m
+}
@@ -0,0 +1,179 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-llm-rpggamemaster/usage"
+)
+
+// MockUsage returns fixed totals and records the filters it is asked for
+type MockUsage struct {
+	tokens  map[int64]int // by user ID, or by chat ID for chat filters
+	err     error
+	filters []usage.Filter
+}
+
+func (m *MockUsage) Totals(ctx context.Context, filter usage.Filter) (usage.Totals, error) {
+	m.filters = append(m.filters, filter)
+	if m.err != nil {
+		return usage.Totals{}, m.err
+	}
+	return usage.Totals{PromptTokens: m.tokens[filter.UserID+filter.ChatID]}, nil
+}
+
+// newTestLimiter creates a limiter with a clock the test controls
+func newTestLimiter(t *testing.T, config *Config, source UsageSource) (*Limiter, *time.Time) {
+	t.Helper()
+	l, err := New(config, source)
+	if err != nil {
+		t.Fatalf("creating limiter: %v", err)
+	}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(&Config{UserDailyTokens: 100}, nil); err == nil {
+		t.Error("expected error for quotas without a usage source")
+	}
+	if _, err := New(nil, nil); err != nil {
+		t.Errorf("unexpected error for default config: %v", err)
+	}
+}
+
+func TestLimiter_AccessLists(t *testing.T) {
+	cases := []struct {
+		name   string
+		config Config
+		chatID int64
+		userID int64
+		want   Verdict
+	}{
+		{"no lists", Config{}, 1, 10, Allowed},
+		{"denied user", Config{DenyUsers: []int64{10}}, 1, 10, Denied},
+		{"denied chat", Config{DenyChats: []int64{1}}, 1, 10, Denied},
+		{"allowed chat", Config{AllowChats: []int64{1}}, 1, 10, Allowed},
+		{"chat not in allow list", Config{AllowChats: []int64{2}}, 1, 10, Denied},
+		{"user not in allow list", Config{AllowUsers: []int64{20}}, 1, 10, Denied},
+		{"deny wins over allow", Config{AllowUsers: []int64{10}, DenyUsers: []int64{10}}, 1, 10, Denied},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			l, _ := newTestLimiter(t, &tc.config, nil)
+			if got := l.Allow(tc.chatID, tc.userID).Verdict; got != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLimiter_RequestRate(t *testing.T) {
+	t.Run("user limit refills over time", func(t *testing.T) {
+		l, now := newTestLimiter(t, &Config{UserRequestsPerMinute: 2, NoticeInterval: time.Minute}, nil)
+
+		for i := 0; i < 2; i++ {
+			if d := l.Allow(1, 10); !d.Allowed() {
+				t.Fatalf("request %d: expected allowed, got %s", i, d.Verdict)
+			}
+		}
+		if d := l.Allow(1, 10); d.Verdict != RateLimited || !d.Notify {
+			t.Errorf("expected a notified rate limit, got %+v", d)
+		}
+		if d := l.Allow(1, 10); d.Verdict != RateLimited || d.Notify {
+			t.Errorf("expected a silent rate limit, got %+v", d)
+		}
+		if d := l.Allow(1, 20); !d.Allowed() {
+			t.Errorf("expected other users to be unaffected, got %s", d.Verdict)
+		}
+
+		*now = now.Add(30 * time.Second)
+		if d := l.Allow(1, 10); !d.Allowed() {
+			t.Errorf("expected a refilled request after 30s, got %s", d.Verdict)
+		}
+	})
+
+	t.Run("chat limit covers all players", func(t *testing.T) {
+		l, _ := newTestLimiter(t, &Config{ChatRequestsPerMinute: 2}, nil)
+		l.Allow(1, 10)
+		l.Allow(1, 20)
+		if d := l.Allow(1, 30); d.Verdict != RateLimited {
+			t.Errorf("expected the chat to be limited, got %s", d.Verdict)
+		}
+		if d := l.Allow(2, 30); !d.Allowed() {
+			t.Errorf("expected other chats to be unaffected, got %s", d.Verdict)
+		}
+	})
+
+	t.Run("idle buckets are forgotten", func(t *testing.T) {
+		l, now := newTestLimiter(t, &Config{UserRequestsPerMinute: 2}, nil)
+		l.Allow(1, 10)
+		*now = now.Add(2 * time.Minute)
+		l.Allow(1, 20)
+		if _, ok := l.buckets["user:10"]; ok {
+			t.Error("expected the idle bucket to be swept")
+		}
+	})
+}
+
+func TestLimiter_Quota(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("user quota counts from the UTC day start", func(t *testing.T) {
+		source := &MockUsage{tokens: map[int64]int{10: 1000}}
+		l, _ := newTestLimiter(t, &Config{UserDailyTokens: 1000}, source)
+
+		if d := l.Quota(ctx, 1, 10); d.Verdict != QuotaExceeded || !d.Notify {
+			t.Errorf("expected an exceeded quota, got %+v", d)
+		}
+		if d := l.Quota(ctx, 1, 20); !d.Allowed() {
+			t.Errorf("expected other users within quota, got %s", d.Verdict)
+		}
+		want := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+		if f := source.filters[0]; f.UserID != 10 || f.ChatID != 0 || !f.Since.Equal(want) {
+			t.Errorf("unexpected filter %+v", f)
+		}
+	})
+
+	t.Run("chat quota", func(t *testing.T) {
+		source := &MockUsage{tokens: map[int64]int{1: 600}}
+		l, _ := newTestLimiter(t, &Config{ChatDailyTokens: 500}, source)
+		if d := l.Quota(ctx, 1, 10); d.Verdict != QuotaExceeded {
+			t.Errorf("expected an exceeded chat quota, got %s", d.Verdict)
+		}
+	})
+
+	t.Run("failed lookups reject the request", func(t *testing.T) {
+		source := &MockUsage{err: errors.New("db down")}
+		l, _ := newTestLimiter(t, &Config{UserDailyTokens: 1}, source)
+		if d := l.Quota(ctx, 1, 10); d.Verdict != QuotaUnavailable || !d.Notify {
+			t.Errorf("expected an unavailable quota, got %+v", d)
+		}
+		if n := l.Failures(); n != 1 {
+			t.Errorf("expected 1 failure, got %d", n)
+		}
+	})
+
+	t.Run("unknown users are checked against the chat quota only", func(t *testing.T) {
+		source := &MockUsage{tokens: map[int64]int{0: 5000, 1: 100}}
+		l, _ := newTestLimiter(t, &Config{UserDailyTokens: 1000, ChatDailyTokens: 500}, source)
+		if d := l.Quota(ctx, 1, 0); !d.Allowed() {
+			t.Errorf("expected allowed, got %s", d.Verdict)
+		}
+		if len(source.filters) != 1 || source.filters[0].ChatID != 1 || source.filters[0].UserID != 0 {
+			t.Errorf("expected only the chat lookup, got %+v", source.filters)
+		}
+	})
+
+	t.Run("no quotas skip the lookup", func(t *testing.T) {
+		source := &MockUsage{}
+		l, _ := newTestLimiter(t, &Config{}, source)
+		l.Quota(ctx, 1, 10)
+		if len(source.filters) != 0 {
+			t.Errorf("expected no usage lookups, got %+v", source.filters)
+		}
+	})
+}
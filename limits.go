@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+
+	"go-llm-rpggamemaster/config"
+	"go-llm-rpggamemaster/limiter"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+)
+
+// limitNotices are the polite refusals of each verdict
+var limitNotices = map[limiter.Verdict]string{
+	limiter.Denied:           "Извините, в этом чате бот недоступен.",
+	limiter.RateLimited:      "Не так быстро! Мастер не успевает за вами — подождите минутку.",
+	limiter.QuotaExceeded:    "На сегодня лимит приключений исчерпан. Мастер ждёт вас завтра!",
+	limiter.QuotaUnavailable: "Мастер не может сверить лимиты приключений. Попробуйте чуть позже.",
+}
+
+// newLimiter applies the configured limits; daily quotas are counted from the usage service
+func newLimiter(cfg config.LimitsConfig) (*limiter.Limiter, error) {
+	limits := limiter.DefaultConfig()
+	limits.UserRequestsPerMinute = cfg.UserRequestsPerMinute
+	limits.ChatRequestsPerMinute = cfg.ChatRequestsPerMinute
+	limits.UserDailyTokens = cfg.UserDailyTokens
+	limits.ChatDailyTokens = cfg.ChatDailyTokens
+	limits.AllowChats = cfg.AllowChats
+	limits.AllowUsers = cfg.AllowUsers
+	limits.DenyChats = cfg.DenyChats
+	limits.DenyUsers = cfg.DenyUsers
+	return limiter.New(limits, usageTracker)
+}
+
+// limitMiddleware drops messages from denied or too eager users and chats before any handler runs
+func limitMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		if update.Message == nil || update.Message.From == nil {
+			next(ctx, b, update)
+			return
+		}
+		chatID := update.Message.Chat.ID
+
+		decision := limits.Allow(chatID, update.Message.From.ID)
+		if !decision.Allowed() {
+			rejectLimited(ctx, b, chatID, update.Message.From.ID, decision)
+			return
+		}
+		next(ctx, b, update)
+	}
+}
+
+// withinQuota checks the daily token quotas before a turn and tells the chat when they are spent
+func withinQuota(ctx context.Context, b *bot.Bot, chatID, userID int64) bool {
+	decision := limits.Quota(ctx, chatID, userID)
+	if decision.Allowed() {
+		return true
+	}
+	rejectLimited(ctx, b, chatID, userID, decision)
+	return false
+}
+
+func rejectLimited(ctx context.Context, b *bot.Bot, chatID, userID int64, decision limiter.Decision) {
+	log.Info().
+		Int64("chat_id", chatID).
+		Int64("user_id", userID).
+		Stringer("verdict", decision.Verdict).
+		Bool("notify", decision.Notify).
+		Msg("Request limited")
+
+	if decision.Notify {
+		reply(ctx, b, chatID, limitNotices[decision.Verdict])
+	}
+}
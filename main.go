@@ -7,19 +7,42 @@ import (
 	"os/signal"
 	"strings"
 
+	"go-llm-rpggamemaster/character"
 	"go-llm-rpggamemaster/config"
+	"go-llm-rpggamemaster/dice"
 	factory "go-llm-rpggamemaster/factory"
 	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/limiter"
+	"go-llm-rpggamemaster/prompts"
+	"go-llm-rpggamemaster/quest"
 	"go-llm-rpggamemaster/retrievers"
+	postgresretriever "go-llm-rpggamemaster/retrievers/postgres"
+	"go-llm-rpggamemaster/session"
+	"go-llm-rpggamemaster/tools"
+	"go-llm-rpggamemaster/usage"
+	"go-llm-rpggamemaster/world"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
 var llmProvider interfaces.InferenceProvider
 var retriever retrievers.Retriever
+var sessions *session.Manager
+var memoryWriter *session.MemoryWriter
+var characters character.Store
+var quests *quest.Service
+var worldMap *world.Service
+var usageTracker *usage.Service
+var limits *limiter.Limiter
+var promptLibrary *prompts.Library
+var botAdmins = make(map[int64]bool)
+var dbPool *pgxpool.Pool
+var gameTools = tools.NewRegistry()
+var roller = dice.NewRandomRoller()
 
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
@@ -28,11 +51,20 @@ func main() {
 	go func() {
 		<-ctx.Done()
 		log.Info().Msg("Shutting down...")
+		if sessions != nil {
+			sessions.Wait()
+		}
+		if memoryWriter != nil {
+			memoryWriter.Close()
+		}
 		if retriever != nil {
 			if closer, ok := retriever.(interface{ Close() }); ok {
 				closer.Close()
 			}
 		}
+		if dbPool != nil {
+			dbPool.Close()
+		}
 	}()
 
 	cfg, err := config.LoadConfig()
@@ -69,8 +101,113 @@ func main() {
 		log.Info().Msgf("Retriever initialized: %s", cfg.VectorRetriever.Type)
 	}
 
+	sessionStore, err := newSessionStore(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create session store")
+	}
+	sessions, err = session.NewManager(llmProvider, sessionStore, session.DefaultConfig())
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create session manager")
+	}
+	characters, err = newCharacterStore()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create character store")
+	}
+	characterContext := character.NewContextSource(characters)
+	sessions.AddContextSource(characterContext)
+
+	quests, err = newQuestService()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create quest service")
+	}
+	sessions.AddContextSource(quests)
+
+	worldMap, err = newWorldService()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create world service")
+	}
+	sessions.AddContextSource(worldMap)
+	sessions.SetLocationFunc(worldMap.LocationID)
+
+	usageTracker, err = newUsageService(cfg.Usage)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create usage service")
+	}
+	sessions.SetUsageRecorder(usageTracker)
+
+	limits, err = newLimiter(cfg.Limits)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create limiter")
+	}
+
+	if err := gameTools.Register(dice.NewTool(roller)); err != nil {
+		log.Fatal().Err(err).Msg("failed to register dice tool")
+	}
+	if err := gameTools.Register(character.NewInventoryTool(characters)); err != nil {
+		log.Fatal().Err(err).Msg("failed to register inventory tool")
+	}
+	if err := gameTools.Register(quest.NewTool(quests)); err != nil {
+		log.Fatal().Err(err).Msg("failed to register quest tool")
+	}
+	sessions.SetTools(gameTools)
+
+	window := session.DefaultContextConfig()
+	if limit := cfg.InferenceContextWindow(); limit > 0 {
+		window.ContextWindow = limit
+	}
+	contextWindow, err := session.NewContextWindow(llmProvider, retriever, window)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create context window")
+	}
+	sessions.SetContextWindow(contextWindow)
+
+	promptLibrary, err = newPromptLibrary(cfg.Prompts)
+	if err != nil {
+		log.Warn().Err(err).Msg("Prompt templates not loaded, using the built-in system prompt")
+	} else {
+		sessions.SetPrompts(promptLibrary)
+	}
+	for _, id := range cfg.Admins {
+		botAdmins[id] = true
+	}
+	if retriever != nil {
+		assemblerConfig := session.DefaultAssemblerConfig()
+		assemblerConfig.MinScores = cfg.VectorRetriever.MinScores
+		if topN := cfg.VectorRetriever.Rerank.TopN; topN > 0 {
+			assemblerConfig.RerankTopN = topN
+		}
+		assembler, err := session.NewAssembler(retriever, assemblerConfig)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create context assembler")
+		}
+		reranker, err := newReranker(cfg.VectorRetriever.Rerank)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create reranker")
+		}
+		if reranker != nil {
+			assembler.SetReranker(reranker)
+			log.Info().Str("reranker", reranker.Name()).Int("top_n", assemblerConfig.RerankTopN).Msg("Retrieval reranking enabled")
+		}
+		sessions.SetAssembler(assembler)
+
+		memoryWriter, err = session.NewMemoryWriter(retriever, session.DefaultMemoryWriterConfig())
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create memory writer")
+		}
+		memoryWriter.AddTagger(func(ctx context.Context, gameID string, userID int64) map[string]string {
+			return map[string]string{
+				session.MetadataCharacterID: characterContext.CharacterID(ctx, gameID, userID),
+				session.MetadataLocationID:  worldMap.LocationID(ctx, gameID),
+				session.MetadataQuestID:     quests.ActiveQuestID(ctx, gameID),
+			}
+		})
+		memoryWriter.SetUsageRecorder(usageTracker)
+		sessions.SetMemoryWriter(memoryWriter)
+	}
+
 	opts := []bot.Option{
 		bot.WithDefaultHandler(gptHandler),
+		bot.WithMiddlewares(limitMiddleware),
 	}
 	token := os.Getenv("RPG_TELEGRAM_BOT_API_KEY")
 	b, err := bot.New(token, opts...)
@@ -80,6 +217,20 @@ func main() {
 
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/echo", bot.MatchTypePrefix, echoHandler)
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/status", bot.MatchTypePrefix, userStatusHandler)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/roll", bot.MatchTypePrefix, rollHandler)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/newchar", bot.MatchTypePrefix, newCharacterHandler)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/sheet", bot.MatchTypePrefix, sheetHandler)
+	// Handlers are matched in registration order, so /quests must come before /quest
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/quests", bot.MatchTypePrefix, questsHandler)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/quest", bot.MatchTypePrefix, questHandler)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/where", bot.MatchTypePrefix, whereHandler)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/go", bot.MatchTypePrefix, goHandler)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/map", bot.MatchTypePrefix, mapHandler)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/usage", bot.MatchTypePrefix, usageHandler)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/setting", bot.MatchTypePrefix, settingHandler)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/textsearch", bot.MatchTypePrefix, textSearchHandler)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/forget", bot.MatchTypePrefix, forgetHandler)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/why", bot.MatchTypePrefix, whyHandler)
 	b.Start(ctx)
 }
 
@@ -88,7 +239,7 @@ func gptHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 		return
 	}
 
-	prompt := strings.TrimSpace(update.Message.Text[len("/gpt"):])
+	prompt := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/gpt"))
 	if prompt == "" {
 		_, err := b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: update.Message.Chat.ID,
@@ -101,33 +252,84 @@ func gptHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 		return
 	}
 
-	messages := []interfaces.Message{
-		{Role: "user", Content: prompt},
+	var userID int64
+	if update.Message.From != nil {
+		userID = update.Message.From.ID
+	}
+	if !withinQuota(ctx, b, update.Message.Chat.ID, userID) {
+		return
+	}
+
+	streamer, err := newMessageStreamer(ctx, b, update.Message.Chat.ID)
+	if err != nil {
+		log.Err(err).Msg("failed to send message")
+		return
 	}
-	response, err := llmProvider.GenerateResponse(ctx, messages, 0.7, 0)
+
+	response, err := sessions.PlayStream(ctx, update.Message.Chat.ID, userID, prompt, streamer.OnDelta(ctx))
 	if err != nil {
 		log.Err(err).Msg("failed to get response from LLM provider")
-		_, err = b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: update.Message.Chat.ID,
-			Text:   fmt.Sprintf("Ошибка обращения к %s: %s", llmProvider.Name(), err.Error()),
-		})
-		if err != nil {
-			log.Err(err).Msg("failed to send error message")
-			return
-		}
+		streamer.Finish(ctx, fmt.Sprintf("Ошибка обращения к %s: %s", llmProvider.Name(), err.Error()))
+		return
+	}
+
+	streamer.Finish(ctx, response)
+}
+
+// rollHandler rolls dice for a player and records the result in the campaign history
+func rollHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil {
 		return
 	}
 
+	notation := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/roll"))
+	if notation == "" {
+		notation = "1d20"
+	}
+
+	var text string
+	result, err := roller.Roll(notation)
+	if err != nil {
+		text = fmt.Sprintf("Не удалось бросить кубики: %s\nПримеры: /roll 2d6+3, /roll 4d6kh3, /roll d20adv, /roll 3d6!, /roll d%%", err.Error())
+	} else {
+		player := "Игрок"
+		if update.Message.From != nil {
+			player = update.Message.From.FirstName
+		}
+		text = fmt.Sprintf("🎲 %s бросает %s", player, result)
+
+		if err := sessions.AppendNote(ctx, update.Message.Chat.ID, text); err != nil {
+			log.Err(err).Int64("chat_id", update.Message.Chat.ID).Msg("failed to record roll")
+		}
+	}
+
 	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID: update.Message.Chat.ID,
-		Text:   response,
+		Text:   text,
 	})
 	if err != nil {
-		log.Err(err).Msg("failed to send response message")
+		log.Err(err).Msg("failed to send message")
 		return
 	}
 }
 
+// newSessionStore persists campaigns in PostgreSQL when DATABASE_URL is set
+func newSessionStore(ctx context.Context) (session.Store, error) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Warn().Msg("DATABASE_URL is not set, game sessions will not survive a restart")
+		return session.NewMemoryStore(), nil
+	}
+
+	pool, err := postgresretriever.NewPool(ctx, dbURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating session database pool: %w", err)
+	}
+	dbPool = pool
+
+	return session.NewPostgresStore(pool)
+}
+
 func userStatusHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	if update.Message == nil {
 		return
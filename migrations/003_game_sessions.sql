@@ -0,0 +1,9 @@
+-- Migration: Game Sessions
+-- Description: Bind games to Telegram chats and persist the running conversation
+-- Dependencies: 001_initial_schema.sql
+
+ALTER TABLE games ADD COLUMN IF NOT EXISTS chat_id BIGINT;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS history JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+-- One active campaign per chat
+CREATE UNIQUE INDEX IF NOT EXISTS idx_games_chat ON games(chat_id);
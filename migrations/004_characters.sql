@@ -0,0 +1,8 @@
+-- Migration: Characters
+-- Description: Bind character sheets to the players of a game
+-- Dependencies: 001_initial_schema.sql
+
+ALTER TABLE characters ADD COLUMN IF NOT EXISTS user_id BIGINT;
+
+-- One active character per player in a game
+CREATE UNIQUE INDEX IF NOT EXISTS idx_characters_game_user ON characters(game_id, user_id);
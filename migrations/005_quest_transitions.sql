@@ -0,0 +1,25 @@
+-- Migration: Quest Transitions
+-- Description: Constrain quest statuses and record every status change
+-- Dependencies: 001_initial_schema.sql
+
+ALTER TABLE quests ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+
+UPDATE quests SET status = 'active' WHERE status IS NULL OR status NOT IN ('active', 'completed', 'failed');
+ALTER TABLE quests ALTER COLUMN status SET NOT NULL;
+ALTER TABLE quests DROP CONSTRAINT IF EXISTS quests_status_check;
+ALTER TABLE quests ADD CONSTRAINT quests_status_check CHECK (status IN ('active', 'completed', 'failed'));
+
+-- Quests are addressed by name within a game
+CREATE UNIQUE INDEX IF NOT EXISTS idx_quests_game_name ON quests(game_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS quest_transitions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    quest_id UUID NOT NULL REFERENCES quests(id) ON DELETE CASCADE,
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    user_id BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_quest_transitions_quest ON quest_transitions(quest_id, created_at);
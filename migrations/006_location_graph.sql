@@ -0,0 +1,19 @@
+-- Migration: Location Graph
+-- Description: Connect locations with named exits and track the party position of each game
+-- Dependencies: 001_initial_schema.sql, 003_game_sessions.sql
+
+-- Locations are addressed by name within a game
+CREATE UNIQUE INDEX IF NOT EXISTS idx_locations_game_name ON locations(game_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS location_exits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    from_location_id UUID NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    to_location_id UUID NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Exit names are unique per location, so /go is unambiguous
+CREATE UNIQUE INDEX IF NOT EXISTS idx_location_exits_name ON location_exits(from_location_id, lower(name));
+
+ALTER TABLE games ADD COLUMN IF NOT EXISTS current_location_id UUID REFERENCES locations(id) ON DELETE SET NULL;
@@ -0,0 +1,20 @@
+-- Migration: Usage Accounting
+-- Description: Token usage and cost of every provider call, attributed to chat, player and game
+-- Dependencies: 001_initial_schema.sql
+
+CREATE TABLE IF NOT EXISTS usage_records (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    game_id UUID REFERENCES games(id) ON DELETE SET NULL,
+    chat_id BIGINT NOT NULL DEFAULT 0,
+    user_id BIGINT NOT NULL DEFAULT 0,
+    provider TEXT NOT NULL,
+    model TEXT NOT NULL DEFAULT '',
+    kind TEXT NOT NULL CHECK (kind IN ('chat', 'embedding')),
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_records_game ON usage_records(game_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_usage_records_created ON usage_records(created_at);
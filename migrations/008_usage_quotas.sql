@@ -0,0 +1,6 @@
+-- Migration: Usage Quotas
+-- Description: Indexes for the daily token quotas of players and chats
+-- Dependencies: 007_usage.sql
+
+CREATE INDEX IF NOT EXISTS idx_usage_records_user ON usage_records(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_usage_records_chat ON usage_records(chat_id, created_at);
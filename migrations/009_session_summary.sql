@@ -0,0 +1,5 @@
+-- Migration: Session Summary
+-- Description: Keep the story so far of turns compacted out of the session history
+-- Dependencies: 003_game_sessions.sql
+
+ALTER TABLE games ADD COLUMN IF NOT EXISTS summary TEXT NOT NULL DEFAULT '';
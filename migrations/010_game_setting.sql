@@ -0,0 +1,5 @@
+-- Migration: Game Setting
+-- Description: Remember the prompt preset (genre) chosen for each game
+-- Dependencies: 003_game_sessions.sql
+
+ALTER TABLE games ADD COLUMN IF NOT EXISTS setting TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,108 @@
+-- Migration: Text Search Configuration
+-- Description: Per-game full-text search configuration with a generated tsvector column.
+--              Replaces the hardcoded 'english' configuration, which does not stem Russian lore.
+-- Dependencies: 001_initial_schema.sql, 002_hybrid_search.sql
+
+-- Supported configurations: russian | english | simple (no stemming) | multilingual (russian + english)
+ALTER TABLE games ADD COLUMN IF NOT EXISTS search_config TEXT NOT NULL DEFAULT 'russian'
+    CHECK (search_config IN ('russian', 'english', 'simple', 'multilingual'));
+
+-- Each item carries the configuration of its game, since a generated column can only read its own row
+ALTER TABLE context_items ADD COLUMN IF NOT EXISTS search_config TEXT NOT NULL DEFAULT 'russian'
+    CHECK (search_config IN ('russian', 'english', 'simple', 'multilingual'));
+
+-- search_vector indexes content with a configuration
+CREATE OR REPLACE FUNCTION search_vector(config TEXT, content TEXT) RETURNS tsvector
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT CASE config
+        WHEN 'english' THEN to_tsvector('english'::regconfig, content)
+        WHEN 'simple' THEN to_tsvector('simple'::regconfig, content)
+        WHEN 'multilingual' THEN to_tsvector('russian'::regconfig, content) || to_tsvector('english'::regconfig, content)
+        ELSE to_tsvector('russian'::regconfig, content)
+    END
+$$;
+
+-- search_query parses a player query with the configuration its content was indexed with
+CREATE OR REPLACE FUNCTION search_query(config TEXT, query TEXT) RETURNS tsquery
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT CASE config
+        WHEN 'english' THEN plainto_tsquery('english'::regconfig, query)
+        WHEN 'simple' THEN plainto_tsquery('simple'::regconfig, query)
+        WHEN 'multilingual' THEN plainto_tsquery('russian'::regconfig, query) || plainto_tsquery('english'::regconfig, query)
+        ELSE plainto_tsquery('russian'::regconfig, query)
+    END
+$$;
+
+-- Backfill the configuration of existing items from their games
+UPDATE context_items ci
+SET search_config = g.search_config
+FROM games g
+WHERE g.id = ci.game_id
+  AND ci.search_config <> g.search_config;
+
+-- Adding a stored generated column computes it for every existing row
+ALTER TABLE context_items ADD COLUMN IF NOT EXISTS content_tsv tsvector
+    GENERATED ALWAYS AS (search_vector(search_config, content)) STORED;
+
+DROP INDEX IF EXISTS idx_content_fts;
+CREATE INDEX IF NOT EXISTS idx_context_items_tsv ON context_items USING GIN(content_tsv);
+
+-- New items take the configuration of their game
+CREATE OR REPLACE FUNCTION context_items_search_config() RETURNS trigger AS $$
+BEGIN
+    SELECT g.search_config INTO NEW.search_config FROM games g WHERE g.id = NEW.game_id;
+    NEW.search_config := COALESCE(NEW.search_config, 'russian');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS context_items_search_config ON context_items;
+CREATE TRIGGER context_items_search_config
+    BEFORE INSERT ON context_items
+    FOR EACH ROW EXECUTE FUNCTION context_items_search_config();
+
+-- Changing the configuration of a game reindexes its items
+CREATE OR REPLACE FUNCTION games_search_config() RETURNS trigger AS $$
+BEGIN
+    UPDATE context_items SET search_config = NEW.search_config WHERE game_id = NEW.id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS games_search_config ON games;
+CREATE TRIGGER games_search_config
+    AFTER UPDATE OF search_config ON games
+    FOR EACH ROW
+    WHEN (OLD.search_config IS DISTINCT FROM NEW.search_config)
+    EXECUTE FUNCTION games_search_config();
+
+-- Keyword search function, now using the game configuration and the GIN index
+CREATE OR REPLACE FUNCTION keyword_search(
+    keyword_query TEXT,
+    p_game_id UUID,
+    p_limit INTEGER DEFAULT 10
+) RETURNS TABLE (
+    id UUID,
+    content TEXT,
+    metadata JSONB,
+    rank INTEGER
+) AS $$
+DECLARE
+    q tsquery := search_query(
+        COALESCE((SELECT g.search_config FROM games g WHERE g.id = p_game_id), 'russian'),
+        keyword_query
+    );
+BEGIN
+    RETURN QUERY
+    SELECT
+        ci.id,
+        ci.content,
+        ci.metadata,
+        ROW_NUMBER() OVER (ORDER BY ts_rank(ci.content_tsv, q) DESC)::INTEGER as rank
+    FROM context_items ci
+    WHERE ci.game_id = p_game_id
+      AND ci.content_tsv @@ q
+    ORDER BY ts_rank(ci.content_tsv, q) DESC
+    LIMIT p_limit;
+END;
+$$ LANGUAGE plpgsql;
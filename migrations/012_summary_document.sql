@@ -0,0 +1,6 @@
+-- Migration: Summary Document
+-- Description: Remember the campaign memory document that stores the summary of each game,
+--              so a new summary replaces exactly that document once it is written
+-- Dependencies: 009_session_summary.sql
+
+ALTER TABLE games ADD COLUMN IF NOT EXISTS summary_id TEXT NOT NULL DEFAULT '';
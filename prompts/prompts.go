@@ -0,0 +1,179 @@
+// Package prompts renders game-master system prompts from text/template files.
+//
+// A template directory holds shared templates (*.tmpl) that define the "system" entry point
+// and its blocks, and genre presets (presets/<name>.tmpl) that override some of the blocks.
+// Every preset should define "title", its human-readable name.
+package prompts
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/rs/zerolog/log"
+)
+
+// entryTemplate is the template rendered for a system prompt
+const entryTemplate = "system"
+
+// Data is the state a system prompt is rendered from
+type Data struct {
+	Setting   string   // Preset name
+	Game      string   // Game name
+	Summary   string   // Story so far
+	State     []string // Game state of the turn, such as the character sheet, quests and location
+	Documents []string // Retrieved campaign memory
+}
+
+// Library holds the parsed presets of a template directory. It is safe for concurrent use.
+type Library struct {
+	dir           string
+	defaultPreset string
+
+	mu      sync.RWMutex
+	presets map[string]*template.Template
+}
+
+// Load parses a template directory. The default preset is used for games without a setting.
+func Load(dir, defaultPreset string) (*Library, error) {
+	l := &Library{dir: dir, defaultPreset: defaultPreset}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reload parses the directory again. On error the previously loaded templates stay in use.
+func (l *Library) Reload() error {
+	presets, err := parse(l.dir)
+	if err != nil {
+		return err
+	}
+	if _, ok := presets[l.defaultPreset]; !ok {
+		return fmt.Errorf("default preset %q not found in %s", l.defaultPreset, l.dir)
+	}
+
+	l.mu.Lock()
+	l.presets = presets
+	l.mu.Unlock()
+
+	log.Info().
+		Str("dir", l.dir).
+		Int("presets", len(presets)).
+		Msg("Prompt templates loaded")
+	return nil
+}
+
+// Default returns the preset of games without a setting
+func (l *Library) Default() string {
+	return l.defaultPreset
+}
+
+// Presets returns the preset names in alphabetical order
+func (l *Library) Presets() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	names := make([]string, 0, len(l.presets))
+	for name := range l.presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether a preset exists
+func (l *Library) Has(preset string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.presets[preset]
+	return ok
+}
+
+// Title returns the human-readable name of a preset, or the preset name when it defines none
+func (l *Library) Title(preset string) string {
+	l.mu.RLock()
+	tmpl, ok := l.presets[preset]
+	l.mu.RUnlock()
+
+	if !ok || tmpl.Lookup("title") == nil {
+		return preset
+	}
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, "title", nil); err != nil {
+		return preset
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Render renders the system prompt of a preset. Unknown or empty presets fall back to the default.
+func (l *Library) Render(preset string, data Data) (string, error) {
+	l.mu.RLock()
+	tmpl, ok := l.presets[preset]
+	if !ok {
+		preset = l.defaultPreset
+		tmpl = l.presets[preset]
+	}
+	l.mu.RUnlock()
+
+	data.Setting = preset
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, entryTemplate, data); err != nil {
+		return "", fmt.Errorf("rendering preset %s: %w", preset, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// parse reads the shared templates and builds every preset on top of them.
+// Each preset is rendered once with empty data, so broken templates fail here and not mid-game.
+func parse(dir string) (map[string]*template.Template, error) {
+	shared, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("listing templates: %w", err)
+	}
+	if len(shared) == 0 {
+		return nil, fmt.Errorf("no templates found in %s", dir)
+	}
+	base, err := template.New("prompts").Funcs(funcs).ParseFiles(shared...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing templates: %w", err)
+	}
+	if base.Lookup(entryTemplate) == nil {
+		return nil, fmt.Errorf("templates in %s do not define %q", dir, entryTemplate)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "presets", "*.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("listing presets: %w", err)
+	}
+	presets := make(map[string]*template.Template, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".tmpl")
+
+		tmpl, err := base.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("cloning templates for preset %s: %w", name, err)
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("reading preset %s: %w", name, err)
+		}
+		if _, err := tmpl.New(filepath.Base(file)).Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("parsing preset %s: %w", name, err)
+		}
+		if err := tmpl.ExecuteTemplate(&bytes.Buffer{}, entryTemplate, Data{Setting: name}); err != nil {
+			return nil, fmt.Errorf("checking preset %s: %w", name, err)
+		}
+		presets[name] = tmpl
+	}
+	return presets, nil
+}
+
+var funcs = template.FuncMap{
+	"join": strings.Join,
+}
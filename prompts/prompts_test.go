@@ -0,0 +1,142 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTemplates creates a template directory from file contents keyed by relative path
+func writeTemplates(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("creating dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestLoad_RepositoryTemplates(t *testing.T) {
+	lib, err := Load("../templates", "fantasy")
+	if err != nil {
+		t.Fatalf("loading templates: %v", err)
+	}
+
+	if got := strings.Join(lib.Presets(), ","); got != "cyberpunk,fantasy,horror" {
+		t.Errorf("unexpected presets %q", got)
+	}
+	if title := lib.Title("cyberpunk"); title != "Киберпанк" {
+		t.Errorf("unexpected title %q", title)
+	}
+
+	data := Data{
+		Game:      "Тени Севера",
+		Summary:   "Герои добрались до перевала.",
+		State:     []string{"Лист персонажа: Пиппин"},
+		Documents: []string{"Дракон спит на золоте"},
+	}
+	for _, preset := range lib.Presets() {
+		t.Run(preset, func(t *testing.T) {
+			prompt, err := lib.Render(preset, data)
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+			for _, want := range []string{"гейм-мастер", "«Тени Севера»", "Жанр:", "Правила:", "Формат ответа:",
+				"Герои добрались до перевала.", "Лист персонажа: Пиппин", "- Дракон спит на золоте"} {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("expected %q in prompt:\n%s", want, prompt)
+				}
+			}
+		})
+	}
+
+	t.Run("empty sections are left out", func(t *testing.T) {
+		prompt, _ := lib.Render("fantasy", Data{})
+		if strings.Contains(prompt, "Краткое содержание") || strings.Contains(prompt, "памяти кампании") {
+			t.Errorf("expected no empty sections:\n%s", prompt)
+		}
+	})
+}
+
+func TestLibrary(t *testing.T) {
+	base := map[string]string{
+		"system.tmpl":       `{{define "system"}}{{template "genre" .}} / {{.Setting}}{{end}}{{define "genre"}}обычный{{end}}`,
+		"presets/calm.tmpl": `{{define "title"}}Спокойный{{end}}`,
+		"presets/dark.tmpl": `{{define "genre"}}мрачный{{end}}`,
+	}
+
+	t.Run("presets override blocks", func(t *testing.T) {
+		lib, err := Load(writeTemplates(t, base), "calm")
+		if err != nil {
+			t.Fatalf("load: %v", err)
+		}
+		if prompt, _ := lib.Render("dark", Data{}); prompt != "мрачный / dark" {
+			t.Errorf("unexpected prompt %q", prompt)
+		}
+		if prompt, _ := lib.Render("calm", Data{}); prompt != "обычный / calm" {
+			t.Errorf("unexpected prompt %q", prompt)
+		}
+		if title := lib.Title("dark"); title != "dark" {
+			t.Errorf("expected the name for presets without a title, got %q", title)
+		}
+	})
+
+	t.Run("unknown preset falls back to the default", func(t *testing.T) {
+		lib, _ := Load(writeTemplates(t, base), "dark")
+		if prompt, _ := lib.Render("", Data{}); prompt != "мрачный / dark" {
+			t.Errorf("unexpected prompt %q", prompt)
+		}
+		if lib.Has("missing") {
+			t.Error("expected unknown preset to be missing")
+		}
+	})
+
+	t.Run("load errors", func(t *testing.T) {
+		cases := map[string]map[string]string{
+			"no templates":      {},
+			"no entry point":    {"other.tmpl": `{{define "other"}}x{{end}}`, "presets/calm.tmpl": ``},
+			"missing default":   {"system.tmpl": base["system.tmpl"]},
+			"syntax error":      {"system.tmpl": base["system.tmpl"], "presets/calm.tmpl": `{{define "genre"}}{{end`},
+			"execution failure": {"system.tmpl": base["system.tmpl"], "presets/calm.tmpl": `{{define "genre"}}{{template "missing"}}{{end}}`},
+		}
+		for name, files := range cases {
+			t.Run(name, func(t *testing.T) {
+				if _, err := Load(writeTemplates(t, files), "calm"); err == nil {
+					t.Error("expected error")
+				}
+			})
+		}
+	})
+
+	t.Run("reload keeps the old templates on error", func(t *testing.T) {
+		dir := writeTemplates(t, base)
+		lib, _ := Load(dir, "calm")
+
+		if err := os.WriteFile(filepath.Join(dir, "presets", "calm.tmpl"), []byte(`{{define "genre"}}{{end`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := lib.Reload(); err == nil {
+			t.Error("expected reload error")
+		}
+		if prompt, _ := lib.Render("calm", Data{}); prompt != "обычный / calm" {
+			t.Errorf("expected the old templates, got %q", prompt)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, "presets", "calm.tmpl"), []byte(`{{define "genre"}}тихий{{end}}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := lib.Reload(); err != nil {
+			t.Fatalf("reload: %v", err)
+		}
+		if prompt, _ := lib.Render("calm", Data{}); prompt != "тихий / calm" {
+			t.Errorf("expected the reloaded templates, got %q", prompt)
+		}
+	})
+}
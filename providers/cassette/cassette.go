@@ -0,0 +1,300 @@
+// Package cassette records HTTP interactions to a YAML file and replays them,
+// so provider tests run against real-looking traffic without network access.
+//
+// Requests are matched by method, URL path and body; JSON bodies are compared
+// after normalization, so key order and whitespace do not matter.
+// Credentials never reach the file: request headers are not stored and
+// configured secrets are replaced in URLs and bodies.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ModeEnv selects the mode of recorders created with ModeFromEnv
+const ModeEnv = "RPG_CASSETTE_MODE"
+
+// Redacted replaces secrets in recorded interactions
+const Redacted = "[REDACTED]"
+
+// ErrNoInteraction is returned in replay mode for requests missing from the cassette
+var ErrNoInteraction = errors.New("cassette: no recorded interaction")
+
+// Mode controls whether a recorder talks to the network
+type Mode uint8
+
+const (
+	// ModeReplay serves recorded responses and fails on unknown requests
+	ModeReplay Mode = iota
+	// ModeRecord sends every request and overwrites the cassette with the new interactions
+	ModeRecord
+	// ModeAuto replays known requests and records the rest
+	ModeAuto
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeRecord:
+		return "record"
+	case ModeAuto:
+		return "auto"
+	default:
+		return "replay"
+	}
+}
+
+// ModeFromEnv reads the mode from RPG_CASSETTE_MODE, replay by default
+func ModeFromEnv() Mode {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(ModeEnv))) {
+	case "record":
+		return ModeRecord
+	case "auto":
+		return ModeAuto
+	default:
+		return ModeReplay
+	}
+}
+
+// Interaction is a recorded request/response pair
+type Interaction struct {
+	Request  Request  `yaml:"request"`
+	Response Response `yaml:"response"`
+}
+
+// Request is the recorded part of an HTTP request
+type Request struct {
+	Method string `yaml:"method"`
+	URL    string `yaml:"url"`
+	Body   string `yaml:"body,omitempty"`
+}
+
+// Response is the recorded part of an HTTP response
+type Response struct {
+	Status      int    `yaml:"status"`
+	ContentType string `yaml:"content_type,omitempty"`
+	Body        string `yaml:"body"`
+}
+
+type file struct {
+	Interactions []Interaction `yaml:"interactions"`
+}
+
+// Recorder is an http.RoundTripper that records or replays a cassette file.
+// It is safe for concurrent use.
+type Recorder struct {
+	path    string
+	mode    Mode
+	next    http.RoundTripper
+	secrets []string
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// New opens the cassette at path. Replay and auto modes load the existing file,
+// replay mode requires it. A nil next uses http.DefaultTransport.
+func New(path string, mode Mode, next http.RoundTripper) (*Recorder, error) {
+	if path == "" {
+		return nil, fmt.Errorf("cassette path is required")
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	r := &Recorder{path: path, mode: mode, next: next}
+	if mode == ModeRecord {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && mode == ModeAuto {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading cassette: %w", err)
+	}
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("decoding cassette %s: %w", path, err)
+	}
+	r.interactions = f.Interactions
+	r.used = make([]bool, len(f.Interactions))
+	return r, nil
+}
+
+// Scrub registers secrets, such as API keys, to replace with Redacted before recording
+func (r *Recorder) Scrub(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, secret := range secrets {
+		if secret != "" {
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+}
+
+// Mode returns the recorder mode
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// RoundTrip replays the matching interaction or performs and records the request
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	recorded := Request{Method: req.Method, URL: req.URL.String(), Body: string(body)}
+	r.mu.Lock()
+	recorded = r.scrub(recorded)
+	if r.mode != ModeRecord {
+		if i := r.find(recorded); i >= 0 {
+			r.used[i] = true
+			resp := r.interactions[i].Response
+			r.mu.Unlock()
+			return resp.httpResponse(req), nil
+		}
+	}
+	r.mu.Unlock()
+
+	if r.mode == ModeReplay {
+		return nil, fmt.Errorf("%w for %s %s in %s", ErrNoInteraction, req.Method, req.URL.Path, r.path)
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, Interaction{
+		Request: recorded,
+		Response: Response{
+			Status:      resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+			Body:        r.scrubString(string(respBody)),
+		},
+	})
+	r.used = append(r.used, true)
+	if err := r.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// find returns the first unused matching interaction, or the last used one
+// so that repeated identical requests keep getting an answer
+func (r *Recorder) find(req Request) int {
+	found := -1
+	for i, interaction := range r.interactions {
+		if !matches(interaction.Request, req) {
+			continue
+		}
+		if !r.used[i] {
+			return i
+		}
+		found = i
+	}
+	return found
+}
+
+// save writes all interactions, the cassette is rewritten after every recorded request
+func (r *Recorder) save() error {
+	data, err := yaml.Marshal(file{Interactions: r.interactions})
+	if err != nil {
+		return fmt.Errorf("encoding cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("creating cassette directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, data, 0o644); err != nil {
+		return fmt.Errorf("writing cassette: %w", err)
+	}
+	return nil
+}
+
+func (r *Recorder) scrub(req Request) Request {
+	req.URL = r.scrubString(req.URL)
+	req.Body = r.scrubString(req.Body)
+	return req
+}
+
+func (r *Recorder) scrubString(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	return s
+}
+
+func (resp Response) httpResponse(req *http.Request) *http.Response {
+	header := make(http.Header)
+	if resp.ContentType != "" {
+		header.Set("Content-Type", resp.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.Status, http.StatusText(resp.Status)),
+		StatusCode:    resp.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(resp.Body)),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}
+}
+
+func matches(recorded, req Request) bool {
+	if recorded.Method != req.Method || requestPath(recorded.URL) != requestPath(req.URL) {
+		return false
+	}
+	return normalizeBody(recorded.Body) == normalizeBody(req.Body)
+}
+
+// requestPath drops the scheme and host, so cassettes recorded against one server replay against another
+func requestPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.RequestURI()
+}
+
+func normalizeBody(body string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return strings.TrimSpace(body)
+	}
+	normalized, err := json.Marshal(v)
+	if err != nil {
+		return strings.TrimSpace(body)
+	}
+	return string(normalized)
+}
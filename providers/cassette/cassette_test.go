@@ -0,0 +1,112 @@
+package cassette
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func post(t *testing.T, client *http.Client, url, body string) (string, error) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", url, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret-key")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return string(data), nil
+}
+
+func TestRecorder(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"call":%d,"echo":%q,"key":"secret-key"}`, calls, body)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.yml")
+
+	recorder, err := New(path, ModeRecord, nil)
+	if err != nil {
+		t.Fatalf("creating recorder: %v", err)
+	}
+	recorder.Scrub("secret-key")
+	client := &http.Client{Transport: recorder}
+	if _, err := post(t, client, server.URL+"/chat/completions", `{"model":"m","input":"a"}`); err != nil {
+		t.Fatalf("recording: %v", err)
+	}
+	if _, err := post(t, client, server.URL+"/embeddings", `{"model":"m","input":"b"}`); err != nil {
+		t.Fatalf("recording: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading cassette: %v", err)
+	}
+	if strings.Contains(string(data), "secret-key") || !strings.Contains(string(data), Redacted) {
+		t.Errorf("expected secrets to be scrubbed:\n%s", data)
+	}
+
+	server.Close()
+	replayer, err := New(path, ModeReplay, nil)
+	if err != nil {
+		t.Fatalf("opening cassette: %v", err)
+	}
+	client = &http.Client{Transport: replayer}
+
+	t.Run("json bodies match regardless of formatting", func(t *testing.T) {
+		body, err := post(t, client, "https://example.com/embeddings", `{ "input": "b", "model": "m" }`)
+		if err != nil {
+			t.Fatalf("replay: %v", err)
+		}
+		if !strings.Contains(body, `"call":2`) {
+			t.Errorf("expected the second recorded response, got %s", body)
+		}
+	})
+
+	t.Run("repeated request replays the last match", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			body, err := post(t, client, server.URL+"/chat/completions", `{"model":"m","input":"a"}`)
+			if err != nil || !strings.Contains(body, `"call":1`) {
+				t.Fatalf("unexpected replay %s: %v", body, err)
+			}
+		}
+	})
+
+	t.Run("unknown request fails", func(t *testing.T) {
+		_, err := post(t, client, server.URL+"/chat/completions", `{"model":"m","input":"c"}`)
+		if !errors.Is(err, ErrNoInteraction) {
+			t.Errorf("expected ErrNoInteraction, got %v", err)
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.yml")
+	if _, err := New(missing, ModeReplay, nil); err == nil {
+		t.Error("expected error for a missing cassette in replay mode")
+	}
+	if _, err := New(missing, ModeAuto, nil); err != nil {
+		t.Errorf("expected auto mode to start an empty cassette, got %v", err)
+	}
+
+	t.Setenv(ModeEnv, "Record")
+	if mode := ModeFromEnv(); mode != ModeRecord {
+		t.Errorf("expected record mode, got %s", mode)
+	}
+	t.Setenv(ModeEnv, "")
+	if mode := ModeFromEnv(); mode != ModeReplay {
+		t.Errorf("expected replay mode by default, got %s", mode)
+	}
+}
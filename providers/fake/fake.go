@@ -0,0 +1,246 @@
+// Package fake provides deterministic offline providers for development and tests.
+// Completions come from a script, embeddings are hashed bags of words.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+const (
+	// DefaultDimensions is the embedding size used when none is configured
+	DefaultDimensions = 256
+
+	// DefaultReply answers messages no turn or rule covers when the script sets no default
+	DefaultReply = "Мастер задумчиво молчит."
+)
+
+// Provider implements InferenceProvider and VectorEmbeddingProvider without network access
+type Provider struct {
+	script     *Script
+	dimensions int
+
+	mu   sync.Mutex
+	turn int
+	call int
+}
+
+var (
+	_ interfaces.InferenceProvider          = (*Provider)(nil)
+	_ interfaces.StreamingInferenceProvider = (*Provider)(nil)
+	_ interfaces.ToolCallingProvider        = (*Provider)(nil)
+	_ interfaces.VectorEmbeddingProvider    = (*Provider)(nil)
+)
+
+// NewProvider creates a fake provider. A nil script answers everything with DefaultReply,
+// dimensions <= 0 selects DefaultDimensions.
+func NewProvider(script *Script, dimensions int) *Provider {
+	if script == nil {
+		script = &Script{}
+	}
+	if dimensions <= 0 {
+		dimensions = DefaultDimensions
+	}
+	return &Provider{script: script, dimensions: dimensions}
+}
+
+// NewProviderFromFile creates a fake provider with a YAML script. An empty path uses no script.
+func NewProviderFromFile(path string, dimensions int) (*Provider, error) {
+	if path == "" {
+		return NewProvider(nil, dimensions), nil
+	}
+	script, err := LoadScript(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewProvider(script, dimensions), nil
+}
+
+func (p *Provider) Name() string {
+	return "fake"
+}
+
+// Remaining returns the number of scripted turns not played yet
+func (p *Provider) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.script.Turns) - p.turn
+}
+
+func (p *Provider) GenerateResponse(ctx context.Context, messages []interfaces.Message, temperature float64, maxTokens int) (string, error) {
+	msg, err := p.next(ctx, messages, false)
+	if err != nil {
+		return "", err
+	}
+	return msg.Content, nil
+}
+
+// GenerateResponseStream delivers the reply word by word
+func (p *Provider) GenerateResponseStream(ctx context.Context, messages []interfaces.Message, temperature float64, maxTokens int, onDelta func(delta string) error) (string, error) {
+	msg, err := p.next(ctx, messages, false)
+	if err != nil {
+		return "", err
+	}
+	if err := stream(msg.Content, onDelta); err != nil {
+		return "", err
+	}
+	return msg.Content, nil
+}
+
+// GenerateWithTools returns scripted tool calls as they are, the tools themselves are not checked
+func (p *Provider) GenerateWithTools(ctx context.Context, messages []interfaces.Message, tools []interfaces.ToolDefinition, temperature float64, maxTokens int, onDelta func(delta string) error) (interfaces.Message, error) {
+	msg, err := p.next(ctx, messages, len(tools) > 0)
+	if err != nil {
+		return interfaces.Message{}, err
+	}
+	if onDelta != nil && len(msg.ToolCalls) == 0 {
+		if err := stream(msg.Content, onDelta); err != nil {
+			return interfaces.Message{}, err
+		}
+	}
+	return msg, nil
+}
+
+// next picks the reply to the conversation: the next turn, else the first matching rule, else the default
+func (p *Provider) next(ctx context.Context, messages []interfaces.Message, withTools bool) (interfaces.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return interfaces.Message{}, err
+	}
+	input := lastUserMessage(messages)
+	afterTool := len(messages) > 0 && messages[len(messages)-1].Role == "tool"
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var reply Reply
+	switch {
+	case p.turn < len(p.script.Turns):
+		reply = p.script.Turns[p.turn]
+		p.turn++
+		if reply.Expect != "" && !strings.Contains(strings.ToLower(input), strings.ToLower(reply.Expect)) {
+			return interfaces.Message{}, fmt.Errorf("turn %d: expected user message containing %q, got %q", p.turn, reply.Expect, input)
+		}
+	default:
+		reply = Reply{Reply: p.script.Default}
+		if reply.Reply == "" {
+			reply.Reply = DefaultReply
+		}
+		for _, rule := range p.script.Rules {
+			// A tool result answers the call, repeating the call would loop forever
+			if len(rule.ToolCalls) > 0 && (afterTool || !withTools) {
+				continue
+			}
+			if rule.matches(input) {
+				reply = rule.Reply
+				reply.Reply = rule.expand(input)
+				break
+			}
+		}
+	}
+
+	if len(reply.ToolCalls) > 0 && !withTools {
+		return interfaces.Message{}, fmt.Errorf("scripted tool call without tools in the request")
+	}
+
+	msg := interfaces.Message{
+		Role:    "assistant",
+		Content: strings.ReplaceAll(reply.Reply, "{{input}}", input),
+	}
+	// Words stand in for tokens, so usage accounting can be tested offline
+	prompt := 0
+	for _, m := range messages {
+		prompt += len(words(m.Content))
+	}
+	interfaces.ReportUsage(ctx, interfaces.Usage{
+		Provider:         p.Name(),
+		Kind:             interfaces.UsageChat,
+		PromptTokens:     prompt,
+		CompletionTokens: len(words(msg.Content)),
+	})
+	for _, call := range reply.ToolCalls {
+		p.call++
+		msg.ToolCalls = append(msg.ToolCalls, interfaces.ToolCall{
+			ID:        fmt.Sprintf("call_%d", p.call),
+			Name:      call.Name,
+			Arguments: call.Arguments,
+		})
+	}
+	return msg, nil
+}
+
+// EmbedDocuments hashes every word into a signed bucket and normalizes the vector,
+// so texts sharing words are similar and equal texts always embed equally
+func (p *Provider) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	embeddings := make([][]float32, len(texts))
+	prompt := 0
+	for i, text := range texts {
+		vector := make([]float32, p.dimensions)
+		for _, word := range words(text) {
+			prompt++
+			h := fnv.New64a()
+			h.Write([]byte(word))
+			sum := h.Sum64()
+			if sum&(1<<63) != 0 {
+				vector[sum%uint64(p.dimensions)]--
+			} else {
+				vector[sum%uint64(p.dimensions)]++
+			}
+		}
+		normalize(vector)
+		embeddings[i] = vector
+	}
+	interfaces.ReportUsage(ctx, interfaces.Usage{Provider: p.Name(), Kind: interfaces.UsageEmbedding, PromptTokens: prompt})
+	return embeddings, nil
+}
+
+func lastUserMessage(messages []interfaces.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+func stream(content string, onDelta func(delta string) error) error {
+	for _, word := range strings.SplitAfter(content, " ") {
+		if word == "" {
+			continue
+		}
+		if err := onDelta(word); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func normalize(vector []float32) {
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] = float32(float64(vector[i]) / norm)
+	}
+}
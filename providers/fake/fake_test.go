@@ -0,0 +1,208 @@
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/session"
+	"go-llm-rpggamemaster/tools"
+)
+
+func userMessage(text string) []interfaces.Message {
+	return []interfaces.Message{
+		{Role: "system", Content: "Ты мастер игры"},
+		{Role: "user", Content: text},
+	}
+}
+
+func TestParseScript(t *testing.T) {
+	t.Run("valid file", func(t *testing.T) {
+		script, err := LoadScript("testdata/conversation.yml")
+		if err != nil {
+			t.Fatalf("loading script: %v", err)
+		}
+		if len(script.Turns) != 3 || len(script.Rules) != 3 {
+			t.Errorf("unexpected script: %+v", script)
+		}
+		if script.Turns[1].ToolCalls[0].Name != "roll_dice" {
+			t.Errorf("expected a roll_dice call, got %+v", script.Turns[1].ToolCalls)
+		}
+	})
+
+	t.Run("rule without condition", func(t *testing.T) {
+		if _, err := ParseScript([]byte("rules:\n  - reply: hi\n")); err == nil {
+			t.Error("expected error for a rule without match or pattern")
+		}
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		if _, err := ParseScript([]byte("rules:\n  - pattern: \"(\"\n")); err == nil {
+			t.Error("expected error for an invalid pattern")
+		}
+	})
+}
+
+func TestProvider_Rules(t *testing.T) {
+	ctx := context.Background()
+	script, err := LoadScript("testdata/conversation.yml")
+	if err != nil {
+		t.Fatalf("loading script: %v", err)
+	}
+	script.Turns = nil
+	p := NewProvider(script, 0)
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Привет всем", "Приветствую, путник! Вы сказали: Привет всем"},
+		{"Осматриваю сундук", "Вы внимательно осматриваете сундук. Ничего необычного."},
+		{"Пою песню", "Мастер задумчиво молчит."},
+		{"Делаю бросок", "Мастер задумчиво молчит."}, // tool rules need tools
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := p.GenerateResponse(ctx, userMessage(tt.input), 0.7, 0)
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("tool rule is skipped after the tool result", func(t *testing.T) {
+		defs := []interfaces.ToolDefinition{{Name: "roll_dice"}}
+		messages := userMessage("Делаю бросок")
+		msg, err := p.GenerateWithTools(ctx, messages, defs, 0.7, 0, nil)
+		if err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		if len(msg.ToolCalls) != 1 {
+			t.Fatalf("expected a tool call, got %+v", msg)
+		}
+
+		messages = append(messages, msg, interfaces.Message{Role: "tool", ToolCallID: msg.ToolCalls[0].ID, Content: "17"})
+		msg, err = p.GenerateWithTools(ctx, messages, defs, 0.7, 0, nil)
+		if err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		if len(msg.ToolCalls) != 0 || msg.Content != "Мастер задумчиво молчит." {
+			t.Errorf("expected the default reply, got %+v", msg)
+		}
+	})
+}
+
+func TestProvider_Turns(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unexpected input fails the turn", func(t *testing.T) {
+		p, err := NewProviderFromFile("testdata/conversation.yml", 0)
+		if err != nil {
+			t.Fatalf("creating provider: %v", err)
+		}
+		if _, err := p.GenerateResponse(ctx, userMessage("Иду в лес"), 0.7, 0); err == nil {
+			t.Error("expected error for an unexpected user message")
+		}
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		p := NewProvider(&Script{Turns: []Reply{{Reply: "Раз два три"}}}, 0)
+		var deltas []string
+		full, err := p.GenerateResponseStream(ctx, userMessage("?"), 0.7, 0, func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("stream: %v", err)
+		}
+		if len(deltas) != 3 || strings.Join(deltas, "") != full || full != "Раз два три" {
+			t.Errorf("unexpected deltas %q for %q", deltas, full)
+		}
+		if p.Remaining() != 0 {
+			t.Errorf("expected all turns played, %d left", p.Remaining())
+		}
+	})
+}
+
+// TestConversation plays the scripted session through the session manager and a real tool loop
+func TestConversation(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewProviderFromFile("testdata/conversation.yml", 0)
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+	m, err := session.NewManager(p, session.NewMemoryStore(), nil)
+	if err != nil {
+		t.Fatalf("creating manager: %v", err)
+	}
+
+	var rolled string
+	registry := tools.NewRegistry()
+	_ = registry.Register(tools.NewFunc(interfaces.ToolDefinition{Name: "roll_dice"}, func(ctx context.Context, inv tools.Invocation, arguments json.RawMessage) (string, error) {
+		rolled = string(arguments)
+		return "1d20: [17] = 17", nil
+	}))
+	m.SetTools(registry)
+
+	reply, err := m.Play(ctx, 1, 10, "Захожу в таверну")
+	if err != nil || !strings.Contains(reply, "Трактирщик") {
+		t.Fatalf("unexpected first reply %q: %v", reply, err)
+	}
+	reply, err = m.Play(ctx, 1, 10, "Атакую трактирщика")
+	if err != nil || !strings.Contains(reply, "Удар достигает цели") {
+		t.Fatalf("unexpected second reply %q: %v", reply, err)
+	}
+	if !strings.Contains(rolled, "1d20") {
+		t.Errorf("expected the dice tool to be called, got %q", rolled)
+	}
+	if p.Remaining() != 0 {
+		t.Errorf("expected all turns played, %d left", p.Remaining())
+	}
+}
+
+func TestProvider_EmbedDocuments(t *testing.T) {
+	ctx := context.Background()
+	p := NewProvider(nil, 64)
+
+	embeddings, err := p.EmbedDocuments(ctx, []string{"Дракон спит на золоте", "дракон спит на золоте!", "Тихий лес", ""})
+	if err != nil {
+		t.Fatalf("embed: %v", err)
+	}
+	if len(embeddings[0]) != 64 {
+		t.Fatalf("expected 64 dimensions, got %d", len(embeddings[0]))
+	}
+	if cosine(embeddings[0], embeddings[1]) < 0.999 {
+		t.Error("expected texts with the same words to embed equally")
+	}
+	if cosine(embeddings[0], embeddings[2]) >= cosine(embeddings[0], embeddings[1]) {
+		t.Error("expected unrelated texts to be less similar")
+	}
+	for _, v := range embeddings[3] {
+		if v != 0 {
+			t.Fatal("expected a zero vector for an empty text")
+		}
+	}
+
+	again, _ := NewProvider(nil, 64).EmbedDocuments(ctx, []string{"Дракон спит на золоте"})
+	for i := range again[0] {
+		if again[0][i] != embeddings[0][i] {
+			t.Fatal("expected embeddings to be deterministic across providers")
+		}
+	}
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,111 @@
+package fake
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Script describes the replies of the fake provider.
+//
+// Turns are played in order, one per completion, which suits end-to-end conversation tests.
+// Once they run out, the first matching rule answers, and Default answers everything else.
+//
+//	turns:
+//	  - expect: "таверн"
+//	    reply: "Трактирщик кивает вам."
+//	  - tool_calls:
+//	      - name: roll_dice
+//	        arguments: '{"notation": "1d20"}'
+//	rules:
+//	  - match: "привет"
+//	    reply: "Приветствую, путник!"
+//	  - pattern: "(?i)^иду (.+)$"
+//	    reply: "Вы идёте: $1"
+//	default: "Мастер задумчиво молчит."
+type Script struct {
+	Turns   []Reply `yaml:"turns"`
+	Rules   []Rule  `yaml:"rules"`
+	Default string  `yaml:"default"`
+}
+
+// Reply is a scripted assistant message.
+// {{input}} in Reply is replaced with the text of the last user message.
+type Reply struct {
+	Expect    string     `yaml:"expect"` // Turn fails unless the last user message contains it
+	Reply     string     `yaml:"reply"`
+	ToolCalls []ToolCall `yaml:"tool_calls"`
+}
+
+// ToolCall is a scripted function call
+type ToolCall struct {
+	Name      string `yaml:"name"`
+	Arguments string `yaml:"arguments"` // JSON encoded arguments object
+}
+
+// Rule answers user messages containing Match (case-insensitive) or matching Pattern.
+// $1, ${name} in the reply of a Pattern rule expand to its capture groups.
+type Rule struct {
+	Reply   `yaml:",inline"`
+	Match   string `yaml:"match"`
+	Pattern string `yaml:"pattern"`
+
+	re *regexp.Regexp
+}
+
+// LoadScript reads a YAML script file
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading script: %w", err)
+	}
+	return ParseScript(data)
+}
+
+// ParseScript decodes a YAML script and compiles its patterns
+func ParseScript(data []byte) (*Script, error) {
+	var s Script
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("decoding script: %w", err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *Script) compile() error {
+	for i := range s.Rules {
+		rule := &s.Rules[i]
+		rule.Match = strings.ToLower(rule.Match)
+		if rule.Match == "" && rule.Pattern == "" {
+			return fmt.Errorf("rule %d: match or pattern is required", i+1)
+		}
+		if rule.Pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("rule %d: compiling pattern: %w", i+1, err)
+		}
+		rule.re = re
+	}
+	return nil
+}
+
+func (r *Rule) matches(input string) bool {
+	if r.re != nil {
+		return r.re.MatchString(input)
+	}
+	return strings.Contains(strings.ToLower(input), r.Match)
+}
+
+func (r *Rule) expand(input string) string {
+	if r.re == nil {
+		return r.Reply.Reply
+	}
+	return string(r.re.ExpandString(nil, r.Reply.Reply, input, r.re.FindStringSubmatchIndex(input)))
+}
@@ -0,0 +1,109 @@
+package fallback
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the state of a circuit breaker
+type State uint8
+
+const (
+	// StateClosed lets calls through
+	StateClosed State = iota
+	// StateOpen skips the provider until the cooldown ends
+	StateOpen
+	// StateHalfOpen lets a single trial call through after the cooldown
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker opens after consecutive failures and allows a trial call once the cooldown passes
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trial    bool // A half-open trial call is in flight
+}
+
+func newBreaker(threshold int, cooldown time.Duration, now func() time.Time) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown, now: now}
+}
+
+// allow reports whether a call may go through and reserves the half-open trial
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		b.state = StateHalfOpen
+		b.trial = false
+	}
+	switch b.state {
+	case StateOpen:
+		return false
+	case StateHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = StateClosed
+	b.failures = 0
+	b.trial = false
+}
+
+// failure counts a failed call and reports whether the breaker opened because of it
+func (b *breaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		opened := b.state != StateOpen
+		b.state = StateOpen
+		b.openedAt = b.now()
+		b.trial = false
+		return opened
+	}
+	return false
+}
+
+// release ends a call that neither succeeded nor failed, such as a cancelled one
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+func (b *breaker) current() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		return StateHalfOpen
+	}
+	return b.state
+}
@@ -0,0 +1,261 @@
+// Package fallback combines inference providers into an ordered failover chain.
+// Each member has a circuit breaker, so a provider that keeps failing is skipped
+// until its cooldown ends instead of delaying every request.
+package fallback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// Member is a provider of the chain with a name that tells its backend apart, e.g. "routerai/gpt-4o-mini"
+type Member struct {
+	Name     string
+	Provider interfaces.InferenceProvider
+}
+
+// Config contains circuit breaker settings shared by all members
+type Config struct {
+	FailureThreshold int           // Consecutive failures that open the breaker
+	Cooldown         time.Duration // Time an open breaker skips its member
+}
+
+// DefaultConfig returns the default fallback settings
+func DefaultConfig() *Config {
+	return &Config{
+		FailureThreshold: 3,
+		Cooldown:         30 * time.Second,
+	}
+}
+
+// MemberHealth is the breaker state of a member
+type MemberHealth struct {
+	Name  string
+	State State
+}
+
+type member struct {
+	Member
+	breaker *breaker
+}
+
+// Provider tries its members in order and fails over on retryable errors.
+// Name reports the member that served the last request.
+type Provider struct {
+	members []*member
+
+	mu     sync.Mutex
+	active int
+}
+
+var (
+	_ interfaces.InferenceProvider          = (*Provider)(nil)
+	_ interfaces.StreamingInferenceProvider = (*Provider)(nil)
+	_ interfaces.ToolCallingProvider        = (*Provider)(nil)
+)
+
+// New creates a failover chain, the first member is the primary
+func New(members []Member, config *Config) (*Provider, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("at least one provider is required")
+	}
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = DefaultConfig().FailureThreshold
+	}
+
+	p := &Provider{}
+	for i, m := range members {
+		if m.Provider == nil {
+			return nil, fmt.Errorf("provider %d is nil", i+1)
+		}
+		if m.Name == "" {
+			m.Name = m.Provider.Name()
+		}
+		p.members = append(p.members, &member{
+			Member:  m,
+			breaker: newBreaker(config.FailureThreshold, config.Cooldown, time.Now),
+		})
+	}
+	return p, nil
+}
+
+// Name returns the name of the member that served the last request
+func (p *Provider) Name() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.members[p.active].Name
+}
+
+// Health returns the breaker state of every member in order
+func (p *Provider) Health() []MemberHealth {
+	health := make([]MemberHealth, len(p.members))
+	for i, m := range p.members {
+		health[i] = MemberHealth{Name: m.Name, State: m.breaker.current()}
+	}
+	return health
+}
+
+func (p *Provider) GenerateResponse(ctx context.Context, messages []interfaces.Message, temperature float64, maxTokens int) (string, error) {
+	var response string
+	err := p.call(ctx, func(m *member) error {
+		var err error
+		response, err = m.Provider.GenerateResponse(ctx, messages, temperature, maxTokens)
+		return err
+	})
+	return response, err
+}
+
+// GenerateResponseStream fails over only until the first delta is delivered.
+// Members without streaming deliver the whole response as one delta.
+func (p *Provider) GenerateResponseStream(ctx context.Context, messages []interfaces.Message, temperature float64, maxTokens int, onDelta func(delta string) error) (string, error) {
+	var response string
+	err := p.call(ctx, func(m *member) error {
+		var err error
+		response, err = m.stream(ctx, messages, temperature, maxTokens, onDelta)
+		return err
+	})
+	return response, err
+}
+
+// GenerateWithTools answers without tools on members that lack tool calling, so a chain
+// without such members still serves the turn and the member order is kept
+func (p *Provider) GenerateWithTools(ctx context.Context, messages []interfaces.Message, tools []interfaces.ToolDefinition, temperature float64, maxTokens int, onDelta func(delta string) error) (interfaces.Message, error) {
+	var msg interfaces.Message
+	err := p.call(ctx, func(m *member) error {
+		calling, ok := m.Provider.(interfaces.ToolCallingProvider)
+		if !ok {
+			content, err := m.stream(ctx, messages, temperature, maxTokens, onDelta)
+			msg = interfaces.Message{Role: "assistant", Content: content}
+			return err
+		}
+		delivered := false
+		var err error
+		msg, err = calling.GenerateWithTools(ctx, messages, tools, temperature, maxTokens, trackDelivery(onDelta, &delivered))
+		if err != nil && delivered {
+			return final(err)
+		}
+		return err
+	})
+	return msg, err
+}
+
+// stream generates a response with the member, streaming it when the member supports streaming
+func (m *member) stream(ctx context.Context, messages []interfaces.Message, temperature float64, maxTokens int, onDelta func(delta string) error) (string, error) {
+	streaming, ok := m.Provider.(interfaces.StreamingInferenceProvider)
+	if !ok {
+		response, err := m.Provider.GenerateResponse(ctx, messages, temperature, maxTokens)
+		if err != nil {
+			return "", err
+		}
+		if onDelta != nil && response != "" {
+			return response, final(onDelta(response))
+		}
+		return response, nil
+	}
+
+	delivered := false
+	response, err := streaming.GenerateResponseStream(ctx, messages, temperature, maxTokens, trackDelivery(onDelta, &delivered))
+	if err != nil && delivered {
+		return response, final(err)
+	}
+	return response, err
+}
+
+// call runs fn against the members in order until one succeeds or fails with a non-retryable error.
+// Members with an open breaker are skipped; when all are open the chain is tried anyway.
+func (p *Provider) call(ctx context.Context, fn func(m *member) error) error {
+	var lastErr error
+	attempted := false
+	for pass := 0; pass < 2 && !attempted; pass++ {
+		for i, m := range p.members {
+			// The second pass ignores breakers, a chance of success beats a certain failure
+			if pass == 0 && !m.breaker.allow() {
+				continue
+			}
+
+			err := fn(m)
+			attempted = true
+			if err == nil {
+				m.breaker.success()
+				p.serve(i)
+				return nil
+			}
+
+			var finalErr *finalError
+			if errors.As(err, &finalErr) {
+				if isRetryable(finalErr.err) {
+					m.breaker.failure()
+				} else {
+					m.breaker.release()
+				}
+				return finalErr.err
+			}
+			if ctx.Err() != nil || !isRetryable(err) {
+				m.breaker.release()
+				return err
+			}
+
+			if m.breaker.failure() {
+				log.Warn().Str("provider", m.Name).Msg("Inference provider circuit opened")
+			}
+			log.Warn().Err(err).Str("provider", m.Name).Msg("Inference provider failed, trying the next one")
+			lastErr = err
+		}
+	}
+	return fmt.Errorf("all inference providers failed: %w", lastErr)
+}
+
+// serve records the member that answered
+func (p *Provider) serve(i int) {
+	p.mu.Lock()
+	previous := p.active
+	p.active = i
+	p.mu.Unlock()
+
+	if previous != i {
+		log.Info().
+			Str("provider", p.members[i].Name).
+			Str("previous", p.members[previous].Name).
+			Msg("Inference served by another provider")
+	}
+}
+
+// finalError ends the failover, e.g. once a partial stream reached the user
+type finalError struct {
+	err error
+}
+
+func (e *finalError) Error() string { return e.err.Error() }
+func (e *finalError) Unwrap() error { return e.err }
+
+func final(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &finalError{err: err}
+}
+
+func trackDelivery(onDelta func(delta string) error, delivered *bool) func(delta string) error {
+	if onDelta == nil {
+		return nil
+	}
+	return func(delta string) error {
+		*delivered = true
+		return onDelta(delta)
+	}
+}
+
+// isRetryable detects errors that providers classify as transient, such as routerai.APIError
+func isRetryable(err error) bool {
+	var retryable interface{ Retryable() bool }
+	return errors.As(err, &retryable) && retryable.Retryable()
+}
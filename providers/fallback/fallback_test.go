@@ -0,0 +1,190 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// transientError is retryable like routerai.APIError of a 5xx
+type transientError struct{}
+
+func (transientError) Error() string   { return "server error" }
+func (transientError) Retryable() bool { return true }
+
+// MockProvider answers with its reply or fails with err
+type MockProvider struct {
+	reply string
+	err   error
+	calls int
+}
+
+func (m *MockProvider) GenerateResponse(ctx context.Context, messages []interfaces.Message, temperature float64, maxTokens int) (string, error) {
+	m.calls++
+	return m.reply, m.err
+}
+
+func (m *MockProvider) Name() string {
+	return "mock"
+}
+
+// MockStreamingProvider streams its reply in two deltas and then fails with err
+type MockStreamingProvider struct {
+	MockProvider
+}
+
+func (m *MockStreamingProvider) GenerateResponseStream(ctx context.Context, messages []interfaces.Message, temperature float64, maxTokens int, onDelta func(delta string) error) (string, error) {
+	m.calls++
+	if onDelta != nil {
+		_ = onDelta(m.reply)
+	}
+	return m.reply, m.err
+}
+
+func newChain(t *testing.T, providers ...interfaces.InferenceProvider) *Provider {
+	t.Helper()
+	members := make([]Member, len(providers))
+	for i, p := range providers {
+		members[i] = Member{Name: string(rune('a' + i)), Provider: p}
+	}
+	chain, err := New(members, &Config{FailureThreshold: 2, Cooldown: time.Minute})
+	if err != nil {
+		t.Fatalf("creating chain: %v", err)
+	}
+	return chain
+}
+
+func TestProvider_Failover(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("transient error fails over", func(t *testing.T) {
+		primary := &MockProvider{err: transientError{}}
+		backup := &MockProvider{reply: "backup"}
+		chain := newChain(t, primary, backup)
+
+		reply, err := chain.GenerateResponse(ctx, nil, 0.7, 0)
+		if err != nil || reply != "backup" {
+			t.Fatalf("expected the backup reply, got %q: %v", reply, err)
+		}
+		if chain.Name() != "b" {
+			t.Errorf("expected Name to report the serving member, got %q", chain.Name())
+		}
+	})
+
+	t.Run("permanent error is returned", func(t *testing.T) {
+		permanent := errors.New("invalid api key")
+		backup := &MockProvider{reply: "backup"}
+		chain := newChain(t, &MockProvider{err: permanent}, backup)
+
+		if _, err := chain.GenerateResponse(ctx, nil, 0.7, 0); !errors.Is(err, permanent) {
+			t.Errorf("expected the permanent error, got %v", err)
+		}
+		if backup.calls != 0 {
+			t.Error("expected no failover on a permanent error")
+		}
+	})
+
+	t.Run("all members failing", func(t *testing.T) {
+		chain := newChain(t, &MockProvider{err: transientError{}}, &MockProvider{err: transientError{}})
+		_, err := chain.GenerateResponse(ctx, nil, 0.7, 0)
+		var transient transientError
+		if !errors.As(err, &transient) {
+			t.Errorf("expected the last member error, got %v", err)
+		}
+	})
+
+	t.Run("partial stream is not repeated", func(t *testing.T) {
+		primary := &MockStreamingProvider{MockProvider{reply: "Начало", err: transientError{}}}
+		backup := &MockProvider{reply: "backup"}
+		chain := newChain(t, primary, backup)
+
+		var deltas []string
+		_, err := chain.GenerateResponseStream(ctx, nil, 0.7, 0, func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		})
+		if err == nil || backup.calls != 0 || len(deltas) != 1 {
+			t.Errorf("expected the stream error without failover, got %v, deltas %q", err, deltas)
+		}
+	})
+
+	t.Run("members without streaming answer in one delta", func(t *testing.T) {
+		chain := newChain(t, &MockProvider{reply: "целиком"})
+		var deltas []string
+		reply, err := chain.GenerateResponseStream(ctx, nil, 0.7, 0, func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		})
+		if err != nil || reply != "целиком" || len(deltas) != 1 {
+			t.Errorf("unexpected stream %q, deltas %q: %v", reply, deltas, err)
+		}
+	})
+
+	t.Run("members without tool calling answer without tools", func(t *testing.T) {
+		primary := &MockStreamingProvider{MockProvider{reply: "без инструментов"}}
+		chain := newChain(t, primary, &MockProvider{reply: "backup"})
+
+		var deltas []string
+		msg, err := chain.GenerateWithTools(ctx, nil, nil, 0.7, 0, func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		})
+		if err != nil || msg.Content != "без инструментов" || msg.Role != "assistant" || len(msg.ToolCalls) != 0 {
+			t.Errorf("expected a plain answer from the primary, got %+v: %v", msg, err)
+		}
+		if primary.calls != 1 || len(deltas) != 1 {
+			t.Errorf("expected the primary to stream once, got %d calls, deltas %q", primary.calls, deltas)
+		}
+	})
+}
+
+func TestProvider_CircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	primary := &MockProvider{err: transientError{}}
+	backup := &MockProvider{reply: "backup"}
+	chain := newChain(t, primary, backup)
+
+	now := time.Now()
+	chain.members[0].breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := chain.GenerateResponse(ctx, nil, 0.7, 0); err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+	}
+	if primary.calls != 2 {
+		t.Errorf("expected the open breaker to skip the primary, got %d calls", primary.calls)
+	}
+	if health := chain.Health(); health[0].State != StateOpen || health[1].State != StateClosed {
+		t.Errorf("unexpected health: %+v", health)
+	}
+
+	// After the cooldown a single trial call closes the breaker again
+	now = now.Add(time.Minute)
+	primary.err = nil
+	primary.reply = "primary"
+	reply, err := chain.GenerateResponse(ctx, nil, 0.7, 0)
+	if err != nil || reply != "primary" || chain.Name() != "a" {
+		t.Errorf("expected the primary to recover, got %q from %s: %v", reply, chain.Name(), err)
+	}
+	if health := chain.Health(); health[0].State != StateClosed {
+		t.Errorf("expected the breaker to close, got %s", health[0].State)
+	}
+}
+
+func TestProvider_AllOpen(t *testing.T) {
+	primary := &MockProvider{err: transientError{}}
+	chain := newChain(t, primary)
+	for i := 0; i < 2; i++ {
+		_, _ = chain.GenerateResponse(context.Background(), nil, 0.7, 0)
+	}
+
+	primary.err = nil
+	primary.reply = "ok"
+	if reply, err := chain.GenerateResponse(context.Background(), nil, 0.7, 0); err != nil || reply != "ok" {
+		t.Errorf("expected members to be tried when every breaker is open, got %q: %v", reply, err)
+	}
+}
@@ -0,0 +1,126 @@
+package routerai
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/providers/cassette"
+)
+
+// newCassetteProvider replays testdata/<name>.yml.
+// RPG_CASSETTE_MODE=record with ROUTERAI_API_KEY set re-records it against the real API.
+func newCassetteProvider(t *testing.T, model, name string) *RouterAIProvider {
+	t.Helper()
+	mode := cassette.ModeFromEnv()
+	apiKey := "test-key"
+	if mode != cassette.ModeReplay {
+		apiKey = os.Getenv("ROUTERAI_API_KEY")
+	}
+
+	recorder, err := cassette.New("testdata/"+name+".yml", mode, nil)
+	if err != nil {
+		t.Fatalf("opening cassette: %v", err)
+	}
+	recorder.Scrub(apiKey)
+
+	provider, err := NewRouterAIProvider(model, apiKey, "")
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+	provider.SetTransport(recorder)
+	return provider
+}
+
+func TestRouterAIProvider_Cassette(t *testing.T) {
+	var usage []interfaces.Usage
+	ctx := interfaces.WithUsageReporter(context.Background(), func(u interfaces.Usage) {
+		usage = append(usage, u)
+	})
+	provider := newCassetteProvider(t, "gpt-4o-mini", "chat")
+
+	t.Run("GenerateResponse", func(t *testing.T) {
+		messages := []interfaces.Message{
+			{Role: "system", Content: "Ты мастер настольной ролевой игры."},
+			{Role: "user", Content: "Я захожу в таверну."},
+		}
+		content, err := provider.GenerateResponse(ctx, messages, 0.7, 200)
+		if err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		if !strings.Contains(content, "Трактирщик") {
+			t.Errorf("unexpected content: %q", content)
+		}
+		want := interfaces.Usage{Provider: "routerai", Model: "gpt-4o-mini", Kind: interfaces.UsageChat, PromptTokens: 31, CompletionTokens: 27}
+		if len(usage) != 1 || usage[0] != want {
+			t.Errorf("expected usage %+v, got %+v", want, usage)
+		}
+	})
+
+	t.Run("GenerateResponseStream", func(t *testing.T) {
+		var deltas []string
+		messages := []interfaces.Message{{Role: "user", Content: "Опиши рынок одним предложением."}}
+		content, err := provider.GenerateResponseStream(ctx, messages, 0.7, 0, func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("stream: %v", err)
+		}
+		if content != "Рынок гудит голосами торговцев." || len(deltas) != 3 {
+			t.Errorf("unexpected stream %q from %q", content, deltas)
+		}
+		if last := usage[len(usage)-1]; last.PromptTokens != 18 || last.CompletionTokens != 9 {
+			t.Errorf("expected the usage of the final chunk, got %+v", last)
+		}
+	})
+
+	t.Run("GenerateWithTools", func(t *testing.T) {
+		tools := []interfaces.ToolDefinition{{
+			Name:        "roll_dice",
+			Description: "Бросок кубиков",
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"notation": map[string]interface{}{"type": "string"}},
+				"required":   []string{"notation"},
+			},
+		}}
+		messages := []interfaces.Message{{Role: "user", Content: "Я атакую гоблина."}}
+		msg, err := provider.GenerateWithTools(ctx, messages, tools, 0.7, 0, nil)
+		if err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Name != "roll_dice" || msg.ToolCalls[0].Arguments != `{"notation":"1d20"}` {
+			t.Errorf("unexpected tool calls: %+v", msg.ToolCalls)
+		}
+	})
+
+	t.Run("API error", func(t *testing.T) {
+		messages := []interfaces.Message{{Role: "user", Content: "Сломай сервер."}}
+		if _, err := provider.GenerateResponse(ctx, messages, 0.7, 0); err == nil || !strings.Contains(err.Error(), "Invalid API key") {
+			t.Errorf("expected the recorded API error, got %v", err)
+		}
+	})
+}
+
+func TestRouterAIProvider_CassetteEmbeddings(t *testing.T) {
+	provider := newCassetteProvider(t, "text-embedding-3-small", "embeddings")
+
+	var usage interfaces.Usage
+	ctx := interfaces.WithUsageReporter(context.Background(), func(u interfaces.Usage) { usage = u })
+	embeddings, err := provider.EmbedDocuments(ctx, []string{"Дракон спит на золоте", "Тихий лес"})
+	if err != nil {
+		t.Fatalf("embed: %v", err)
+	}
+	if len(embeddings) != 2 || len(embeddings[0]) != 4 {
+		t.Fatalf("unexpected embeddings: %v", embeddings)
+	}
+	if embeddings[0][0] != 0.0231 || embeddings[1][0] != -0.0121 {
+		t.Errorf("expected embeddings ordered by index, got %v", embeddings)
+	}
+	if usage.Kind != interfaces.UsageEmbedding || usage.PromptTokens != 9 {
+		t.Errorf("unexpected embedding usage: %+v", usage)
+	}
+}
@@ -0,0 +1,197 @@
+package routerai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error classes, match them with errors.Is
+var (
+	ErrRateLimited     = errors.New("rate limited")
+	ErrAuthFailed      = errors.New("authentication failed")
+	ErrContextTooLong  = errors.New("context too long")
+	ErrServer          = errors.New("server error")
+	ErrTimeout         = errors.New("timeout")
+	ErrNetwork         = errors.New("network error")
+	ErrInvalidRequest  = errors.New("invalid request")
+	errUnknownResponse = errors.New("unexpected response")
+)
+
+// maxErrorBody limits how much of an error response is read
+const maxErrorBody = 64 << 10
+
+// APIError is a failed call to the API, classified by Kind
+type APIError struct {
+	Kind       error         // One of the Err* classes
+	StatusCode int           // 0 for transport errors
+	Message    string        // Message of the API or the transport error
+	RetryAfter time.Duration // Delay requested by the server, 0 when not set
+
+	err error // Transport error
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	b.WriteString("routerai: ")
+	b.WriteString(e.Kind.Error())
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " (HTTP %d)", e.StatusCode)
+	}
+	if e.Message != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Message)
+	}
+	if e.Kind == ErrAuthFailed {
+		b.WriteString("; check api_key in the config")
+	}
+	return b.String()
+}
+
+// Is matches the error class
+func (e *APIError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// Retryable reports whether repeating the same request may succeed
+func (e *APIError) Retryable() bool {
+	switch e.Kind {
+	case ErrRateLimited, ErrServer, ErrTimeout, ErrNetwork:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRetryable reports whether err is an APIError worth retrying
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Retryable()
+}
+
+// responseError classifies a non-200 response and consumes its body
+func responseError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+	var errResp struct {
+		Error *Error `json:"error"`
+	}
+	message := strings.TrimSpace(string(body))
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error != nil {
+		message = errResp.Error.Message
+	}
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+
+	apiErr := &APIError{
+		Kind:       classifyStatus(resp.StatusCode),
+		StatusCode: resp.StatusCode,
+		Message:    message,
+		RetryAfter: retryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+	if errResp.Error != nil && isContextTooLong(errResp.Error) {
+		apiErr.Kind = ErrContextTooLong
+	}
+	return apiErr
+}
+
+// bodyError classifies an error object returned with status 200
+func bodyError(e *Error) *APIError {
+	apiErr := &APIError{Kind: errUnknownResponse, Message: e.Message}
+	switch {
+	case isContextTooLong(e):
+		apiErr.Kind = ErrContextTooLong
+	case strings.Contains(e.Type, "rate_limit"):
+		apiErr.Kind = ErrRateLimited
+	case strings.Contains(e.Type, "authentication"):
+		apiErr.Kind = ErrAuthFailed
+	case strings.Contains(e.Type, "server_error"):
+		apiErr.Kind = ErrServer
+	case strings.Contains(e.Type, "invalid_request"):
+		apiErr.Kind = ErrInvalidRequest
+	}
+	return apiErr
+}
+
+// transportError classifies an error of http.Client.Do.
+// Cancellation by the caller is returned as is, it must not be retried,
+// and so are failures of custom transports that are not network errors.
+func transportError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	cause := err
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		cause = urlErr.Err
+	}
+	var netErr net.Error
+	switch {
+	case errors.As(cause, &netErr) && netErr.Timeout():
+		return &APIError{Kind: ErrTimeout, Message: err.Error(), err: err}
+	case errors.As(cause, &netErr), errors.Is(cause, io.EOF), errors.Is(cause, io.ErrUnexpectedEOF):
+		return &APIError{Kind: ErrNetwork, Message: err.Error(), err: err}
+	default:
+		return fmt.Errorf("do request: %w", err)
+	}
+}
+
+func classifyStatus(status int) error {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrAuthFailed
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return ErrTimeout
+	case status == http.StatusRequestEntityTooLarge:
+		return ErrContextTooLong
+	case status >= 500:
+		return ErrServer
+	case status >= 400:
+		return ErrInvalidRequest
+	default:
+		return errUnknownResponse
+	}
+}
+
+func isContextTooLong(e *Error) bool {
+	if code, ok := e.Code.(string); ok && code == "context_length_exceeded" {
+		return true
+	}
+	message := strings.ToLower(e.Message)
+	return strings.Contains(message, "context length") || strings.Contains(message, "context window") ||
+		strings.Contains(message, "too many tokens")
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package routerai
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RetryConfig controls retries of rate limited, timed out and failed calls
+type RetryConfig struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration // Also caps Retry-After
+	Jitter     float64
+
+	// Budget limits the time of a call including retries: no retry starts
+	// once waiting for it would exceed the budget. Zero disables the limit.
+	Budget time.Duration
+}
+
+func DefaultRetryConfig() *RetryConfig {
+	return &RetryConfig{
+		MaxRetries: 3,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   10 * time.Second,
+		Jitter:     0.25,
+		Budget:     90 * time.Second,
+	}
+}
+
+// do sends the request built by newRequest and returns a 200 response.
+// Retryable failures are repeated with exponential backoff, honoring Retry-After.
+// Each attempt times out when the server stays silent for longer than the provider timeout,
+// including while the caller reads the response body.
+func (p *RouterAIProvider) do(ctx context.Context, newRequest func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	config := p.retry
+	if config == nil {
+		config = &RetryConfig{}
+	}
+	start := time.Now()
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		silence := newSilenceTimer(p.timeout, cancel)
+		req, err := newRequest(attemptCtx)
+		if err != nil {
+			silence.stop()
+			return nil, err
+		}
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			lastErr = silence.err(transportError(ctx, err))
+			silence.stop()
+		} else if resp.StatusCode != http.StatusOK {
+			lastErr = responseError(resp)
+			resp.Body.Close()
+			silence.stop()
+		} else {
+			if attempt > 0 {
+				log.Info().Int("attempts", attempt+1).Msg("RouterAI call succeeded after retry")
+			}
+			resp.Body = &silentBody{ReadCloser: resp.Body, silence: silence}
+			return resp, nil
+		}
+
+		apiErr, ok := lastErr.(*APIError)
+		if !ok || !apiErr.Retryable() || attempt >= config.MaxRetries {
+			break
+		}
+
+		delay := backoff(config, attempt, apiErr.RetryAfter)
+		if config.Budget > 0 && time.Since(start)+delay > config.Budget {
+			log.Warn().Err(lastErr).Dur("delay", delay).Msg("RouterAI retry budget exhausted")
+			break
+		}
+
+		log.Warn().
+			Int("attempt", attempt+1).
+			Dur("delay", delay).
+			Err(lastErr).
+			Msg("Retrying RouterAI call")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("context cancelled during retry: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+	return nil, lastErr
+}
+
+// backoff returns the delay before the retry after attempt, at least retryAfter
+func backoff(config *RetryConfig, attempt int, retryAfter time.Duration) time.Duration {
+	delay := config.BaseDelay * time.Duration(1<<uint(attempt))
+	if delay > config.MaxDelay {
+		delay = config.MaxDelay
+	}
+	delay += time.Duration(float64(delay) * config.Jitter * (2*rand.Float64() - 1))
+
+	if retryAfter > config.MaxDelay {
+		retryAfter = config.MaxDelay
+	}
+	if delay < retryAfter {
+		delay = retryAfter
+	}
+	return delay
+}
@@ -0,0 +1,162 @@
+package routerai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+func testRetryConfig() *RetryConfig {
+	return &RetryConfig{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+}
+
+// newFailingServer answers with the given statuses in order, then with a completion
+func newFailingServer(t *testing.T, calls *int32, statuses ...int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(calls, 1))
+		w.Header().Set("Content-Type", "application/json")
+		if n <= len(statuses) {
+			status := statuses[n-1]
+			if status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+			w.WriteHeader(status)
+			body := fmt.Sprintf(`{"error":{"message":"status %d","type":"error"}}`, status)
+			if status == http.StatusBadRequest {
+				body = `{"error":{"message":"This model's maximum context length is 8192 tokens","code":"context_length_exceeded"}}`
+			}
+			fmt.Fprint(w, body)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRouterAIProvider_Retry(t *testing.T) {
+	messages := []interfaces.Message{{Role: "user", Content: "hi"}}
+
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantErr   error
+		wantCalls int32
+	}{
+		{"rate limit is retried", []int{429, 429}, nil, 3},
+		{"server errors are retried", []int{500, 502, 503}, nil, 4},
+		{"retries are limited", []int{500, 500, 500, 500, 500}, ErrServer, 4},
+		{"invalid key fails fast", []int{401}, ErrAuthFailed, 1},
+		{"context too long fails fast", []int{400}, ErrContextTooLong, 1},
+		{"gateway timeout is retried", []int{504}, nil, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := newFailingServer(t, &calls, tt.statuses...)
+			provider, _ := NewRouterAIProvider("gpt-4o-mini", "test-key", server.URL)
+			provider.SetRetryConfig(testRetryConfig())
+
+			content, err := provider.GenerateResponse(context.Background(), messages, 0.7, 0)
+			if tt.wantErr == nil && (err != nil || content != "ok") {
+				t.Errorf("expected success, got %q: %v", content, err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, calls)
+			}
+		})
+	}
+
+	t.Run("auth error message", func(t *testing.T) {
+		var calls int32
+		server := newFailingServer(t, &calls, 401)
+		provider, _ := NewRouterAIProvider("gpt-4o-mini", "bad-key", server.URL)
+
+		_, err := provider.EmbedDocuments(context.Background(), []string{"hi"})
+		if err == nil || !strings.Contains(err.Error(), "authentication failed (HTTP 401)") || !strings.Contains(err.Error(), "api_key") {
+			t.Errorf("expected a clear auth error, got %v", err)
+		}
+	})
+
+	t.Run("stream setup is retried", func(t *testing.T) {
+		var calls int32
+		server := newFailingServer(t, &calls, 503)
+		provider, _ := NewRouterAIProvider("gpt-4o-mini", "test-key", server.URL)
+		provider.SetRetryConfig(testRetryConfig())
+
+		// The fake server answers without SSE framing, so the stream is empty
+		_, err := provider.GenerateResponseStream(context.Background(), messages, 0.7, 0, nil)
+		if errors.Is(err, ErrServer) || calls != 2 {
+			t.Errorf("expected the 503 to be retried, got %v after %d calls", err, calls)
+		}
+	})
+
+	t.Run("budget stops retries", func(t *testing.T) {
+		var calls int32
+		server := newFailingServer(t, &calls, 500, 500)
+		provider, _ := NewRouterAIProvider("gpt-4o-mini", "test-key", server.URL)
+		config := testRetryConfig()
+		config.BaseDelay = time.Second
+		config.MaxDelay = time.Second
+		config.Budget = 100 * time.Millisecond
+		provider.SetRetryConfig(config)
+
+		if _, err := provider.GenerateResponse(context.Background(), messages, 0.7, 0); !errors.Is(err, ErrServer) || calls != 1 {
+			t.Errorf("expected to give up after one call, got %v after %d calls", err, calls)
+		}
+	})
+
+	t.Run("cancellation stops retries", func(t *testing.T) {
+		var calls int32
+		server := newFailingServer(t, &calls, 500, 500)
+		provider, _ := NewRouterAIProvider("gpt-4o-mini", "test-key", server.URL)
+		config := testRetryConfig()
+		config.BaseDelay = time.Minute
+		config.MaxDelay = time.Minute
+		provider.SetRetryConfig(config)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := provider.GenerateResponse(ctx, messages, 0.7, 0); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the deadline error, got %v", err)
+		}
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"-1", 0},
+		{now.Add(5 * time.Second).Format(http.TimeFormat), 5 * time.Second},
+		{now.Add(-5 * time.Second).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.header, now); got != tt.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+
+	config := testRetryConfig()
+	if delay := backoff(config, 0, time.Hour); delay != config.MaxDelay {
+		t.Errorf("expected Retry-After to be capped at %v, got %v", config.MaxDelay, delay)
+	}
+}
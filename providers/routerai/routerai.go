@@ -12,6 +12,8 @@ import (
 )
 
 const (
+	// defaultTimeout limits how long the server may stay silent: until the response
+	// arrives and between reads of its body, so long streams are not cut off
 	defaultTimeout = 60 * time.Second
 	defaultBaseURL = "https://routerai.ru/v1"
 )
@@ -22,11 +24,15 @@ type RouterAIProvider struct {
 	apiKey  string
 	baseURL string
 	client  *http.Client
+	retry   *RetryConfig
+	timeout time.Duration
 }
 
 var (
-	_ interfaces.InferenceProvider       = (*RouterAIProvider)(nil)
-	_ interfaces.VectorEmbeddingProvider = (*RouterAIProvider)(nil)
+	_ interfaces.InferenceProvider          = (*RouterAIProvider)(nil)
+	_ interfaces.StreamingInferenceProvider = (*RouterAIProvider)(nil)
+	_ interfaces.ToolCallingProvider        = (*RouterAIProvider)(nil)
+	_ interfaces.VectorEmbeddingProvider    = (*RouterAIProvider)(nil)
 )
 
 func NewRouterAIProvider(model, apiKey, baseURL string) (*RouterAIProvider, error) {
@@ -41,17 +47,76 @@ func NewRouterAIProvider(model, apiKey, baseURL string) (*RouterAIProvider, erro
 		model:   model,
 		apiKey:  apiKey,
 		baseURL: baseURL,
-		client:  &http.Client{Timeout: defaultTimeout},
+		client:  &http.Client{},
+		retry:   DefaultRetryConfig(),
+		timeout: defaultTimeout,
 	}, nil
 }
 
+// SetRetryConfig replaces the retry policy, nil disables retries
+func (p *RouterAIProvider) SetRetryConfig(config *RetryConfig) {
+	p.retry = config
+}
+
+// SetTransport replaces the HTTP transport, e.g. with a cassette recorder in tests
+func (p *RouterAIProvider) SetTransport(transport http.RoundTripper) {
+	p.client.Transport = transport
+}
+
 func (p *RouterAIProvider) GenerateResponse(ctx context.Context, messages []interfaces.Message, temperature float64, maxTokens int) (string, error) {
-	var reqMessages []Message
+	msg, err := p.complete(ctx, messages, nil, temperature, maxTokens)
+	if err != nil {
+		return "", err
+	}
+	return msg.Content, nil
+}
+
+// complete performs a non-streamed chat completion and returns the assistant message
+func (p *RouterAIProvider) complete(ctx context.Context, messages []interfaces.Message, tools []interfaces.ToolDefinition, temperature float64, maxTokens int) (interfaces.Message, error) {
+	resp, err := p.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return p.newChatRequest(ctx, messages, tools, temperature, maxTokens, false)
+	})
+	if err != nil {
+		return interfaces.Message{}, err
+	}
+	defer resp.Body.Close()
+
+	var chatResp ChatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return interfaces.Message{}, fmt.Errorf("decode response: %w", err)
+	}
+
+	if chatResp.Error != nil {
+		return interfaces.Message{}, bodyError(chatResp.Error)
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return interfaces.Message{}, fmt.Errorf("no choices in response")
+	}
+
+	p.reportUsage(ctx, interfaces.UsageChat, chatResp.Usage)
+	return fromAPIMessage(chatResp.Choices[0].Message), nil
+}
+
+// reportUsage passes the token counts of a call to the usage reporter of ctx
+func (p *RouterAIProvider) reportUsage(ctx context.Context, kind string, usage *Usage) {
+	if usage == nil {
+		return
+	}
+	interfaces.ReportUsage(ctx, interfaces.Usage{
+		Provider:         p.Name(),
+		Model:            p.model,
+		Kind:             kind,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+	})
+}
+
+// newChatRequest builds a /chat/completions request
+func (p *RouterAIProvider) newChatRequest(ctx context.Context, messages []interfaces.Message, tools []interfaces.ToolDefinition, temperature float64, maxTokens int, stream bool) (*http.Request, error) {
+	reqMessages := make([]Message, 0, len(messages))
 	for _, m := range messages {
-		reqMessages = append(reqMessages, Message{
-			Role:    m.Role,
-			Content: m.Content,
-		})
+		reqMessages = append(reqMessages, toAPIMessage(m))
 	}
 
 	reqBody := ChatCompletionRequest{
@@ -59,43 +124,35 @@ func (p *RouterAIProvider) GenerateResponse(ctx context.Context, messages []inte
 		Messages:    reqMessages,
 		Temperature: temperature,
 		MaxTokens:   maxTokens,
+		Stream:      stream,
+	}
+	if stream {
+		reqBody.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+	if len(tools) > 0 {
+		reqBody.Tools = toAPITools(tools)
+		reqBody.ToolChoice = "auto"
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("marshal request: %w", err)
+		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
+		return nil, fmt.Errorf("create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	if p.apiKey != "" {
 		req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	}
-
-	resp, err := p.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("do request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var chatResp ChatCompletionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-		return "", fmt.Errorf("decode response: %w", err)
-	}
-
-	if chatResp.Error != nil {
-		return "", fmt.Errorf("API error: %s", chatResp.Error.Message)
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
 	}
 
-	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
-	}
-
-	return chatResp.Choices[0].Message.Content, nil
+	return req, nil
 }
 
 func (p *RouterAIProvider) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
@@ -109,19 +166,19 @@ func (p *RouterAIProvider) EmbedDocuments(ctx context.Context, texts []string) (
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/embeddings", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	if p.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+p.apiKey)
-	}
-
-	resp, err := p.client.Do(req)
+	resp, err := p.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/embeddings", bytes.NewReader(jsonBody))
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if p.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+p.apiKey)
+		}
+		return req, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -131,9 +188,11 @@ func (p *RouterAIProvider) EmbedDocuments(ctx context.Context, texts []string) (
 	}
 
 	if embedResp.Error != nil {
-		return nil, fmt.Errorf("API error: %s", embedResp.Error.Message)
+		return nil, bodyError(embedResp.Error)
 	}
 
+	p.reportUsage(ctx, interfaces.UsageEmbedding, embedResp.Usage)
+
 	result := make([][]float32, len(texts))
 	for _, data := range embedResp.Data {
 		if data.Index < len(result) {
@@ -0,0 +1,145 @@
+package routerai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+const (
+	sseDataPrefix = "data:"
+	sseDone       = "[DONE]"
+)
+
+// GenerateResponseStream requests a streamed chat completion and reports token deltas as they arrive
+func (p *RouterAIProvider) GenerateResponseStream(ctx context.Context, messages []interfaces.Message, temperature float64, maxTokens int, onDelta func(delta string) error) (string, error) {
+	msg, err := p.completeStream(ctx, messages, nil, temperature, maxTokens, onDelta)
+	if err != nil {
+		return "", err
+	}
+	return msg.Content, nil
+}
+
+// completeStream performs a streamed chat completion and returns the assembled assistant message
+func (p *RouterAIProvider) completeStream(ctx context.Context, messages []interfaces.Message, tools []interfaces.ToolDefinition, temperature float64, maxTokens int, onDelta func(delta string) error) (interfaces.Message, error) {
+	// Only establishing the stream is retried, delivered deltas cannot be taken back
+	resp, err := p.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return p.newChatRequest(ctx, messages, tools, temperature, maxTokens, true)
+	})
+	if err != nil {
+		return interfaces.Message{}, err
+	}
+	defer resp.Body.Close()
+
+	msg, usage, err := readStream(resp.Body, onDelta)
+	if err != nil {
+		return interfaces.Message{}, err
+	}
+	p.reportUsage(ctx, interfaces.UsageChat, usage)
+	return msg, nil
+}
+
+// readStream parses server-sent events of a chat completion.
+// Content deltas are reported to onDelta, tool call fragments are joined by their index.
+// Usage is nil unless the server sent it in the final chunk.
+func readStream(body io.Reader, onDelta func(delta string) error) (interfaces.Message, *Usage, error) {
+	var content strings.Builder
+	var usage *Usage
+	calls := make(map[int]*interfaces.ToolCall)
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	done := false
+	for !done && scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, sseDataPrefix) {
+			// Blank separators, comments and other SSE fields carry no content
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, sseDataPrefix))
+		if data == sseDone {
+			done = true
+			continue
+		}
+
+		var chunk ChatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return interfaces.Message{}, nil, fmt.Errorf("decode stream chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if chunk.Error != nil {
+			return interfaces.Message{}, nil, bodyError(chunk.Error)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta
+		for _, fragment := range delta.ToolCalls {
+			call, ok := calls[fragment.Index]
+			if !ok {
+				call = &interfaces.ToolCall{}
+				calls[fragment.Index] = call
+			}
+			if fragment.ID != "" {
+				call.ID = fragment.ID
+			}
+			call.Name += fragment.Function.Name
+			call.Arguments += fragment.Function.Arguments
+		}
+
+		if delta.Content == "" {
+			continue
+		}
+		content.WriteString(delta.Content)
+		if onDelta != nil {
+			if err := onDelta(delta.Content); err != nil {
+				return interfaces.Message{}, nil, fmt.Errorf("stream aborted: %w", err)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return interfaces.Message{}, nil, fmt.Errorf("read stream: %w", err)
+	}
+
+	// Some servers close the connection without sending [DONE]
+	msg := interfaces.Message{
+		Role:      "assistant",
+		Content:   content.String(),
+		ToolCalls: sortedToolCalls(calls),
+	}
+	if msg.Content == "" && len(msg.ToolCalls) == 0 {
+		return interfaces.Message{}, nil, fmt.Errorf("no content in stream")
+	}
+	return msg, usage, nil
+}
+
+func sortedToolCalls(calls map[int]*interfaces.ToolCall) []interfaces.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	indexes := make([]int, 0, len(calls))
+	for index := range calls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	result := make([]interfaces.ToolCall, len(indexes))
+	for i, index := range indexes {
+		result[i] = *calls[index]
+	}
+	return result
+}
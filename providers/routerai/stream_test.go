@@ -0,0 +1,170 @@
+package routerai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+func TestReadStream(t *testing.T) {
+	t.Run("concatenates deltas", func(t *testing.T) {
+		body := strings.Join([]string{
+			`: keep-alive`,
+			`data: {"choices":[{"delta":{"role":"assistant"}}]}`,
+			``,
+			`data: {"choices":[{"delta":{"content":"Вы входите"}}]}`,
+			``,
+			`data: {"choices":[{"delta":{"content":" в таверну."}}]}`,
+			``,
+			`data: [DONE]`,
+			``,
+		}, "\n")
+
+		var deltas []string
+		msg, _, err := readStream(strings.NewReader(body), func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if msg.Content != "Вы входите в таверну." {
+			t.Errorf("unexpected content: %q", msg.Content)
+		}
+		if len(deltas) != 2 {
+			t.Errorf("expected 2 deltas, got %d", len(deltas))
+		}
+	})
+
+	t.Run("joins tool call fragments", func(t *testing.T) {
+		body := strings.Join([]string{
+			`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"roll_dice","arguments":""}}]}}]}`,
+			`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"notation\":"}}]}}]}`,
+			`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"1d20\"}"}}]}}]}`,
+			`data: {"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+			`data: [DONE]`,
+		}, "\n")
+
+		msg, _, err := readStream(strings.NewReader(body), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(msg.ToolCalls) != 1 {
+			t.Fatalf("expected 1 tool call, got %d", len(msg.ToolCalls))
+		}
+		call := msg.ToolCalls[0]
+		if call.ID != "call_1" || call.Name != "roll_dice" || call.Arguments != `{"notation":"1d20"}` {
+			t.Errorf("unexpected tool call: %+v", call)
+		}
+	})
+
+	t.Run("callback error aborts", func(t *testing.T) {
+		body := "data: {\"choices\":[{\"delta\":{\"content\":\"a\"}}]}\n\ndata: [DONE]\n"
+		_, _, err := readStream(strings.NewReader(body), func(delta string) error {
+			return errors.New("stop")
+		})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("error event", func(t *testing.T) {
+		body := "data: {\"error\":{\"message\":\"overloaded\"}}\n"
+		_, _, err := readStream(strings.NewReader(body), nil)
+		if err == nil || !strings.Contains(err.Error(), "overloaded") {
+			t.Errorf("expected API error, got %v", err)
+		}
+	})
+
+	t.Run("empty stream", func(t *testing.T) {
+		if _, _, err := readStream(strings.NewReader(""), nil); err == nil {
+			t.Error("expected error for empty stream")
+		}
+	})
+}
+
+func TestRouterAIProvider_GenerateResponseStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if !req.Stream {
+			t.Error("expected stream: true in request")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, word := range []string{"Hello", ", ", "traveller"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", word)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	provider, err := NewRouterAIProvider("gpt-4o-mini", "test-key", server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	messages := []interfaces.Message{{Role: "user", Content: "hi"}}
+	content, err := provider.GenerateResponseStream(context.Background(), messages, 0.7, 0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content != "Hello, traveller" {
+		t.Errorf("unexpected content: %q", content)
+	}
+}
+
+func TestRouterAIProvider_StreamTimeout(t *testing.T) {
+	// Each chunk arrives within the timeout, the stream as a whole takes longer
+	stream := func(pause time.Duration, stall time.Duration) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for i := 0; i < 4; i++ {
+				fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":\"%d\"}}]}\n\n", i)
+				w.(http.Flusher).Flush()
+				time.Sleep(pause)
+			}
+			time.Sleep(stall)
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		}))
+	}
+	messages := []interfaces.Message{{Role: "user", Content: "hi"}}
+
+	t.Run("long stream is not cut off", func(t *testing.T) {
+		server := stream(40*time.Millisecond, 0)
+		defer server.Close()
+		provider, _ := NewRouterAIProvider("gpt-4o-mini", "test-key", server.URL)
+		provider.timeout = 100 * time.Millisecond
+
+		content, err := provider.GenerateResponseStream(context.Background(), messages, 0.7, 0, nil)
+		if err != nil || content != "0123" {
+			t.Errorf("expected the whole stream, got %q: %v", content, err)
+		}
+	})
+
+	t.Run("stalled stream times out", func(t *testing.T) {
+		server := stream(0, 300*time.Millisecond)
+		defer server.Close()
+		provider, _ := NewRouterAIProvider("gpt-4o-mini", "test-key", server.URL)
+		provider.timeout = 50 * time.Millisecond
+
+		var deltas []string
+		_, err := provider.GenerateResponseStream(context.Background(), messages, 0.7, 0, func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		})
+		if !errors.Is(err, ErrTimeout) || len(deltas) != 4 {
+			t.Errorf("expected a timeout after 4 deltas, got %v with %q", err, deltas)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package routerai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// silenceTimer cancels a call when the server stays silent for too long. Unlike http.Client.Timeout,
+// it is restarted by every read of the body, so only stalled responses time out, not long ones.
+type silenceTimer struct {
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	expired atomic.Bool
+}
+
+// newSilenceTimer starts the timer of a call, a zero timeout never expires
+func newSilenceTimer(timeout time.Duration, cancel context.CancelFunc) *silenceTimer {
+	t := &silenceTimer{timeout: timeout, cancel: cancel}
+	if timeout > 0 {
+		t.timer = time.AfterFunc(timeout, func() {
+			t.expired.Store(true)
+			cancel()
+		})
+	}
+	return t
+}
+
+// restart gives the server another timeout to respond
+func (t *silenceTimer) restart() {
+	if t.timer != nil && !t.expired.Load() {
+		t.timer.Reset(t.timeout)
+	}
+}
+
+// stop ends the call and releases its context
+func (t *silenceTimer) stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.cancel()
+}
+
+// err reports a failure caused by the expired timer as a timeout
+func (t *silenceTimer) err(err error) error {
+	if err == nil || !t.expired.Load() {
+		return err
+	}
+	return &APIError{
+		Kind:    ErrTimeout,
+		Message: fmt.Sprintf("no response for %s", t.timeout),
+		err:     err,
+	}
+}
+
+// silentBody restarts the silence timer on every read and stops it on close
+type silentBody struct {
+	io.ReadCloser
+	silence *silenceTimer
+}
+
+func (b *silentBody) Read(p []byte) (int, error) {
+	b.silence.restart()
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = b.silence.err(err)
+	}
+	return n, err
+}
+
+func (b *silentBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.silence.stop()
+	return err
+}
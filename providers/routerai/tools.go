@@ -0,0 +1,65 @@
+package routerai
+
+import (
+	"context"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// GenerateWithTools offers the tools to the model and returns its message, which holds either content or tool calls
+func (p *RouterAIProvider) GenerateWithTools(ctx context.Context, messages []interfaces.Message, tools []interfaces.ToolDefinition, temperature float64, maxTokens int, onDelta func(delta string) error) (interfaces.Message, error) {
+	if onDelta != nil {
+		return p.completeStream(ctx, messages, tools, temperature, maxTokens, onDelta)
+	}
+	return p.complete(ctx, messages, tools, temperature, maxTokens)
+}
+
+func toAPITools(tools []interfaces.ToolDefinition) []Tool {
+	apiTools := make([]Tool, len(tools))
+	for i, tool := range tools {
+		apiTools[i] = Tool{
+			Type: "function",
+			Function: FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		}
+	}
+	return apiTools
+}
+
+func toAPIMessage(m interfaces.Message) Message {
+	msg := Message{
+		Role:       m.Role,
+		Content:    m.Content,
+		ToolCallID: m.ToolCallID,
+	}
+	for _, call := range m.ToolCalls {
+		msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+			ID:   call.ID,
+			Type: "function",
+			Function: FunctionCall{
+				Name:      call.Name,
+				Arguments: call.Arguments,
+			},
+		})
+	}
+	return msg
+}
+
+func fromAPIMessage(m Message) interfaces.Message {
+	msg := interfaces.Message{
+		Role:       m.Role,
+		Content:    m.Content,
+		ToolCallID: m.ToolCallID,
+	}
+	for _, call := range m.ToolCalls {
+		msg.ToolCalls = append(msg.ToolCalls, interfaces.ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+	return msg
+}
@@ -0,0 +1,69 @@
+package routerai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+func TestRouterAIProvider_GenerateWithTools(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if len(req.Tools) != 1 || req.Tools[0].Type != "function" || req.Tools[0].Function.Name != "roll_dice" {
+			t.Errorf("unexpected tools: %+v", req.Tools)
+		}
+		if req.ToolChoice != "auto" {
+			t.Errorf("expected tool_choice auto, got %v", req.ToolChoice)
+		}
+
+		last := req.Messages[len(req.Messages)-1]
+		if last.Role == "tool" {
+			if last.ToolCallID != "call_1" {
+				t.Errorf("expected tool result for call_1, got %q", last.ToolCallID)
+			}
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"You rolled 17."},"finish_reason":"stop"}]}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[
+			{"id":"call_1","type":"function","function":{"name":"roll_dice","arguments":"{\"notation\":\"1d20\"}"}}
+		]},"finish_reason":"tool_calls"}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewRouterAIProvider("gpt-4o-mini", "test-key", server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	tools := []interfaces.ToolDefinition{{
+		Name:        "roll_dice",
+		Description: "Roll dice",
+		Parameters:  map[string]interface{}{"type": "object"},
+	}}
+	messages := []interfaces.Message{{Role: "user", Content: "I attack"}}
+
+	msg, err := provider.GenerateWithTools(context.Background(), messages, tools, 0.7, 0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Name != "roll_dice" || msg.ToolCalls[0].Arguments != `{"notation":"1d20"}` {
+		t.Fatalf("unexpected tool calls: %+v", msg.ToolCalls)
+	}
+
+	messages = append(messages, msg, interfaces.Message{Role: "tool", ToolCallID: "call_1", Content: "17"})
+	msg, err = provider.GenerateWithTools(context.Background(), messages, tools, 0.7, 0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Content != "You rolled 17." {
+		t.Errorf("unexpected content: %q", msg.Content)
+	}
+}
@@ -2,33 +2,108 @@ package routerai
 
 // ChatCompletionRequest represents the request body for chat completions
 type ChatCompletionRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	Temperature float64   `json:"temperature,omitempty"`
-	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Model         string         `json:"model"`
+	Messages      []Message      `json:"messages"`
+	Temperature   float64        `json:"temperature,omitempty"`
+	MaxTokens     int            `json:"max_tokens,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	Tools         []Tool         `json:"tools,omitempty"`
+	ToolChoice    interface{}    `json:"tool_choice,omitempty"` // "auto", "none", "required" or a function selector
+}
+
+// StreamOptions asks for the usage block in the final chunk of a stream
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// Usage is the token count of a request
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // Message represents a chat message
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// Tool represents a tool the model may call
+type Tool struct {
+	Type     string             `json:"type"`
+	Function FunctionDefinition `json:"function"`
+}
+
+// FunctionDefinition describes a callable function
+type FunctionDefinition struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// ToolCall represents a function call requested by the model
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall holds the function name and its JSON encoded arguments
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 // ChatCompletionResponse represents the response from chat completions
 type ChatCompletionResponse struct {
 	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage,omitempty"`
 	Error   *Error   `json:"error,omitempty"`
 }
 
 // Choice represents a single completion choice
 type Choice struct {
-	Message Message `json:"message"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason,omitempty"`
+}
+
+// ChatCompletionChunk represents a single server-sent event of a streamed chat completion
+type ChatCompletionChunk struct {
+	Choices []ChunkChoice `json:"choices"`
+	Usage   *Usage        `json:"usage,omitempty"` // Only in the final chunk when requested
+	Error   *Error        `json:"error,omitempty"`
+}
+
+// ChunkChoice represents the delta of a single completion choice
+type ChunkChoice struct {
+	Delta        ChunkDelta `json:"delta"`
+	FinishReason string     `json:"finish_reason,omitempty"`
+}
+
+// ChunkDelta represents a fragment of the assistant message
+type ChunkDelta struct {
+	Role      string          `json:"role,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	ToolCalls []ToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+// ToolCallDelta represents a fragment of a streamed tool call, identified by its index
+type ToolCallDelta struct {
+	Index    int          `json:"index"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function FunctionCall `json:"function"`
 }
 
 // Error represents an API error response
 type Error struct {
-	Message string `json:"message"`
-	Type    string `json:"type"`
+	Message string      `json:"message"`
+	Type    string      `json:"type"`
+	Code    interface{} `json:"code,omitempty"` // String or number depending on the upstream provider
 }
 
 // EmbeddingRequest represents the request body for embeddings
@@ -40,6 +115,7 @@ type EmbeddingRequest struct {
 // EmbeddingResponse represents the response from embeddings
 type EmbeddingResponse struct {
 	Data  []EmbeddingData `json:"data"`
+	Usage *Usage          `json:"usage,omitempty"`
 	Error *Error          `json:"error,omitempty"`
 }
 
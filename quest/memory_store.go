@@ -0,0 +1,119 @@
+package quest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go-llm-rpggamemaster/internal/uuid"
+)
+
+// MemoryStore keeps quests in process memory. Quests are lost on restart.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	quests  []*Quest
+	history map[string][]Transition
+}
+
+// Compile-time interface check
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty in-memory quest store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		history: make(map[string][]Transition),
+	}
+}
+
+// Create stores a copy of the quest and its initial transition
+func (s *MemoryStore) Create(ctx context.Context, q *Quest, initial Transition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.find(q.GameID, q.Name) != nil {
+		return ErrExists
+	}
+
+	now := time.Now()
+	q.ID = uuid.New()
+	q.CreatedAt = now
+	q.UpdatedAt = now
+	copied := *q
+	s.quests = append(s.quests, &copied)
+
+	initial.QuestID = q.ID
+	initial.CreatedAt = now
+	s.history[q.ID] = []Transition{initial}
+	return nil
+}
+
+// Get returns a copy of the quest
+func (s *MemoryStore) Get(ctx context.Context, gameID, name string) (*Quest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored := s.find(gameID, name)
+	if stored == nil {
+		return nil, ErrNotFound
+	}
+	copied := *stored
+	return &copied, nil
+}
+
+// List returns copies of the quests of a game
+func (s *MemoryStore) List(ctx context.Context, gameID string) ([]*Quest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var quests []*Quest
+	for _, stored := range s.quests {
+		if stored.GameID == gameID {
+			copied := *stored
+			quests = append(quests, &copied)
+		}
+	}
+	return quests, nil
+}
+
+// Transition updates the quest status and appends to its history
+func (s *MemoryStore) Transition(ctx context.Context, q *Quest, t Transition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.find(q.GameID, q.Name)
+	if stored == nil {
+		return ErrNotFound
+	}
+	if stored.Status != t.From {
+		return ErrConflict
+	}
+
+	now := time.Now()
+	stored.Status = t.To
+	stored.UpdatedAt = now
+	q.Status = t.To
+	q.UpdatedAt = now
+
+	t.QuestID = stored.ID
+	t.CreatedAt = now
+	s.history[stored.ID] = append(s.history[stored.ID], t)
+	return nil
+}
+
+// History returns a copy of the quest transitions
+func (s *MemoryStore) History(ctx context.Context, questID string) ([]Transition, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]Transition(nil), s.history[questID]...), nil
+}
+
+func (s *MemoryStore) find(gameID, name string) *Quest {
+	for _, q := range s.quests {
+		if q.GameID == gameID && strings.EqualFold(q.Name, name) {
+			return q
+		}
+	}
+	return nil
+}
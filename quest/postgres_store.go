@@ -0,0 +1,183 @@
+package quest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// uniqueViolation is the PostgreSQL error code of a unique index conflict
+const uniqueViolation = "23505"
+
+// PostgresStore persists quests in the quests and quest_transitions tables
+type PostgresStore struct {
+	db *pgxpool.Pool
+}
+
+// Compile-time interface check
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore creates a quest store backed by PostgreSQL
+func NewPostgresStore(db *pgxpool.Pool) (*PostgresStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database pool cannot be nil")
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// Create inserts the quest and its initial transition in one transaction
+func (s *PostgresStore) Create(ctx context.Context, q *Quest, initial Transition) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO quests (game_id, name, description, status)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`, q.GameID, q.Name, q.Description, q.Status).Scan(&q.ID, &q.CreatedAt, &q.UpdatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return ErrExists
+	}
+	if err != nil {
+		return fmt.Errorf("creating quest: %w", err)
+	}
+
+	initial.QuestID = q.ID
+	if err := insertTransition(ctx, tx, initial); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing quest: %w", err)
+	}
+	return nil
+}
+
+// Get reads a quest by case-insensitive name
+func (s *PostgresStore) Get(ctx context.Context, gameID, name string) (*Quest, error) {
+	q := &Quest{GameID: gameID}
+	err := s.db.QueryRow(ctx, `
+		SELECT id, name, description, status, created_at, updated_at
+		FROM quests
+		WHERE game_id = $1 AND lower(name) = lower($2)
+	`, gameID, name).Scan(&q.ID, &q.Name, &q.Description, &q.Status, &q.CreatedAt, &q.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading quest: %w", err)
+	}
+	return q, nil
+}
+
+// List reads the quests of a game
+func (s *PostgresStore) List(ctx context.Context, gameID string) ([]*Quest, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, name, description, status, created_at, updated_at
+		FROM quests
+		WHERE game_id = $1
+		ORDER BY created_at
+	`, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("listing quests: %w", err)
+	}
+	defer rows.Close()
+
+	var quests []*Quest
+	for rows.Next() {
+		q := &Quest{GameID: gameID}
+		if err := rows.Scan(&q.ID, &q.Name, &q.Description, &q.Status, &q.CreatedAt, &q.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning quest: %w", err)
+		}
+		quests = append(quests, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing quests: %w", err)
+	}
+	return quests, nil
+}
+
+// Transition updates the status only if it still equals t.From and records the change
+func (s *PostgresStore) Transition(ctx context.Context, q *Quest, t Transition) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	err = tx.QueryRow(ctx, `
+		UPDATE quests
+		SET status = $1, updated_at = NOW()
+		WHERE id = $2 AND status = $3
+		RETURNING updated_at
+	`, t.To, q.ID, t.From).Scan(&q.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("updating quest status: %w", err)
+	}
+
+	t.QuestID = q.ID
+	if err := insertTransition(ctx, tx, t); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing quest transition: %w", err)
+	}
+	q.Status = t.To
+	return nil
+}
+
+// History reads the transitions of a quest
+func (s *PostgresStore) History(ctx context.Context, questID string) ([]Transition, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT COALESCE(from_status, ''), to_status, note, COALESCE(user_id, 0), created_at
+		FROM quest_transitions
+		WHERE quest_id = $1
+		ORDER BY created_at, id
+	`, questID)
+	if err != nil {
+		return nil, fmt.Errorf("loading quest history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []Transition
+	for rows.Next() {
+		t := Transition{QuestID: questID}
+		if err := rows.Scan(&t.From, &t.To, &t.Note, &t.UserID, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning quest transition: %w", err)
+		}
+		history = append(history, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("loading quest history: %w", err)
+	}
+	return history, nil
+}
+
+func insertTransition(ctx context.Context, tx pgx.Tx, t Transition) error {
+	var from *string
+	if t.From != "" {
+		status := string(t.From)
+		from = &status
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO quest_transitions (quest_id, from_status, to_status, note, user_id)
+		VALUES ($1, $2, $3, $4, $5)
+	`, t.QuestID, from, t.To, t.Note, t.UserID)
+	if err != nil {
+		return fmt.Errorf("recording quest transition: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Package quest tracks the quests of a game and their status changes.
+package quest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned by a Store when a game has no quest with the given name
+	ErrNotFound = errors.New("quest not found")
+
+	// ErrExists is returned by a Store when a game already has a quest with the given name
+	ErrExists = errors.New("quest already exists")
+
+	// ErrConflict is returned by a Store when the quest status changed concurrently
+	ErrConflict = errors.New("quest status changed concurrently")
+)
+
+// Status is the state of a quest
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// transitions lists the allowed status changes. Progress is recorded as active -> active.
+var transitions = map[Status][]Status{
+	StatusActive:    {StatusActive, StatusCompleted, StatusFailed},
+	StatusCompleted: {},
+	StatusFailed:    {},
+}
+
+// CanTransition reports whether a quest may move from one status to another
+func CanTransition(from, to Status) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Label returns the status shown to players
+func (s Status) Label() string {
+	switch s {
+	case StatusActive:
+		return "активен"
+	case StatusCompleted:
+		return "выполнен"
+	case StatusFailed:
+		return "провален"
+	}
+	return string(s)
+}
+
+// Quest is a storyline goal of a game
+type Quest struct {
+	ID          string
+	GameID      string
+	Name        string
+	Description string
+	Status      Status
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Transition is a recorded status change of a quest. From is empty for the creation.
+type Transition struct {
+	QuestID   string
+	From      Status
+	To        Status
+	Note      string
+	UserID    int64
+	CreatedAt time.Time
+}
+
+// Store persists quests and their transition history
+type Store interface {
+	// Create inserts a quest with its initial transition and assigns its ID, or returns ErrExists
+	Create(ctx context.Context, q *Quest, initial Transition) error
+
+	// Get returns a quest by case-insensitive name or ErrNotFound
+	Get(ctx context.Context, gameID, name string) (*Quest, error)
+
+	// List returns the quests of a game, oldest first
+	List(ctx context.Context, gameID string) ([]*Quest, error)
+
+	// Transition records a status change. It returns ErrConflict when the stored status is not t.From.
+	Transition(ctx context.Context, q *Quest, t Transition) error
+
+	// History returns the transitions of a quest, oldest first
+	History(ctx context.Context, questID string) ([]Transition, error)
+}
+
+// validateName rejects names that cannot be addressed by the /quest command
+func validateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("quest name is required")
+	}
+	if len([]rune(name)) > 100 {
+		return fmt.Errorf("quest name is longer than 100 characters")
+	}
+	return nil
+}
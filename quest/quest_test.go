@@ -0,0 +1,207 @@
+package quest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/tools"
+)
+
+func TestCanTransition(t *testing.T) {
+	cases := []struct {
+		from, to Status
+		want     bool
+	}{
+		{StatusActive, StatusActive, true},
+		{StatusActive, StatusCompleted, true},
+		{StatusActive, StatusFailed, true},
+		{StatusCompleted, StatusActive, false},
+		{StatusCompleted, StatusFailed, false},
+		{StatusFailed, StatusCompleted, false},
+		{Status("unknown"), StatusActive, false},
+	}
+	for _, tc := range cases {
+		if got := CanTransition(tc.from, tc.to); got != tc.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tc.from, tc.to, got, tc.want)
+		}
+	}
+}
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	s, err := NewService(NewMemoryStore())
+	if err != nil {
+		t.Fatalf("creating service: %v", err)
+	}
+	return s
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("nil store", func(t *testing.T) {
+		if _, err := NewService(nil); err == nil {
+			t.Error("expected error for nil store")
+		}
+	})
+
+	t.Run("lifecycle is recorded", func(t *testing.T) {
+		s := newTestService(t)
+		if _, err := s.Create(ctx, "game", 1, "Спасти деревню", "Орки у ворот"); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if _, err := s.Progress(ctx, "game", 2, "спасти деревню", "Ворота укреплены"); err != nil {
+			t.Fatalf("progress: %v", err)
+		}
+		q, err := s.Complete(ctx, "game", 1, "Спасти деревню", "Орки отступили")
+		if err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+		if q.Status != StatusCompleted {
+			t.Errorf("expected completed, got %s", q.Status)
+		}
+
+		_, history, err := s.Get(ctx, "game", "Спасти деревню")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if len(history) != 3 {
+			t.Fatalf("expected 3 transitions, got %+v", history)
+		}
+		if history[0].From != "" || history[1].From != StatusActive || history[2].To != StatusCompleted || history[1].UserID != 2 {
+			t.Errorf("unexpected history %+v", history)
+		}
+	})
+
+	t.Run("finished quests cannot change", func(t *testing.T) {
+		s := newTestService(t)
+		_, _ = s.Create(ctx, "game", 1, "Найти меч", "")
+		if _, err := s.Fail(ctx, "game", 1, "Найти меч", "Меч утонул"); err != nil {
+			t.Fatalf("fail: %v", err)
+		}
+		if _, err := s.Complete(ctx, "game", 1, "Найти меч", ""); err == nil {
+			t.Error("expected a failed quest to stay failed")
+		}
+		if _, err := s.Progress(ctx, "game", 1, "Найти меч", "Нашли"); err == nil {
+			t.Error("expected no progress on a failed quest")
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		s := newTestService(t)
+		if _, err := s.Create(ctx, "game", 1, "  ", ""); err == nil {
+			t.Error("expected error for an empty name")
+		}
+		_, _ = s.Create(ctx, "game", 1, "Квест", "")
+		if _, err := s.Create(ctx, "game", 1, "КВЕСТ", ""); !errors.Is(err, ErrExists) {
+			t.Errorf("expected ErrExists, got %v", err)
+		}
+		if _, err := s.Create(ctx, "other", 1, "Квест", ""); err != nil {
+			t.Errorf("expected names to be scoped to a game: %v", err)
+		}
+		if _, err := s.Progress(ctx, "game", 1, "Квест", ""); err == nil {
+			t.Error("expected error for an empty progress note")
+		}
+		if _, err := s.Complete(ctx, "game", 1, "Нет такого", ""); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestService_TurnContext(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	text, err := s.TurnContext(ctx, "game", 1)
+	if err != nil || text != "" {
+		t.Errorf("expected no context without quests, got %q, %v", text, err)
+	}
+
+	_, _ = s.Create(ctx, "game", 1, "Дракон", "Убить дракона")
+	_, _ = s.Progress(ctx, "game", 1, "Дракон", "Логово найдено")
+	_, _ = s.Create(ctx, "game", 1, "Клад", "Найти клад")
+	_, _ = s.Complete(ctx, "game", 1, "Клад", "")
+
+	text, _ = s.TurnContext(ctx, "game", 1)
+	if !strings.Contains(text, "- Дракон: Логово найдено") {
+		t.Errorf("expected the latest note of the active quest, got %q", text)
+	}
+	if strings.Contains(text, "Клад") {
+		t.Errorf("expected finished quests to be left out, got %q", text)
+	}
+}
+
+func TestService_ActiveQuestID(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	if id := s.ActiveQuestID(ctx, "game"); id != "" {
+		t.Errorf("expected no active quest, got %q", id)
+	}
+
+	dragon, _ := s.Create(ctx, "game", 1, "Дракон", "")
+	treasure, _ := s.Create(ctx, "game", 1, "Клад", "")
+	if id := s.ActiveQuestID(ctx, "game"); id != treasure.ID {
+		t.Errorf("expected the latest quest %q, got %q", treasure.ID, id)
+	}
+
+	_, _ = s.Complete(ctx, "game", 1, "Клад", "")
+	if id := s.ActiveQuestID(ctx, "game"); id != dragon.ID {
+		t.Errorf("expected the remaining active quest %q, got %q", dragon.ID, id)
+	}
+}
+
+func TestMemoryStore_Conflict(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	q := &Quest{GameID: "game", Name: "Квест", Status: StatusActive}
+	_ = store.Create(ctx, q, Transition{To: StatusActive})
+
+	stale := *q
+	if err := store.Transition(ctx, q, Transition{From: StatusActive, To: StatusFailed}); err != nil {
+		t.Fatalf("transition: %v", err)
+	}
+	err := store.Transition(ctx, &stale, Transition{From: StatusActive, To: StatusCompleted})
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict for a stale status, got %v", err)
+	}
+}
+
+func TestTool(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	registry := tools.NewRegistry()
+	if err := registry.Register(NewTool(s)); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	call := func(arguments string) (string, error) {
+		return registry.Call(ctx, tools.Invocation{GameID: "g1", UserID: 1},
+			interfaces.ToolCall{ID: "call_1", Name: ToolName, Arguments: arguments})
+	}
+
+	result, err := call(`{"action":"create","name":"Пропавший караван","note":"Найти караван"}`)
+	if err != nil || result != "Квест «Пропавший караван» — активен" {
+		t.Fatalf("unexpected result %q: %v", result, err)
+	}
+	if _, err := call(`{"action":"progress","name":"пропавший караван"}`); err == nil {
+		t.Error("expected error for progress without a note")
+	}
+	result, err = call(`{"action":"complete","name":"Пропавший караван","note":"Караван спасён"}`)
+	if err != nil || !strings.Contains(result, "выполнен") {
+		t.Errorf("unexpected result %q: %v", result, err)
+	}
+	if _, err := call(`{"action":"fail","name":"Дракон"}`); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+	if _, err := call(`{"action":"delete","name":"Пропавший караван"}`); err == nil {
+		t.Error("expected error for an unknown action")
+	}
+
+	q, history, _ := s.Get(ctx, "g1", "Пропавший караван")
+	if q.Status != StatusCompleted || len(history) != 2 || history[1].UserID != 1 {
+		t.Errorf("expected the quest completed by the player, got %+v with %+v", q, history)
+	}
+}
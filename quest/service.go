@@ -0,0 +1,170 @@
+package quest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MaxContextQuests bounds the number of active quests summarized for the game master
+const MaxContextQuests = 10
+
+// Service creates quests and moves them through the state machine
+type Service struct {
+	store Store
+}
+
+// NewService creates a quest service
+func NewService(store Store) (*Service, error) {
+	if store == nil {
+		return nil, fmt.Errorf("quest store cannot be nil")
+	}
+	return &Service{store: store}, nil
+}
+
+// Create starts a new active quest
+func (s *Service) Create(ctx context.Context, gameID string, userID int64, name, description string) (*Quest, error) {
+	name = strings.TrimSpace(name)
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	q := &Quest{
+		GameID:      gameID,
+		Name:        name,
+		Description: strings.TrimSpace(description),
+		Status:      StatusActive,
+	}
+	if err := s.store.Create(ctx, q, Transition{To: StatusActive, Note: q.Description, UserID: userID}); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("game_id", gameID).
+		Str("quest_id", q.ID).
+		Str("quest", q.Name).
+		Msg("Quest created")
+
+	return q, nil
+}
+
+// Progress records an advance of an active quest
+func (s *Service) Progress(ctx context.Context, gameID string, userID int64, name, note string) (*Quest, error) {
+	if strings.TrimSpace(note) == "" {
+		return nil, fmt.Errorf("progress note is required")
+	}
+	return s.transition(ctx, gameID, userID, name, StatusActive, note)
+}
+
+// Complete marks an active quest as completed
+func (s *Service) Complete(ctx context.Context, gameID string, userID int64, name, note string) (*Quest, error) {
+	return s.transition(ctx, gameID, userID, name, StatusCompleted, note)
+}
+
+// Fail marks an active quest as failed
+func (s *Service) Fail(ctx context.Context, gameID string, userID int64, name, note string) (*Quest, error) {
+	return s.transition(ctx, gameID, userID, name, StatusFailed, note)
+}
+
+// Get returns a quest with its transition history
+func (s *Service) Get(ctx context.Context, gameID, name string) (*Quest, []Transition, error) {
+	q, err := s.store.Get(ctx, gameID, strings.TrimSpace(name))
+	if err != nil {
+		return nil, nil, err
+	}
+	history, err := s.store.History(ctx, q.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return q, history, nil
+}
+
+// List returns the quests of a game
+func (s *Service) List(ctx context.Context, gameID string) ([]*Quest, error) {
+	return s.store.List(ctx, gameID)
+}
+
+func (s *Service) transition(ctx context.Context, gameID string, userID int64, name string, to Status, note string) (*Quest, error) {
+	q, err := s.store.Get(ctx, gameID, strings.TrimSpace(name))
+	if err != nil {
+		return nil, err
+	}
+	if !CanTransition(q.Status, to) {
+		return nil, fmt.Errorf("quest %q is %s and cannot become %s", q.Name, q.Status.Label(), to.Label())
+	}
+
+	t := Transition{QuestID: q.ID, From: q.Status, To: to, Note: strings.TrimSpace(note), UserID: userID}
+	if err := s.store.Transition(ctx, q, t); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("game_id", gameID).
+		Str("quest_id", q.ID).
+		Str("from", string(t.From)).
+		Str("to", string(t.To)).
+		Msg("Quest transition")
+
+	return q, nil
+}
+
+// TurnContext summarizes the active quests of the game for the game master
+func (s *Service) TurnContext(ctx context.Context, gameID string, userID int64) (string, error) {
+	quests, err := s.store.List(ctx, gameID)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	count := 0
+	for _, q := range quests {
+		if q.Status != StatusActive || count == MaxContextQuests {
+			continue
+		}
+		count++
+
+		fmt.Fprintf(&b, "\n- %s", q.Name)
+		history, err := s.store.History(ctx, q.ID)
+		if err != nil {
+			return "", err
+		}
+		if last := lastNote(history); last != "" {
+			fmt.Fprintf(&b, ": %s", last)
+		}
+	}
+	if count == 0 {
+		return "", nil
+	}
+	return "Активные квесты группы (держи сюжет согласованным с ними):" + b.String(), nil
+}
+
+// ActiveQuestID returns the ID of the most recently updated active quest of the game, it is used to tag campaign memory.
+// It is empty when the game has no active quest or the quests cannot be read.
+func (s *Service) ActiveQuestID(ctx context.Context, gameID string) string {
+	quests, err := s.store.List(ctx, gameID)
+	if err != nil {
+		return ""
+	}
+	var latest *Quest
+	for _, q := range quests {
+		if q.Status == StatusActive && (latest == nil || !q.UpdatedAt.Before(latest.UpdatedAt)) {
+			latest = q
+		}
+	}
+	if latest == nil {
+		return ""
+	}
+	return latest.ID
+}
+
+// lastNote returns the most recent note, the description of a quest without progress
+func lastNote(history []Transition) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Note != "" {
+			return history[i].Note
+		}
+	}
+	return ""
+}
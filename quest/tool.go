@@ -0,0 +1,78 @@
+package quest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/tools"
+)
+
+// ToolName is the name of the quest tool offered to the game master
+const ToolName = "update_quest"
+
+type toolArguments struct {
+	Action string `json:"action"`
+	Name   string `json:"name"`
+	Note   string `json:"note"`
+}
+
+// NewTool lets the game master record quest changes as they happen in the story,
+// so the quest log stays consistent with the narration
+func NewTool(service *Service) tools.Tool {
+	definition := interfaces.ToolDefinition{
+		Name:        ToolName,
+		Description: "Обновить журнал квестов группы: начать новый квест, отметить продвижение, выполнение или провал. Используй, когда в сюжете появляется цель или меняется её состояние.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"action": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"create", "progress", "complete", "fail"},
+					"description": "create — новый квест, progress — продвижение, complete — выполнен, fail — провален",
+				},
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Короткое название квеста, например «Пропавший караван»",
+				},
+				"note": map[string]interface{}{
+					"type":        "string",
+					"description": "Описание нового квеста или что произошло. Для progress обязательно",
+				},
+			},
+			"required": []string{"action", "name"},
+		},
+	}
+
+	return tools.NewFunc(definition, func(ctx context.Context, inv tools.Invocation, arguments json.RawMessage) (string, error) {
+		var args toolArguments
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return "", fmt.Errorf("decoding arguments: %w", err)
+		}
+
+		var q *Quest
+		var err error
+		switch args.Action {
+		case "create":
+			q, err = service.Create(ctx, inv.GameID, inv.UserID, args.Name, args.Note)
+		case "progress":
+			q, err = service.Progress(ctx, inv.GameID, inv.UserID, args.Name, args.Note)
+		case "complete":
+			q, err = service.Complete(ctx, inv.GameID, inv.UserID, args.Name, args.Note)
+		case "fail":
+			q, err = service.Fail(ctx, inv.GameID, inv.UserID, args.Name, args.Note)
+		default:
+			return "", fmt.Errorf("unknown action %q, expected create, progress, complete or fail", args.Action)
+		}
+
+		switch {
+		case errors.Is(err, ErrNotFound):
+			return "", fmt.Errorf("quest %q not found, create it first", args.Name)
+		case err != nil:
+			return "", err
+		}
+		return fmt.Sprintf("Квест «%s» — %s", q.Name, q.Status.Label()), nil
+	})
+}
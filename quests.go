@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-llm-rpggamemaster/quest"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+)
+
+const questUsage = `Использование:
+/quests — список квестов
+/quest <название> — квест и его история
+/quest new <название> | <описание>
+/quest progress <название> | <что произошло>
+/quest done <название> | <итог>
+/quest fail <название> | <итог>`
+
+// newQuestService keeps quests next to the game sessions
+func newQuestService() (*quest.Service, error) {
+	if dbPool == nil {
+		return quest.NewService(quest.NewMemoryStore())
+	}
+	store, err := quest.NewPostgresStore(dbPool)
+	if err != nil {
+		return nil, err
+	}
+	return quest.NewService(store)
+}
+
+// questsHandler lists the quests of the chat's game, active first
+func questsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	gameID, ok := chatGame(ctx, b, chatID)
+	if !ok {
+		return
+	}
+
+	list, err := quests.List(ctx, gameID)
+	if err != nil {
+		log.Err(err).Int64("chat_id", chatID).Msg("failed to list quests")
+		reply(ctx, b, chatID, "Не удалось загрузить квесты, попробуйте позже")
+		return
+	}
+	if len(list) == 0 {
+		reply(ctx, b, chatID, "Квестов пока нет.\n\n"+questUsage)
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString("📜 Квесты:")
+	for _, status := range []quest.Status{quest.StatusActive, quest.StatusCompleted, quest.StatusFailed} {
+		for _, q := range list {
+			if q.Status == status {
+				fmt.Fprintf(&text, "\n%s %s — %s", questIcon(q.Status), q.Name, q.Status.Label())
+			}
+		}
+	}
+	reply(ctx, b, chatID, text.String())
+}
+
+// questHandler shows a quest or changes its status
+func questHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+
+	args := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/quest"))
+	if args == "" {
+		reply(ctx, b, chatID, questUsage)
+		return
+	}
+
+	gameID, ok := chatGame(ctx, b, chatID)
+	if !ok {
+		return
+	}
+
+	action, rest, _ := strings.Cut(args, " ")
+	name, note, _ := strings.Cut(rest, "|")
+	name, note = strings.TrimSpace(name), strings.TrimSpace(note)
+
+	var q *quest.Quest
+	var err error
+	switch strings.ToLower(action) {
+	case "new":
+		q, err = quests.Create(ctx, gameID, userID, name, note)
+	case "progress":
+		q, err = quests.Progress(ctx, gameID, userID, name, note)
+	case "done":
+		q, err = quests.Complete(ctx, gameID, userID, name, note)
+	case "fail":
+		q, err = quests.Fail(ctx, gameID, userID, name, note)
+	default:
+		showQuest(ctx, b, chatID, gameID, args)
+		return
+	}
+
+	switch {
+	case errors.Is(err, quest.ErrNotFound):
+		reply(ctx, b, chatID, fmt.Sprintf("Квест «%s» не найден", name))
+	case errors.Is(err, quest.ErrExists):
+		reply(ctx, b, chatID, fmt.Sprintf("Квест «%s» уже есть", name))
+	case err != nil:
+		reply(ctx, b, chatID, fmt.Sprintf("Не удалось обновить квест: %s\n\n%s", err.Error(), questUsage))
+	default:
+		reply(ctx, b, chatID, fmt.Sprintf("%s Квест «%s» — %s", questIcon(q.Status), q.Name, q.Status.Label()))
+	}
+}
+
+func showQuest(ctx context.Context, b *bot.Bot, chatID int64, gameID, name string) {
+	q, history, err := quests.Get(ctx, gameID, name)
+	if errors.Is(err, quest.ErrNotFound) {
+		reply(ctx, b, chatID, fmt.Sprintf("Квест «%s» не найден", name))
+		return
+	}
+	if err != nil {
+		log.Err(err).Int64("chat_id", chatID).Msg("failed to load quest")
+		reply(ctx, b, chatID, "Не удалось загрузить квест, попробуйте позже")
+		return
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s %s — %s", questIcon(q.Status), q.Name, q.Status.Label())
+	if q.Description != "" {
+		fmt.Fprintf(&text, "\n%s", q.Description)
+	}
+	text.WriteString("\n\nИстория:")
+	for _, t := range history {
+		fmt.Fprintf(&text, "\n%s %s", t.CreatedAt.Format("02.01 15:04"), t.To.Label())
+		if t.Note != "" {
+			fmt.Fprintf(&text, ": %s", t.Note)
+		}
+	}
+	reply(ctx, b, chatID, text.String())
+}
+
+func questIcon(status quest.Status) string {
+	switch status {
+	case quest.StatusCompleted:
+		return "✅"
+	case quest.StatusFailed:
+		return "❌"
+	}
+	return "🔸"
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+
+	"go-llm-rpggamemaster/config"
+	"go-llm-rpggamemaster/retrievers/rerank"
+	"go-llm-rpggamemaster/session"
+)
+
+// newReranker creates the reranker chosen in the config, or nil when reranking is off
+func newReranker(cfg config.RerankConfig) (session.Reranker, error) {
+	switch cfg.Type {
+	case "":
+		return nil, nil
+	case "llm":
+		return rerank.NewLLMReranker(llmProvider, rerank.DefaultLLMConfig())
+	case "lexical":
+		return rerank.NewLexicalReranker(), nil
+	default:
+		return nil, fmt.Errorf("unknown reranker type %q, expected llm or lexical", cfg.Type)
+	}
+}
@@ -2,18 +2,24 @@ package dualwrite
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/internal/uuid"
 )
 
 // Retriever defines the interface for document retrieval
 type Retriever interface {
-	GetRelevantDocuments(ctx context.Context, query string) ([]interfaces.Document, error)
+	GetScopedDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.Document, error)
+	GetScoredDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.ScoredDocument, error)
 	AddDocuments(ctx context.Context, docs []interfaces.Document) error
+	UpsertDocuments(ctx context.Context, docs []interfaces.Document) error
+	DeleteDocuments(ctx context.Context, ids []string) error
+	DeleteByFilter(ctx context.Context, filter interfaces.DocumentFilter) error
 }
 
 // ReadSource specifies which database to read from
@@ -50,8 +56,44 @@ func NewDualWriteRetriever(qdrant, postgres Retriever, readFrom ReadSource) (*Du
 	}, nil
 }
 
-// AddDocuments writes documents to both databases concurrently
+// AddDocuments writes documents to both databases concurrently.
+// IDs are assigned first, so a document has the same ID in both.
 func (r *DualWriteRetriever) AddDocuments(ctx context.Context, docs []interfaces.Document) error {
+	assignIDs(docs)
+	return either(r.write(ctx, "Dual-write", func(target Retriever) error {
+		return target.AddDocuments(ctx, docs)
+	}))
+}
+
+// UpsertDocuments replaces documents in both databases concurrently
+func (r *DualWriteRetriever) UpsertDocuments(ctx context.Context, docs []interfaces.Document) error {
+	assignIDs(docs)
+	return either(r.write(ctx, "Dual-upsert", func(target Retriever) error {
+		return target.UpsertDocuments(ctx, docs)
+	}))
+}
+
+// DeleteDocuments removes documents from both databases concurrently.
+// It fails when either database fails, so a forgotten fact cannot survive in the other one.
+func (r *DualWriteRetriever) DeleteDocuments(ctx context.Context, ids []string) error {
+	return both(r.write(ctx, "Dual-delete", func(target Retriever) error {
+		return target.DeleteDocuments(ctx, ids)
+	}))
+}
+
+// DeleteByFilter removes matching documents from both databases concurrently.
+// It fails when either database fails.
+func (r *DualWriteRetriever) DeleteByFilter(ctx context.Context, filter interfaces.DocumentFilter) error {
+	if filter.IsEmpty() {
+		return interfaces.ErrEmptyFilter
+	}
+	return both(r.write(ctx, "Dual-delete", func(target Retriever) error {
+		return target.DeleteByFilter(ctx, filter)
+	}))
+}
+
+// write applies an operation to both databases and returns the error of each
+func (r *DualWriteRetriever) write(ctx context.Context, operation string, apply func(Retriever) error) (qdrantErr, postgresErr error) {
 	var wg sync.WaitGroup
 
 	metrics := struct {
@@ -66,9 +108,9 @@ func (r *DualWriteRetriever) AddDocuments(ctx context.Context, docs []interfaces
 	go func() {
 		defer wg.Done()
 		start := time.Now()
-		if err := r.qdrant.AddDocuments(ctx, docs); err != nil {
+		if err := apply(r.qdrant); err != nil {
 			metrics.qdrantErr = err
-			log.Error().Err(err).Msg("Failed to write to Qdrant")
+			log.Error().Err(err).Str("operation", operation).Msg("Failed to write to Qdrant")
 		}
 		metrics.qdrant = time.Since(start)
 	}()
@@ -78,9 +120,9 @@ func (r *DualWriteRetriever) AddDocuments(ctx context.Context, docs []interfaces
 	go func() {
 		defer wg.Done()
 		start := time.Now()
-		if err := r.postgres.AddDocuments(ctx, docs); err != nil {
+		if err := apply(r.postgres); err != nil {
 			metrics.postgresErr = err
-			log.Error().Err(err).Msg("Failed to write to PostgreSQL")
+			log.Error().Err(err).Str("operation", operation).Msg("Failed to write to PostgreSQL")
 		}
 		metrics.postgres = time.Since(start)
 	}()
@@ -91,35 +133,78 @@ func (r *DualWriteRetriever) AddDocuments(ctx context.Context, docs []interfaces
 	log.Info().
 		Dur("qdrant_latency", metrics.qdrant).
 		Dur("postgres_latency", metrics.postgres).
-		Msg("Dual-write completed")
+		Msg(operation + " completed")
 
-	// Handle errors
-	if metrics.qdrantErr != nil && metrics.postgresErr != nil {
-		return fmt.Errorf("both databases failed: qdrant=%v, postgres=%v", metrics.qdrantErr, metrics.postgresErr)
+	return metrics.qdrantErr, metrics.postgresErr
+}
+
+// either keeps a write best-effort: it fails only when both databases fail
+func either(qdrantErr, postgresErr error) error {
+	if qdrantErr != nil && postgresErr != nil {
+		return fmt.Errorf("both databases failed: qdrant=%v, postgres=%v", qdrantErr, postgresErr)
 	}
-	if metrics.qdrantErr != nil {
-		log.Warn().Err(metrics.qdrantErr).Msg("Qdrant write failed but PostgreSQL succeeded")
+	if qdrantErr != nil {
+		log.Warn().Err(qdrantErr).Msg("Qdrant write failed but PostgreSQL succeeded")
 	}
-	if metrics.postgresErr != nil {
-		log.Warn().Err(metrics.postgresErr).Msg("PostgreSQL write failed but Qdrant succeeded")
+	if postgresErr != nil {
+		log.Warn().Err(postgresErr).Msg("PostgreSQL write failed but Qdrant succeeded")
 	}
-
 	return nil
 }
 
-// GetRelevantDocuments retrieves documents from configured source
-func (r *DualWriteRetriever) GetRelevantDocuments(ctx context.Context, query string) ([]interfaces.Document, error) {
+// both fails when either database fails
+func both(qdrantErr, postgresErr error) error {
+	var errs []error
+	if qdrantErr != nil {
+		errs = append(errs, fmt.Errorf("qdrant: %w", qdrantErr))
+	}
+	if postgresErr != nil {
+		errs = append(errs, fmt.Errorf("postgres: %w", postgresErr))
+	}
+	return errors.Join(errs...)
+}
+
+// assignIDs gives documents without an ID a new one before they are written to both databases
+func assignIDs(docs []interfaces.Document) {
+	for i := range docs {
+		if docs[i].ID == "" {
+			docs[i].ID = uuid.New()
+		}
+	}
+}
+
+// GetScopedDocuments retrieves documents of a single game from configured source
+func (r *DualWriteRetriever) GetScopedDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.Document, error) {
+	switch r.readFrom {
+	case ReadFromQdrant:
+		return r.qdrant.GetScopedDocuments(ctx, query, scope)
+	case ReadFromPostgres:
+		return r.postgres.GetScopedDocuments(ctx, query, scope)
+	case ReadFromDual:
+		docs, err := r.postgres.GetScopedDocuments(ctx, query, scope)
+		if err != nil {
+			log.Warn().Err(err).Msg("PostgreSQL read failed, falling back to Qdrant")
+			return r.qdrant.GetScopedDocuments(ctx, query, scope)
+		}
+		return docs, nil
+	default:
+		return nil, fmt.Errorf("unknown read source: %s", r.readFrom)
+	}
+}
+
+// GetScoredDocuments retrieves scored documents of a single game from configured source.
+// The source of each document tells which database answered.
+func (r *DualWriteRetriever) GetScoredDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.ScoredDocument, error) {
 	switch r.readFrom {
 	case ReadFromQdrant:
-		return r.qdrant.GetRelevantDocuments(ctx, query)
+		return r.qdrant.GetScoredDocuments(ctx, query, scope)
 	case ReadFromPostgres:
-		return r.postgres.GetRelevantDocuments(ctx, query)
+		return r.postgres.GetScoredDocuments(ctx, query, scope)
 	case ReadFromDual:
-		// Read from both and merge (simple implementation: prefer Postgres)
-		docs, err := r.postgres.GetRelevantDocuments(ctx, query)
+		docs, err := r.postgres.GetScoredDocuments(ctx, query, scope)
 		if err != nil {
 			log.Warn().Err(err).Msg("PostgreSQL read failed, falling back to Qdrant")
-			return r.qdrant.GetRelevantDocuments(ctx, query)
+			return r.qdrant.GetScoredDocuments(ctx, query, scope)
 		}
 		return docs, nil
 	default:
@@ -0,0 +1,143 @@
+package dualwrite
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// MockRetriever fails every write with err and records the written documents and deleted IDs
+type MockRetriever struct {
+	err error
+
+	mu      sync.Mutex
+	docs    []interfaces.Document
+	deleted []string
+}
+
+func (m *MockRetriever) GetScopedDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.Document, error) {
+	return nil, nil
+}
+
+func (m *MockRetriever) GetScoredDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.ScoredDocument, error) {
+	return nil, nil
+}
+
+func (m *MockRetriever) AddDocuments(ctx context.Context, docs []interfaces.Document) error {
+	return m.store(docs)
+}
+
+func (m *MockRetriever) UpsertDocuments(ctx context.Context, docs []interfaces.Document) error {
+	return m.store(docs)
+}
+
+func (m *MockRetriever) DeleteDocuments(ctx context.Context, ids []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.deleted = append(m.deleted, ids...)
+	return nil
+}
+
+func (m *MockRetriever) DeleteByFilter(ctx context.Context, filter interfaces.DocumentFilter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+func (m *MockRetriever) store(docs []interfaces.Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.docs = append(m.docs, docs...)
+	return nil
+}
+
+func TestDualWriteRetriever_Writes(t *testing.T) {
+	ctx := context.Background()
+	failure := errors.New("store down")
+	filter := interfaces.DocumentFilter{GameID: "game"}
+
+	writes := map[string]func(r *DualWriteRetriever) error{
+		"add": func(r *DualWriteRetriever) error {
+			return r.AddDocuments(ctx, []interfaces.Document{{PageContent: "Дракон спит"}})
+		},
+		"upsert": func(r *DualWriteRetriever) error {
+			return r.UpsertDocuments(ctx, []interfaces.Document{{ID: "doc", PageContent: "Дракон проснулся"}})
+		},
+	}
+	deletes := map[string]func(r *DualWriteRetriever) error{
+		"delete": func(r *DualWriteRetriever) error {
+			return r.DeleteDocuments(ctx, []string{"doc"})
+		},
+		"delete by filter": func(r *DualWriteRetriever) error {
+			return r.DeleteByFilter(ctx, filter)
+		},
+	}
+
+	for name, write := range writes {
+		t.Run(name+" succeeds when one store fails", func(t *testing.T) {
+			qdrant, postgres := &MockRetriever{err: failure}, &MockRetriever{}
+			r, _ := NewDualWriteRetriever(qdrant, postgres, ReadFromPostgres)
+			if err := write(r); err != nil {
+				t.Errorf("expected a best-effort write, got %v", err)
+			}
+			if len(postgres.docs) != 1 || postgres.docs[0].ID == "" {
+				t.Errorf("expected the document with an ID in postgres, got %+v", postgres.docs)
+			}
+		})
+
+		t.Run(name+" fails when both stores fail", func(t *testing.T) {
+			r, _ := NewDualWriteRetriever(&MockRetriever{err: failure}, &MockRetriever{err: failure}, ReadFromPostgres)
+			if err := write(r); err == nil {
+				t.Error("expected error when both stores fail")
+			}
+		})
+	}
+
+	for name, remove := range deletes {
+		t.Run(name+" fails when qdrant fails", func(t *testing.T) {
+			r, _ := NewDualWriteRetriever(&MockRetriever{err: failure}, &MockRetriever{}, ReadFromPostgres)
+			if err := remove(r); !errors.Is(err, failure) {
+				t.Errorf("expected the qdrant error, got %v", err)
+			}
+		})
+
+		t.Run(name+" fails when postgres fails", func(t *testing.T) {
+			r, _ := NewDualWriteRetriever(&MockRetriever{}, &MockRetriever{err: failure}, ReadFromPostgres)
+			if err := remove(r); !errors.Is(err, failure) {
+				t.Errorf("expected the postgres error, got %v", err)
+			}
+		})
+
+		t.Run(name+" succeeds in both stores", func(t *testing.T) {
+			r, _ := NewDualWriteRetriever(&MockRetriever{}, &MockRetriever{}, ReadFromPostgres)
+			if err := remove(r); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	t.Run("delete reaches both stores", func(t *testing.T) {
+		qdrant, postgres := &MockRetriever{}, &MockRetriever{}
+		r, _ := NewDualWriteRetriever(qdrant, postgres, ReadFromPostgres)
+		_ = r.DeleteDocuments(ctx, []string{"a", "b"})
+		if len(qdrant.deleted) != 2 || len(postgres.deleted) != 2 {
+			t.Errorf("expected both stores to delete, got qdrant=%v postgres=%v", qdrant.deleted, postgres.deleted)
+		}
+	})
+
+	t.Run("delete by empty filter", func(t *testing.T) {
+		r, _ := NewDualWriteRetriever(&MockRetriever{}, &MockRetriever{}, ReadFromPostgres)
+		if err := r.DeleteByFilter(ctx, interfaces.DocumentFilter{}); !errors.Is(err, interfaces.ErrEmptyFilter) {
+			t.Errorf("expected ErrEmptyFilter, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,448 @@
+// Package memory provides a retriever that keeps documents in process memory.
+// It needs no database or network, which suits tests and ephemeral games.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/internal/uuid"
+	"go-llm-rpggamemaster/retrievers/rank"
+)
+
+// Retriever defines the interface for document retrieval
+type Retriever interface {
+	GetScopedDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.Document, error)
+	GetScoredDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.ScoredDocument, error)
+	AddDocuments(ctx context.Context, docs []interfaces.Document) error
+	UpsertDocuments(ctx context.Context, docs []interfaces.Document) error
+	DeleteDocuments(ctx context.Context, ids []string) error
+	DeleteByFilter(ctx context.Context, filter interfaces.DocumentFilter) error
+}
+
+// Config contains in-memory retriever settings
+type Config struct {
+	SnapshotPath string // Loaded on creation and written on Close when set
+	Limit        int    // Maximum number of returned documents
+}
+
+// DefaultConfig returns the default in-memory retriever settings
+func DefaultConfig() *Config {
+	return &Config{
+		Limit: 10,
+	}
+}
+
+// SearchOptions narrows a search. Scope.GameID is required; Metadata entries must all match the document metadata.
+type SearchOptions struct {
+	Scope    interfaces.SearchScope
+	Metadata map[string]interface{}
+	Limit    int
+}
+
+type document struct {
+	ID        string                 `json:"id"`
+	Content   string                 `json:"content"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Embedding []float32              `json:"embedding"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// MemoryRetriever ranks documents by cosine similarity and keyword overlap fused with RRF.
+// It is safe for concurrent use.
+type MemoryRetriever struct {
+	embedder interfaces.VectorEmbeddingProvider
+	config   *Config
+
+	mu   sync.RWMutex
+	docs []document
+}
+
+// Compile-time interface check
+var _ Retriever = (*MemoryRetriever)(nil)
+
+// NewMemoryRetriever creates an in-memory retriever, loading the snapshot when one exists
+func NewMemoryRetriever(embedder interfaces.VectorEmbeddingProvider, config *Config) (*MemoryRetriever, error) {
+	if embedder == nil {
+		return nil, fmt.Errorf("embedding provider cannot be nil")
+	}
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.Limit <= 0 {
+		config.Limit = DefaultConfig().Limit
+	}
+
+	r := &MemoryRetriever{embedder: embedder, config: config}
+	if config.SnapshotPath != "" {
+		if err := r.Load(config.SnapshotPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Close writes the snapshot when a snapshot path is configured
+func (r *MemoryRetriever) Close() {
+	if r.config.SnapshotPath == "" {
+		return
+	}
+	if err := r.Save(r.config.SnapshotPath); err != nil {
+		log.Error().Err(err).Str("path", r.config.SnapshotPath).Msg("Failed to write retriever snapshot")
+	}
+}
+
+// Len returns the number of stored documents
+func (r *MemoryRetriever) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.docs)
+}
+
+// AddDocuments embeds and stores documents. Documents without an ID get one.
+func (r *MemoryRetriever) AddDocuments(ctx context.Context, docs []interfaces.Document) error {
+	stored, err := r.prepare(ctx, docs)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.docs = append(r.docs, stored...)
+	r.mu.Unlock()
+	return nil
+}
+
+// UpsertDocuments replaces the documents with the same IDs and adds the others
+func (r *MemoryRetriever) UpsertDocuments(ctx context.Context, docs []interfaces.Document) error {
+	stored, err := r.prepare(ctx, docs)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index := make(map[string]int, len(r.docs))
+	for i, doc := range r.docs {
+		index[doc.ID] = i
+	}
+	for _, doc := range stored {
+		if i, ok := index[doc.ID]; ok {
+			doc.CreatedAt = r.docs[i].CreatedAt
+			r.docs[i] = doc
+			continue
+		}
+		index[doc.ID] = len(r.docs)
+		r.docs = append(r.docs, doc)
+	}
+	return nil
+}
+
+// DeleteDocuments removes the documents with the given IDs
+func (r *MemoryRetriever) DeleteDocuments(ctx context.Context, ids []string) error {
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	r.delete(func(doc *document) bool { return remove[doc.ID] })
+	return nil
+}
+
+// DeleteByFilter removes the documents matching the filter
+func (r *MemoryRetriever) DeleteByFilter(ctx context.Context, filter interfaces.DocumentFilter) error {
+	if filter.IsEmpty() {
+		return interfaces.ErrEmptyFilter
+	}
+	opts := SearchOptions{Scope: interfaces.SearchScope{GameID: filter.GameID, UserID: filter.UserID}}
+	if len(filter.Metadata) > 0 {
+		opts.Metadata = make(map[string]interface{}, len(filter.Metadata))
+		for key, value := range filter.Metadata {
+			opts.Metadata[key] = value
+		}
+	}
+	r.delete(func(doc *document) bool { return matches(doc, opts) })
+	return nil
+}
+
+func (r *MemoryRetriever) delete(remove func(*document) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.docs[:0]
+	for i := range r.docs {
+		if !remove(&r.docs[i]) {
+			kept = append(kept, r.docs[i])
+		}
+	}
+	// Clear the tail so removed embeddings can be collected
+	for i := len(kept); i < len(r.docs); i++ {
+		r.docs[i] = document{}
+	}
+	r.docs = kept
+}
+
+// prepare embeds documents for storage, assigning IDs to those without one
+func (r *MemoryRetriever) prepare(ctx context.Context, docs []interfaces.Document) ([]document, error) {
+	if len(docs) == 0 {
+		return nil, nil
+	}
+
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.PageContent
+	}
+	embeddings, err := r.embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embed documents: %w", err)
+	}
+	if len(embeddings) != len(docs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(docs), len(embeddings))
+	}
+
+	now := time.Now()
+	stored := make([]document, len(docs))
+	for i, doc := range docs {
+		if doc.ID == "" {
+			docs[i].ID = uuid.New()
+		}
+		stored[i] = document{
+			ID:        docs[i].ID,
+			Content:   doc.PageContent,
+			Metadata:  copyMetadata(doc.Metadata),
+			Embedding: embeddings[i],
+			CreatedAt: now,
+		}
+	}
+	return stored, nil
+}
+
+// GetScopedDocuments searches the documents of a game and, optionally, a player
+func (r *MemoryRetriever) GetScopedDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.Document, error) {
+	return r.Search(ctx, query, SearchOptions{Scope: scope})
+}
+
+// GetScoredDocuments works like GetScopedDocuments and reports the RRF score and ranks of each document
+func (r *MemoryRetriever) GetScoredDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.ScoredDocument, error) {
+	return r.ScoredSearch(ctx, query, SearchOptions{Scope: scope})
+}
+
+// Search runs a hybrid search over the documents matching the options
+func (r *MemoryRetriever) Search(ctx context.Context, query string, opts SearchOptions) ([]interfaces.Document, error) {
+	scored, err := r.ScoredSearch(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	return interfaces.Documents(scored), nil
+}
+
+// ScoredSearch works like Search and keeps the scores of the results
+func (r *MemoryRetriever) ScoredSearch(ctx context.Context, query string, opts SearchOptions) ([]interfaces.ScoredDocument, error) {
+	if opts.Scope.GameID == "" {
+		return nil, interfaces.ErrEmptyScope
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = r.config.Limit
+	}
+
+	embeddings, err := r.embedder.EmbedDocuments(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	if len(embeddings) == 0 || len(embeddings[0]) == 0 {
+		return nil, fmt.Errorf("empty embedding returned")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	candidates := make(map[string]*document)
+	for i := range r.docs {
+		if matches(&r.docs[i], opts) {
+			candidates[r.docs[i].ID] = &r.docs[i]
+		}
+	}
+
+	fused := rank.RRF(rank.DefaultK,
+		semanticRanking(candidates, embeddings[0], opts.Limit*2),
+		keywordRanking(candidates, rank.Terms(query), opts.Limit*2),
+	)
+	if opts.Scope.LocationID != "" {
+		for i := range fused {
+			if rank.MetadataString(candidates[fused[i].ID].Metadata["location_id"]) == opts.Scope.LocationID {
+				fused[i].Score *= 1 + rank.DefaultLocationBoost
+			}
+		}
+		rank.Sort(fused)
+	}
+	if len(fused) > opts.Limit {
+		fused = fused[:opts.Limit]
+	}
+
+	result := make([]interfaces.ScoredDocument, len(fused))
+	for i, f := range fused {
+		doc := candidates[f.ID]
+		result[i] = interfaces.ScoredDocument{
+			Document:     interfaces.Document{ID: doc.ID, PageContent: doc.Content, Metadata: copyMetadata(doc.Metadata)},
+			Score:        f.Score,
+			SemanticRank: f.Ranks[0],
+			KeywordRank:  f.Ranks[1],
+			Source:       "memory",
+		}
+	}
+	return result, nil
+}
+
+// Save writes all documents to a JSON snapshot. The file is replaced atomically.
+func (r *MemoryRetriever) Save(path string) error {
+	r.mu.RLock()
+	count := len(r.docs)
+	data, err := json.Marshal(r.docs)
+	r.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing snapshot: %w", err)
+	}
+
+	log.Debug().Str("path", path).Int("document_count", count).Msg("Retriever snapshot written")
+	return nil
+}
+
+// Load replaces all documents with the contents of a snapshot
+func (r *MemoryRetriever) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	var docs []document
+	if err := json.Unmarshal(data, &docs); err != nil {
+		return fmt.Errorf("decoding snapshot: %w", err)
+	}
+
+	r.mu.Lock()
+	r.docs = docs
+	r.mu.Unlock()
+
+	log.Info().Str("path", path).Int("document_count", len(docs)).Msg("Retriever snapshot loaded")
+	return nil
+}
+
+// matches applies the scope and metadata filters.
+// Values are compared as text, so numbers survive a JSON snapshot round trip.
+func matches(doc *document, opts SearchOptions) bool {
+	if opts.Scope.GameID != "" && rank.MetadataString(doc.Metadata["game_id"]) != opts.Scope.GameID {
+		return false
+	}
+	if opts.Scope.UserID != 0 && rank.MetadataString(doc.Metadata["user_id"]) != strconv.FormatInt(opts.Scope.UserID, 10) {
+		return false
+	}
+	for key, value := range opts.Metadata {
+		if rank.MetadataString(doc.Metadata[key]) != rank.MetadataString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func semanticRanking(candidates map[string]*document, query []float32, limit int) rank.Ranking {
+	scores := make(map[string]float64, len(candidates))
+	for id, doc := range candidates {
+		if len(doc.Embedding) == len(query) {
+			scores[id] = rank.Cosine(query, doc.Embedding)
+		}
+	}
+	return topN(scores, limit)
+}
+
+// keywordRanking scores documents by the occurrences of query terms, weighting rare terms higher
+func keywordRanking(candidates map[string]*document, terms []string, limit int) rank.Ranking {
+	if len(terms) == 0 {
+		return nil
+	}
+
+	contents := make(map[string]string, len(candidates))
+	documentFrequency := make(map[string]int, len(terms))
+	for id, doc := range candidates {
+		content := strings.ToLower(doc.Content)
+		contents[id] = content
+		for _, term := range terms {
+			if strings.Contains(content, term) {
+				documentFrequency[term]++
+			}
+		}
+	}
+
+	scores := make(map[string]float64)
+	for id, content := range contents {
+		score := 0.0
+		for _, term := range terms {
+			if count := strings.Count(content, term); count > 0 {
+				idf := math.Log(1 + float64(len(candidates))/float64(documentFrequency[term]))
+				score += (1 + math.Log(float64(count))) * idf
+			}
+		}
+		if score > 0 {
+			scores[id] = score
+		}
+	}
+	return topN(scores, limit)
+}
+
+// topN ranks the highest scores, ties by ID
+func topN(scores map[string]float64, limit int) rank.Ranking {
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	ranking := make(rank.Ranking)
+	for i := 0; i < len(ids) && i < limit; i++ {
+		ranking[ids[i]] = i + 1
+	}
+	return ranking
+}
+
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,251 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// MockEmbedder embeds texts as bag-of-words vectors over a fixed vocabulary
+type MockEmbedder struct {
+	vocabulary []string
+}
+
+func (m *MockEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, len(m.vocabulary))
+		for j, word := range m.vocabulary {
+			if strings.Contains(strings.ToLower(text), word) {
+				vector[j] = 1
+			}
+		}
+		embeddings[i] = vector
+	}
+	return embeddings, nil
+}
+
+func (m *MockEmbedder) Name() string {
+	return "mock"
+}
+
+var testDocs = []interfaces.Document{
+	{PageContent: "A dragon sleeps on a pile of gold", Metadata: map[string]interface{}{"game_id": "g1", "user_id": int64(1)}},
+	{PageContent: "The tavern keeper fears the dragon", Metadata: map[string]interface{}{"game_id": "g1", "user_id": int64(2), "location_id": "tavern"}},
+	{PageContent: "The forest is quiet", Metadata: map[string]interface{}{"game_id": "g1", "user_id": int64(1), "type": "description"}},
+	{PageContent: "Another dragon in another game", Metadata: map[string]interface{}{"game_id": "g2", "user_id": int64(1)}},
+}
+
+func newTestRetriever(t *testing.T, config *Config) *MemoryRetriever {
+	t.Helper()
+	embedder := &MockEmbedder{vocabulary: []string{"dragon", "tavern", "forest", "gold"}}
+	r, err := NewMemoryRetriever(embedder, config)
+	if err != nil {
+		t.Fatalf("creating retriever: %v", err)
+	}
+	return r
+}
+
+func TestMemoryRetriever_Search(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRetriever(t, nil)
+	if err := r.AddDocuments(ctx, testDocs); err != nil {
+		t.Fatalf("adding documents: %v", err)
+	}
+
+	t.Run("results are scoped to the game", func(t *testing.T) {
+		found, err := r.GetScopedDocuments(ctx, "dragon gold", interfaces.SearchScope{GameID: "g1"})
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if len(found) == 0 || found[0].PageContent != testDocs[0].PageContent {
+			t.Fatalf("expected the dragon hoard first, got %+v", found)
+		}
+		for _, doc := range found {
+			if doc.Metadata["game_id"] != "g1" {
+				t.Errorf("unexpected document from another game: %q", doc.PageContent)
+			}
+		}
+	})
+
+	t.Run("user scope filters players", func(t *testing.T) {
+		found, err := r.GetScopedDocuments(ctx, "dragon", interfaces.SearchScope{GameID: "g1", UserID: 2})
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if len(found) != 1 || found[0].PageContent != testDocs[1].PageContent {
+			t.Errorf("expected only the tavern document, got %+v", found)
+		}
+	})
+
+	t.Run("metadata filter", func(t *testing.T) {
+		found, err := r.Search(ctx, "quiet", SearchOptions{Scope: interfaces.SearchScope{GameID: "g1"}, Metadata: map[string]interface{}{"type": "description"}})
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if len(found) != 1 || found[0].PageContent != testDocs[2].PageContent {
+			t.Errorf("expected only the forest description, got %+v", found)
+		}
+	})
+
+	t.Run("location boost", func(t *testing.T) {
+		found, err := r.GetScopedDocuments(ctx, "dragon", interfaces.SearchScope{GameID: "g1", LocationID: "tavern"})
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if len(found) == 0 || found[0].PageContent != testDocs[1].PageContent {
+			t.Errorf("expected the tavern document first, got %+v", found)
+		}
+	})
+
+	t.Run("empty scope is rejected", func(t *testing.T) {
+		if _, err := r.GetScopedDocuments(ctx, "dragon", interfaces.SearchScope{}); !errors.Is(err, interfaces.ErrEmptyScope) {
+			t.Errorf("expected ErrEmptyScope, got %v", err)
+		}
+	})
+
+	t.Run("keyword scorer finds words outside the vocabulary", func(t *testing.T) {
+		found, err := r.GetScopedDocuments(ctx, "keeper", interfaces.SearchScope{GameID: "g1"})
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if len(found) == 0 || found[0].PageContent != testDocs[1].PageContent {
+			t.Errorf("expected the tavern keeper first, got %+v", found)
+		}
+	})
+}
+
+func TestMemoryRetriever_UpsertAndDelete(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRetriever(t, nil)
+	docs := make([]interfaces.Document, len(testDocs))
+	for i, doc := range testDocs {
+		docs[i] = interfaces.Document{PageContent: doc.PageContent, Metadata: doc.Metadata}
+	}
+	if err := r.AddDocuments(ctx, docs); err != nil {
+		t.Fatalf("adding documents: %v", err)
+	}
+	if docs[0].ID == "" || docs[0].ID == docs[1].ID {
+		t.Fatalf("expected distinct IDs assigned on add, got %+v", docs)
+	}
+
+	t.Run("scores and ranks are reported", func(t *testing.T) {
+		found, err := r.GetScoredDocuments(ctx, "dragon gold", interfaces.SearchScope{GameID: "g1"})
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if len(found) == 0 || found[0].ID != docs[0].ID || found[0].Source != "memory" {
+			t.Fatalf("expected the hoard first, got %+v", found)
+		}
+		if found[0].SemanticRank != 1 || found[0].KeywordRank != 1 || found[0].Score <= found[len(found)-1].Score {
+			t.Errorf("unexpected scores: %+v", found)
+		}
+	})
+
+	t.Run("upsert replaces by ID and adds new documents", func(t *testing.T) {
+		err := r.UpsertDocuments(ctx, []interfaces.Document{
+			{ID: docs[0].ID, PageContent: "The dragon has left the gold", Metadata: docs[0].Metadata},
+			{PageContent: "A new tavern opened", Metadata: map[string]interface{}{"game_id": "g1"}},
+		})
+		if err != nil {
+			t.Fatalf("upserting: %v", err)
+		}
+		if r.Len() != len(docs)+1 {
+			t.Errorf("expected one new document, got %d in total", r.Len())
+		}
+		found, _ := r.GetScopedDocuments(ctx, "gold", interfaces.SearchScope{GameID: "g1"})
+		if len(found) == 0 || found[0].ID != docs[0].ID || found[0].PageContent != "The dragon has left the gold" {
+			t.Errorf("expected the replaced document, got %+v", found)
+		}
+	})
+
+	t.Run("delete by ID", func(t *testing.T) {
+		if err := r.DeleteDocuments(ctx, []string{docs[1].ID, "unknown"}); err != nil {
+			t.Fatalf("deleting: %v", err)
+		}
+		if r.Len() != len(docs) {
+			t.Errorf("expected one document deleted, got %d left", r.Len())
+		}
+	})
+
+	t.Run("delete by filter", func(t *testing.T) {
+		if err := r.DeleteByFilter(ctx, interfaces.DocumentFilter{}); err != interfaces.ErrEmptyFilter {
+			t.Errorf("expected an empty filter refused, got %v", err)
+		}
+		if err := r.DeleteByFilter(ctx, interfaces.DocumentFilter{GameID: "g1"}); err != nil {
+			t.Fatalf("deleting: %v", err)
+		}
+		if found, _ := r.GetScopedDocuments(ctx, "dragon", interfaces.SearchScope{GameID: "g1"}); len(found) != 0 {
+			t.Errorf("expected the game deleted, got %+v", found)
+		}
+		found, _ := r.GetScopedDocuments(ctx, "dragon", interfaces.SearchScope{GameID: "g2"})
+		if len(found) != 1 || found[0].ID != docs[3].ID {
+			t.Errorf("expected only the other game left, got %+v", found)
+		}
+	})
+}
+
+func TestMemoryRetriever_Snapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	config := DefaultConfig()
+	config.SnapshotPath = path
+	r := newTestRetriever(t, config)
+	if err := r.AddDocuments(ctx, testDocs); err != nil {
+		t.Fatalf("adding documents: %v", err)
+	}
+	r.Close()
+
+	restored := newTestRetriever(t, config)
+	if restored.Len() != len(testDocs) {
+		t.Fatalf("expected %d documents after load, got %d", len(testDocs), restored.Len())
+	}
+
+	// user_id is decoded as float64 and must still match the scope
+	found, err := restored.GetScopedDocuments(ctx, "dragon", interfaces.SearchScope{GameID: "g1", UserID: 2})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(found) != 1 || found[0].PageContent != testDocs[1].PageContent {
+		t.Errorf("expected the tavern document after load, got %+v", found)
+	}
+
+	if err := restored.Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error for a missing snapshot")
+	}
+}
+
+func TestMemoryRetriever_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRetriever(t, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			doc := interfaces.Document{PageContent: fmt.Sprintf("dragon %d", i), Metadata: map[string]interface{}{"game_id": "g1"}}
+			if err := r.AddDocuments(ctx, []interfaces.Document{doc}); err != nil {
+				t.Errorf("adding document: %v", err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := r.GetScopedDocuments(ctx, "dragon", interfaces.SearchScope{GameID: "g1"}); err != nil {
+				t.Errorf("search: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if r.Len() != 20 {
+		t.Errorf("expected 20 documents, got %d", r.Len())
+	}
+}
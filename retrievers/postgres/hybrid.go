@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/pgvector/pgvector-go"
 
 	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/retrievers/rank"
 )
 
 // HybridSearchResult represents a single search result with RRF score
@@ -16,24 +18,105 @@ type HybridSearchResult struct {
 	Score        float64
 	SemanticRank int
 	KeywordRank  int
+	LocationID   string
+	CreatedAt    time.Time
+
+	embedding []float32
 }
 
-// SearchOptions contains options for hybrid search
+// SearchOptions contains options for hybrid search.
+// GameID is required, a zero UserID matches every player of the game.
+// Tuning is used as is, start from DefaultTuning.
 type SearchOptions struct {
 	GameID string
 	UserID int64
 	Limit  int
 	RRFK   int // RRF constant, default 60
+
+	// LocationID boosts results tagged with this location by LocationBoost, default 0.5 (+50%)
+	LocationID    string
+	LocationBoost float64
+
+	Tuning
+}
+
+// Tuning adjusts how hybrid search ranks results. DefaultTuning ranks by plain RRF.
+type Tuning struct {
+	// SemanticWeight and KeywordWeight scale the RRF score of each channel, 0 turns a channel off
+	SemanticWeight float64
+	KeywordWeight  float64
+
+	// MMRLambda diversifies results with maximal marginal relevance over their embeddings.
+	// 1 ranks by relevance only, lower values push out paraphrases of results already chosen. 0 disables MMR.
+	MMRLambda float64
+
+	// RecencyHalfLife decays the score of older items: RecencyWeight of the score halves every half-life.
+	// A zero half-life or a zero weight disables the decay.
+	RecencyHalfLife time.Duration
+	RecencyWeight   float64
+}
+
+// DefaultRecencyWeight is the share of the score that decays with age when none is configured,
+// so old lore keeps at least half of its score
+const DefaultRecencyWeight = 0.5
+
+// DefaultTuning weighs both channels equally, without MMR and recency decay
+func DefaultTuning() Tuning {
+	return Tuning{
+		SemanticWeight: 1,
+		KeywordWeight:  1,
+		RecencyWeight:  DefaultRecencyWeight,
+	}
+}
+
+// Validate rejects negative weights and values out of range
+func (t Tuning) Validate() error {
+	if t.SemanticWeight < 0 || t.KeywordWeight < 0 {
+		return fmt.Errorf("channel weights cannot be negative: semantic %v, keyword %v", t.SemanticWeight, t.KeywordWeight)
+	}
+	if t.SemanticWeight == 0 && t.KeywordWeight == 0 {
+		return fmt.Errorf("at least one channel weight must be positive")
+	}
+	if t.MMRLambda < 0 || t.MMRLambda > 1 {
+		return fmt.Errorf("MMR lambda must be between 0 and 1, got %v", t.MMRLambda)
+	}
+	if t.RecencyHalfLife < 0 {
+		return fmt.Errorf("recency half-life cannot be negative, got %s", t.RecencyHalfLife)
+	}
+	if t.RecencyWeight < 0 || t.RecencyWeight > 1 {
+		return fmt.Errorf("recency weight must be between 0 and 1, got %v", t.RecencyWeight)
+	}
+	return nil
 }
 
 // HybridSearch performs hybrid search combining semantic and keyword results
 func (r *PostgresRetriever) HybridSearch(ctx context.Context, query string, opts SearchOptions) ([]interfaces.Document, error) {
+	results, err := r.ScoredHybridSearch(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]interfaces.Document, len(results))
+	for i, res := range results {
+		docs[i] = res.Document
+	}
+	return docs, nil
+}
+
+// ScoredHybridSearch performs hybrid search and keeps the RRF score and per-channel ranks of each result
+func (r *PostgresRetriever) ScoredHybridSearch(ctx context.Context, query string, opts SearchOptions) ([]HybridSearchResult, error) {
+	if opts.GameID == "" {
+		return nil, interfaces.ErrEmptyScope
+	}
 	if opts.RRFK == 0 {
-		opts.RRFK = 60
+		opts.RRFK = rank.DefaultK
 	}
 	if opts.Limit == 0 {
 		opts.Limit = 10
 	}
+	if opts.LocationBoost == 0 {
+		opts.LocationBoost = rank.DefaultLocationBoost
+	}
 
 	// Generate embedding for semantic search
 	embeddings, err := r.embedder.EmbedDocuments(ctx, []string{query})
@@ -86,38 +169,42 @@ func (r *PostgresRetriever) HybridSearch(ctx context.Context, query string, opts
 	}
 
 	// Combine using RRF
-	fused := rrfFusion(semantic, keyword, opts.RRFK)
+	fused := weightedFusion(semantic, keyword, opts.RRFK, opts.SemanticWeight, opts.KeywordWeight)
+	boostLocation(fused, opts.LocationID, opts.LocationBoost)
+	decayRecency(fused, time.Now(), opts.RecencyHalfLife, opts.RecencyWeight)
 
 	// Sort by score and limit
 	sort.Slice(fused, func(i, j int) bool {
 		return fused[i].Score > fused[j].Score
 	})
 
+	if opts.MMRLambda > 0 {
+		return diversify(fused, opts.MMRLambda, opts.Limit), nil
+	}
 	if len(fused) > opts.Limit {
 		fused = fused[:opts.Limit]
 	}
 
-	// Convert to documents
-	docs := make([]interfaces.Document, len(fused))
-	for i, res := range fused {
-		docs[i] = res.Document
-	}
-
-	return docs, nil
+	return fused, nil
 }
 
 type searchResult struct {
-	ID       string
-	Content  string
-	Metadata map[string]interface{}
-	Rank     int
+	ID         string
+	Content    string
+	Metadata   map[string]interface{}
+	LocationID string
+	Embedding  []float32
+	CreatedAt  time.Time
+	Rank       int
 }
 
 func (r *PostgresRetriever) semanticSearch(ctx context.Context, embedding []float32, gameID string, userID int64, limit int) ([]searchResult, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT id, content, metadata
+		SELECT id, content, metadata, COALESCE(location_id::text, ''), embedding, COALESCE(created_at, NOW())
 		FROM context_items
-		WHERE game_id = $1 AND user_id = $2 AND embedding IS NOT NULL
+		WHERE game_id = $1::uuid
+		  AND ($2::bigint = 0 OR user_id = $2::bigint)
+		  AND embedding IS NOT NULL
 		ORDER BY embedding <=> $3
 		LIMIT $4
 	`, gameID, userID, pgvector.NewVector(embedding), limit)
@@ -130,10 +217,12 @@ func (r *PostgresRetriever) semanticSearch(ctx context.Context, embedding []floa
 	rank := 1
 	for rows.Next() {
 		var sr searchResult
-		err := rows.Scan(&sr.ID, &sr.Content, &sr.Metadata)
+		var embedding pgvector.Vector
+		err := rows.Scan(&sr.ID, &sr.Content, &sr.Metadata, &sr.LocationID, &embedding, &sr.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("scanning semantic result: %w", err)
 		}
+		sr.Embedding = embedding.Slice()
 		sr.Rank = rank
 		rank++
 		results = append(results, sr)
@@ -142,15 +231,7 @@ func (r *PostgresRetriever) semanticSearch(ctx context.Context, embedding []floa
 }
 
 func (r *PostgresRetriever) keywordSearch(ctx context.Context, query, gameID string, userID int64, limit int) ([]searchResult, error) {
-	rows, err := r.db.Query(ctx, `
-		SELECT id, content, metadata,
-		       ts_rank(to_tsvector('english', content), plainto_tsquery('english', $1)) as rank
-		FROM context_items
-		WHERE game_id = $2 AND user_id = $3
-		  AND to_tsvector('english', content) @@ plainto_tsquery('english', $1)
-		ORDER BY rank DESC
-		LIMIT $4
-	`, query, gameID, userID, limit)
+	rows, err := r.db.Query(ctx, keywordSearchSQL(r.table), query, gameID, userID, limit)
 	if err != nil {
 		return nil, fmt.Errorf("keyword search query: %w", err)
 	}
@@ -160,11 +241,15 @@ func (r *PostgresRetriever) keywordSearch(ctx context.Context, query, gameID str
 	rank := 1
 	for rows.Next() {
 		var sr searchResult
+		var embedding *pgvector.Vector
 		var tsRank float64
-		err := rows.Scan(&sr.ID, &sr.Content, &sr.Metadata, &tsRank)
+		err := rows.Scan(&sr.ID, &sr.Content, &sr.Metadata, &sr.LocationID, &embedding, &sr.CreatedAt, &tsRank)
 		if err != nil {
 			return nil, fmt.Errorf("scanning keyword result: %w", err)
 		}
+		if embedding != nil {
+			sr.Embedding = embedding.Slice()
+		}
 		sr.Rank = rank
 		rank++
 		results = append(results, sr)
@@ -173,69 +258,85 @@ func (r *PostgresRetriever) keywordSearch(ctx context.Context, query, gameID str
 }
 
 func rrfFusion(semantic, keyword []searchResult, k int) []HybridSearchResult {
-	semanticMap := make(map[string]int)
-	for _, s := range semantic {
-		semanticMap[s.ID] = s.Rank
-	}
-
-	keywordMap := make(map[string]int)
-	for _, kw := range keyword {
-		keywordMap[kw.ID] = kw.Rank
-	}
+	return weightedFusion(semantic, keyword, k, 1, 1)
+}
 
-	// Collect all unique IDs
-	allIDs := make(map[string]struct{})
+// weightedFusion fuses the channels with RRF, scaling the score of each channel by its weight
+func weightedFusion(semantic, keyword []searchResult, k int, semanticWeight, keywordWeight float64) []HybridSearchResult {
+	docs := make(map[string]searchResult, len(semantic)+len(keyword))
+	semanticRanking := make(rank.Ranking, len(semantic))
 	for _, s := range semantic {
-		allIDs[s.ID] = struct{}{}
+		semanticRanking[s.ID] = s.Rank
+		docs[s.ID] = s
 	}
+	keywordRanking := make(rank.Ranking, len(keyword))
 	for _, kw := range keyword {
-		allIDs[kw.ID] = struct{}{}
+		keywordRanking[kw.ID] = kw.Rank
+		if _, ok := docs[kw.ID]; !ok {
+			docs[kw.ID] = kw
+		}
 	}
 
-	// Calculate RRF scores
-	var results []HybridSearchResult
-	for id := range allIDs {
-		semanticRank := semanticMap[id]
-		keywordRank := keywordMap[id]
-
-		score := 0.0
-		if semanticRank > 0 {
-			score += 1.0 / float64(k+semanticRank)
-		}
-		if keywordRank > 0 {
-			score += 1.0 / float64(k+keywordRank)
+	fused := rank.WeightedRRF(k, []float64{semanticWeight, keywordWeight}, semanticRanking, keywordRanking)
+	results := make([]HybridSearchResult, len(fused))
+	for i, f := range fused {
+		doc := docs[f.ID]
+		results[i] = HybridSearchResult{
+			Document: interfaces.Document{
+				ID:          doc.ID,
+				PageContent: doc.Content,
+				Metadata:    doc.Metadata,
+			},
+			Score:        f.Score,
+			SemanticRank: f.Ranks[0],
+			KeywordRank:  f.Ranks[1],
+			LocationID:   doc.LocationID,
+			CreatedAt:    doc.CreatedAt,
+			embedding:    doc.Embedding,
 		}
+	}
+	return results
+}
 
-		// Get document from either list
-		var doc interfaces.Document
-		for _, s := range semantic {
-			if s.ID == id {
-				doc = interfaces.Document{
-					PageContent: s.Content,
-					Metadata:    s.Metadata,
-				}
-				break
-			}
+// boostLocation raises the score of results tagged with the party location
+func boostLocation(results []HybridSearchResult, locationID string, boost float64) {
+	if locationID == "" {
+		return
+	}
+	for i := range results {
+		if results[i].LocationID == locationID {
+			results[i].Score *= 1 + boost
 		}
-		if doc.PageContent == "" {
-			for _, kw := range keyword {
-				if kw.ID == id {
-					doc = interfaces.Document{
-						PageContent: kw.Content,
-						Metadata:    kw.Metadata,
-					}
-					break
-				}
-			}
+	}
+}
+
+// decayRecency lowers the score of older results. weight is the share of the score that decays.
+func decayRecency(results []HybridSearchResult, now time.Time, halfLife time.Duration, weight float64) {
+	if halfLife <= 0 {
+		return
+	}
+	for i := range results {
+		if results[i].CreatedAt.IsZero() {
+			continue
 		}
+		decay := rank.Decay(now.Sub(results[i].CreatedAt), halfLife)
+		results[i].Score *= 1 - weight + weight*decay
+	}
+}
 
-		results = append(results, HybridSearchResult{
-			Document:     doc,
-			Score:        score,
-			SemanticRank: semanticRank,
-			KeywordRank:  keywordRank,
-		})
+// diversify picks up to limit results sorted by score with maximal marginal relevance
+func diversify(results []HybridSearchResult, lambda float64, limit int) []HybridSearchResult {
+	relevance := make([]float64, len(results))
+	embeddings := make([][]float32, len(results))
+	for i, res := range results {
+		relevance[i] = res.Score
+		embeddings[i] = res.embedding
 	}
 
-	return results
+	selected := rank.MMR(relevance, embeddings, lambda, limit)
+	diverse := make([]HybridSearchResult, len(selected))
+	for i, index := range selected {
+		diverse[i] = results[index]
+	}
+	return diverse
 }
@@ -2,7 +2,10 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -10,16 +13,25 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/internal/uuid"
+	"go-llm-rpggamemaster/retrievers/rank"
 )
 
 const (
 	defaultTableName = "context_items"
 )
 
+// ErrNoGameID is returned when a document to write has no game_id metadata
+var ErrNoGameID = errors.New("document has no game_id")
+
 // Retriever defines the interface for document retrieval
 type Retriever interface {
-	GetRelevantDocuments(ctx context.Context, query string) ([]interfaces.Document, error)
+	GetScopedDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.Document, error)
+	GetScoredDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.ScoredDocument, error)
 	AddDocuments(ctx context.Context, docs []interfaces.Document) error
+	UpsertDocuments(ctx context.Context, docs []interfaces.Document) error
+	DeleteDocuments(ctx context.Context, ids []string) error
+	DeleteByFilter(ctx context.Context, filter interfaces.DocumentFilter) error
 }
 
 // PostgresRetriever implements Retriever using PostgreSQL with pgvector
@@ -27,6 +39,7 @@ type PostgresRetriever struct {
 	db       *pgxpool.Pool
 	embedder interfaces.VectorEmbeddingProvider
 	table    string
+	tuning   Tuning
 }
 
 // Compile-time interface check
@@ -44,20 +57,47 @@ func NewPostgresRetriever(db *pgxpool.Pool, embedder interfaces.VectorEmbeddingP
 		db:       db,
 		embedder: embedder,
 		table:    defaultTableName,
+		tuning:   DefaultTuning(),
 	}, nil
 }
 
-// GetRelevantDocuments retrieves documents relevant to a query using hybrid search
-func (r *PostgresRetriever) GetRelevantDocuments(ctx context.Context, query string) ([]interfaces.Document, error) {
+// SetTuning adjusts the ranking of GetScopedDocuments and GetScoredDocuments. It must be called before use.
+// Start from DefaultTuning to change only some of the values.
+func (r *PostgresRetriever) SetTuning(tuning Tuning) error {
+	if err := tuning.Validate(); err != nil {
+		return fmt.Errorf("invalid ranking: %w", err)
+	}
+	r.tuning = tuning
+	return nil
+}
+
+// GetScopedDocuments retrieves documents of a single game (and player, if set) using hybrid search
+func (r *PostgresRetriever) GetScopedDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.Document, error) {
+	scored, err := r.GetScoredDocuments(ctx, query, scope)
+	if err != nil {
+		return nil, err
+	}
+	return interfaces.Documents(scored), nil
+}
+
+// GetScoredDocuments works like GetScopedDocuments and reports the RRF score and ranks of each document
+func (r *PostgresRetriever) GetScoredDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.ScoredDocument, error) {
+	if scope.GameID == "" {
+		return nil, interfaces.ErrEmptyScope
+	}
 	start := time.Now()
 
-	var docs []interfaces.Document
+	var results []HybridSearchResult
 
 	err := withRetry(ctx, DefaultRetryConfig(), func() error {
 		var err error
-		docs, err = r.HybridSearch(ctx, query, SearchOptions{
-			Limit: 10,
-			RRFK:  60,
+		results, err = r.ScoredHybridSearch(ctx, query, SearchOptions{
+			GameID:     scope.GameID,
+			UserID:     scope.UserID,
+			Limit:      10,
+			RRFK:       60,
+			LocationID: scope.LocationID,
+			Tuning:     r.tuning,
 		})
 		return err
 	})
@@ -71,16 +111,44 @@ func (r *PostgresRetriever) GetRelevantDocuments(ctx context.Context, query stri
 
 	log.Debug().
 		Dur("query_duration", time.Since(start)).
-		Int("result_count", len(docs)).
+		Str("game_id", scope.GameID).
+		Int64("user_id", scope.UserID).
+		Int("result_count", len(results)).
 		Msg("Documents retrieved successfully")
 
+	docs := make([]interfaces.ScoredDocument, len(results))
+	for i, res := range results {
+		docs[i] = interfaces.ScoredDocument{
+			Document:     res.Document,
+			Score:        res.Score,
+			SemanticRank: res.SemanticRank,
+			KeywordRank:  res.KeywordRank,
+			Source:       "postgres",
+		}
+	}
 	return docs, nil
 }
 
-// AddDocuments adds documents to the retriever
+// AddDocuments adds documents to the retriever. Documents without an ID get one.
 func (r *PostgresRetriever) AddDocuments(ctx context.Context, docs []interfaces.Document) error {
+	return r.write(ctx, docs, false)
+}
+
+// UpsertDocuments replaces the documents with the same IDs and adds the others
+func (r *PostgresRetriever) UpsertDocuments(ctx context.Context, docs []interfaces.Document) error {
+	return r.write(ctx, docs, true)
+}
+
+func (r *PostgresRetriever) write(ctx context.Context, docs []interfaces.Document, upsert bool) error {
 	start := time.Now()
 
+	// Every row belongs to a game; check before paying for the embeddings
+	for i, doc := range docs {
+		if rank.MetadataString(doc.Metadata["game_id"]) == "" {
+			return fmt.Errorf("document %d %q: %w", i, doc.ID, ErrNoGameID)
+		}
+	}
+
 	// Generate embeddings for all documents
 	texts := make([]string, len(docs))
 	for i, doc := range docs {
@@ -97,28 +165,133 @@ func (r *PostgresRetriever) AddDocuments(ctx context.Context, docs []interfaces.
 	}
 
 	// Insert each document with its embedding
-	insertSQL := fmt.Sprintf("INSERT INTO %s (game_id, user_id, content, embedding, metadata) VALUES ($1, $2, $3, $4, $5)", r.table)
+	insertSQL := fmt.Sprintf(`INSERT INTO %s (id, game_id, user_id, character_id, location_id, quest_id, content, embedding, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, r.table)
+	if upsert {
+		// search_config is set by the insert trigger from the game of the row
+		insertSQL += ` ON CONFLICT (id) DO UPDATE SET
+			game_id = EXCLUDED.game_id,
+			user_id = EXCLUDED.user_id,
+			character_id = EXCLUDED.character_id,
+			location_id = EXCLUDED.location_id,
+			quest_id = EXCLUDED.quest_id,
+			content = EXCLUDED.content,
+			embedding = EXCLUDED.embedding,
+			metadata = EXCLUDED.metadata,
+			search_config = EXCLUDED.search_config,
+			updated_at = NOW()`
+	}
 	for i, doc := range docs {
-		gameID, _ := doc.Metadata["game_id"].(string)
-		userID, _ := doc.Metadata["user_id"].(string)
+		if doc.ID == "" {
+			docs[i].ID = uuid.New()
+		}
+		gameID := rank.MetadataString(doc.Metadata["game_id"])
+		userID := rank.MetadataInt64(doc.Metadata["user_id"])
+		characterID := metadataUUID(doc.Metadata["character_id"])
+		locationID := metadataUUID(doc.Metadata["location_id"])
+		questID := metadataUUID(doc.Metadata["quest_id"])
 
 		err = withRetry(ctx, DefaultRetryConfig(), func() error {
-			_, err := r.db.Exec(ctx, insertSQL, gameID, userID, doc.PageContent, pgvector.NewVector(embeddings[i]), doc.Metadata)
+			_, err := r.db.Exec(ctx, insertSQL, docs[i].ID, gameID, userID, characterID, locationID, questID,
+				doc.PageContent, pgvector.NewVector(embeddings[i]), doc.Metadata)
 			return err
 		})
 		if err != nil {
-			return fmt.Errorf("inserting document %d: %w", i, err)
+			return fmt.Errorf("writing document %d: %w", i, err)
 		}
 	}
 
 	log.Info().
 		Int("document_count", len(docs)).
+		Bool("upsert", upsert).
 		Dur("operation_duration", time.Since(start)).
 		Msg("Documents added successfully")
 
 	return nil
 }
 
+// DeleteDocuments removes the documents with the given IDs
+func (r *PostgresRetriever) DeleteDocuments(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var deleted int64
+	err := withRetry(ctx, DefaultRetryConfig(), func() error {
+		tag, err := r.db.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = ANY($1::uuid[])`, r.table), ids)
+		deleted = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("deleting documents: %w", err)
+	}
+
+	log.Info().
+		Int("requested", len(ids)).
+		Int64("deleted", deleted).
+		Msg("Documents deleted")
+	return nil
+}
+
+// DeleteByFilter removes the documents matching the filter
+func (r *PostgresRetriever) DeleteByFilter(ctx context.Context, filter interfaces.DocumentFilter) error {
+	if filter.IsEmpty() {
+		return interfaces.ErrEmptyFilter
+	}
+	query, args := deleteByFilterSQL(r.table, filter)
+
+	var deleted int64
+	err := withRetry(ctx, DefaultRetryConfig(), func() error {
+		tag, err := r.db.Exec(ctx, query, args...)
+		deleted = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("deleting documents: %w", err)
+	}
+
+	log.Info().
+		Str("game_id", filter.GameID).
+		Int64("user_id", filter.UserID).
+		Int64("deleted", deleted).
+		Msg("Documents deleted by filter")
+	return nil
+}
+
+// deleteByFilterSQL builds the DELETE statement of a non-empty filter
+func deleteByFilterSQL(table string, filter interfaces.DocumentFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.GameID != "" {
+		conditions = append(conditions, "game_id = "+arg(filter.GameID)+"::uuid")
+	}
+	if filter.UserID != 0 {
+		conditions = append(conditions, "user_id = "+arg(filter.UserID))
+	}
+	keys := make([]string, 0, len(filter.Metadata))
+	for key := range filter.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		conditions = append(conditions, "metadata ->> "+arg(key)+" = "+arg(filter.Metadata[key]))
+	}
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", table, strings.Join(conditions, " AND ")), args
+}
+
+// metadataUUID returns a nullable UUID column value
+func metadataUUID(v interface{}) *string {
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return nil
+	}
+	return &s
+}
+
 // Close closes the database connection pool
 func (r *PostgresRetriever) Close() {
 	log.Debug().Msg("closing postgres retriever connection pool")
@@ -2,14 +2,20 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"testing"
 	"time"
+
+	"go-llm-rpggamemaster/interfaces"
 )
 
 // MockEmbedder is a mock embedding provider for testing
 type MockEmbedder struct {
 	vectors map[string][]float32
+	calls   int
 }
 
 func (m *MockEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
@@ -24,6 +30,7 @@ func (m *MockEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]fl
 }
 
 func (m *MockEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	m.calls++
 	embeddings := make([][]float32, len(texts))
 	for i, text := range texts {
 		vec, err := m.GenerateEmbedding(ctx, text)
@@ -83,13 +90,42 @@ func TestNewPostgresRetriever(t *testing.T) {
 	})
 }
 
-func TestPostgresRetriever_GetRelevantDocuments(t *testing.T) {
+func TestPostgresRetriever_GetScoredDocuments(t *testing.T) {
+	t.Run("empty scope is rejected", func(t *testing.T) {
+		r := &PostgresRetriever{embedder: &MockEmbedder{}}
+		if _, err := r.GetScoredDocuments(context.Background(), "dragon", interfaces.SearchScope{}); !errors.Is(err, interfaces.ErrEmptyScope) {
+			t.Errorf("expected ErrEmptyScope, got %v", err)
+		}
+		if _, err := r.ScoredHybridSearch(context.Background(), "dragon", SearchOptions{}); !errors.Is(err, interfaces.ErrEmptyScope) {
+			t.Errorf("expected ErrEmptyScope from hybrid search, got %v", err)
+		}
+	})
+
 	t.Run("context cancellation", func(t *testing.T) {
 		t.Skip("Requires actual database connection - will be tested with testcontainers-go")
 	})
 }
 
 func TestPostgresRetriever_AddDocuments(t *testing.T) {
+	t.Run("documents without a game are rejected before embedding", func(t *testing.T) {
+		embedder := &MockEmbedder{}
+		r := &PostgresRetriever{embedder: embedder, table: defaultTableName}
+		docs := []interfaces.Document{
+			{ID: "a", PageContent: "Дракон спит", Metadata: map[string]interface{}{"game_id": "game"}},
+			{ID: "b", PageContent: "Без игры", Metadata: map[string]interface{}{"game_id": ""}},
+		}
+		err := r.AddDocuments(context.Background(), docs)
+		if !errors.Is(err, ErrNoGameID) || !strings.Contains(err.Error(), `"b"`) {
+			t.Errorf("expected ErrNoGameID naming the document, got %v", err)
+		}
+		if err := r.UpsertDocuments(context.Background(), []interfaces.Document{{PageContent: "Без метаданных"}}); !errors.Is(err, ErrNoGameID) {
+			t.Errorf("expected ErrNoGameID on upsert, got %v", err)
+		}
+		if embedder.calls != 0 {
+			t.Errorf("expected no embedding requests, got %d", embedder.calls)
+		}
+	})
+
 	t.Run("empty document list", func(t *testing.T) {
 		t.Skip("Requires actual database connection - will be tested with testcontainers-go")
 	})
@@ -167,6 +203,117 @@ func TestHybridSearch(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("location boost", func(t *testing.T) {
+		semantic := []searchResult{
+			{ID: "1", Content: "tavern rumor", Rank: 1},
+			{ID: "2", Content: "forest lore", LocationID: "forest", Rank: 2},
+		}
+
+		results := rrfFusion(semantic, nil, 60)
+		boostLocation(results, "forest", 0.5)
+
+		for _, res := range results {
+			switch res.Document.PageContent {
+			case "tavern rumor":
+				if res.Score != 1.0/61.0 {
+					t.Errorf("untagged result should keep its score, got %f", res.Score)
+				}
+			case "forest lore":
+				if res.Score <= 1.0/61.0 {
+					t.Errorf("tagged result should outrank the untagged one, got %f", res.Score)
+				}
+			}
+		}
+	})
+}
+
+func TestRankingTuning(t *testing.T) {
+	t.Run("validation", func(t *testing.T) {
+		r := &PostgresRetriever{tuning: DefaultTuning()}
+
+		keywordOnly := DefaultTuning()
+		keywordOnly.SemanticWeight = 0
+		keywordOnly.RecencyWeight = 0
+		if err := r.SetTuning(keywordOnly); err != nil {
+			t.Errorf("expected a channel and the decay to be turned off, got %v", err)
+		}
+		if r.tuning.SemanticWeight != 0 || r.tuning.KeywordWeight != 1 || r.tuning.RecencyWeight != 0 {
+			t.Errorf("expected explicit zeros kept, got %+v", r.tuning)
+		}
+
+		invalid := map[string]Tuning{
+			"negative weight":   {SemanticWeight: -1, KeywordWeight: 1},
+			"no channel":        {},
+			"MMR out of range":  {SemanticWeight: 1, MMRLambda: 1.5},
+			"negative halflife": {SemanticWeight: 1, RecencyHalfLife: -time.Hour},
+			"recency over 1":    {SemanticWeight: 1, RecencyWeight: 2},
+		}
+		for name, tuning := range invalid {
+			if err := r.SetTuning(tuning); err == nil {
+				t.Errorf("%s: expected error", name)
+			}
+		}
+		if r.tuning.SemanticWeight != 0 || r.tuning.KeywordWeight != 1 {
+			t.Errorf("expected invalid tuning to be ignored, got %+v", r.tuning)
+		}
+	})
+
+	t.Run("zero channel weight drops the channel", func(t *testing.T) {
+		semantic := []searchResult{{ID: "1", Content: "semantic", Rank: 1}}
+		keyword := []searchResult{{ID: "2", Content: "keyword", Rank: 1}}
+
+		results := weightedFusion(semantic, keyword, 60, 1, 0)
+		for _, res := range results {
+			if res.Document.PageContent == "keyword" && res.Score != 0 {
+				t.Errorf("expected no score from the keyword channel, got %+v", res)
+			}
+		}
+	})
+
+	t.Run("channel weights", func(t *testing.T) {
+		semantic := []searchResult{{ID: "1", Content: "semantic", Rank: 1}}
+		keyword := []searchResult{{ID: "2", Content: "keyword", Rank: 1}}
+
+		results := weightedFusion(semantic, keyword, 60, 0.5, 1)
+		sort.Slice(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+		if results[0].Document.PageContent != "keyword" || results[1].Score != 0.5/61.0 {
+			t.Errorf("expected the keyword channel to weigh more, got %+v", results)
+		}
+	})
+
+	t.Run("recency decay", func(t *testing.T) {
+		now := time.Now()
+		results := []HybridSearchResult{
+			{Score: 1, CreatedAt: now},
+			{Score: 1, CreatedAt: now.Add(-24 * time.Hour)},
+			{Score: 1},
+		}
+		decayRecency(results, now, 24*time.Hour, 0.5)
+		if results[0].Score != 1 || results[1].Score != 0.75 || results[2].Score != 1 {
+			t.Errorf("expected half of the score to halve per day, got %+v", results)
+		}
+
+		decayRecency(results, now, 0, 0.5)
+		if results[1].Score != 0.75 {
+			t.Errorf("expected no decay without a half-life, got %f", results[1].Score)
+		}
+	})
+
+	t.Run("MMR drops paraphrases", func(t *testing.T) {
+		semantic := []searchResult{
+			{ID: "1", Content: "the dragon burned the mill", Embedding: []float32{1, 0}, Rank: 1},
+			{ID: "2", Content: "the mill was burned by the dragon", Embedding: []float32{0.99, 0.1}, Rank: 2},
+			{ID: "3", Content: "the mayor owes the party gold", Embedding: []float32{0, 1}, Rank: 3},
+		}
+		results := rrfFusion(semantic, nil, 60)
+		sort.Slice(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+
+		diverse := diversify(results, 0.5, 2)
+		if len(diverse) != 2 || diverse[0].Document.ID != "1" || diverse[1].Document.ID != "3" {
+			t.Errorf("expected the paraphrase dropped, got %+v", diverse)
+		}
+	})
 }
 
 func TestPoolConfig(t *testing.T) {
@@ -307,6 +454,17 @@ func TestContains(t *testing.T) {
 	}
 }
 
+func TestMetadataHelpers(t *testing.T) {
+	t.Run("empty uuid is null", func(t *testing.T) {
+		if metadataUUID("") != nil || metadataUUID(nil) != nil {
+			t.Error("expected nil for empty uuid")
+		}
+		if id := metadataUUID("abc"); id == nil || *id != "abc" {
+			t.Errorf("unexpected uuid: %v", id)
+		}
+	})
+}
+
 func TestMockEmbedder(t *testing.T) {
 	t.Run("generate embedding for known text", func(t *testing.T) {
 		embedder := &MockEmbedder{
@@ -499,3 +657,19 @@ func TestPostgresRetriever_Close(t *testing.T) {
 		retriever.Close()
 	})
 }
+
+func TestDeleteByFilterSQL(t *testing.T) {
+	query, args := deleteByFilterSQL("context_items", interfaces.DocumentFilter{
+		GameID:   "game-1",
+		UserID:   42,
+		Metadata: map[string]string{"type": "summary"},
+	})
+
+	expected := "DELETE FROM context_items WHERE game_id = $1::uuid AND user_id = $2 AND metadata ->> $3 = $4"
+	if query != expected {
+		t.Errorf("expected %q, got %q", expected, query)
+	}
+	if len(args) != 4 || args[0] != "game-1" || args[1] != int64(42) || args[2] != "type" || args[3] != "summary" {
+		t.Errorf("unexpected arguments: %v", args)
+	}
+}
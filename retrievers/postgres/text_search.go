@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TextSearch is the full-text search configuration of a game, see migrations/011_text_search_config.sql
+type TextSearch string
+
+const (
+	TextSearchRussian      TextSearch = "russian"
+	TextSearchEnglish      TextSearch = "english"
+	TextSearchSimple       TextSearch = "simple"       // no stemming, for names and mixed jargon
+	TextSearchMultilingual TextSearch = "multilingual" // russian and english stemming together
+)
+
+// DefaultTextSearch is the configuration of games that never chose one
+const DefaultTextSearch = TextSearchRussian
+
+// TextSearches lists the supported configurations
+var TextSearches = []TextSearch{TextSearchRussian, TextSearchEnglish, TextSearchSimple, TextSearchMultilingual}
+
+// ErrGameNotFound is returned when the configuration of a missing game is changed
+var ErrGameNotFound = errors.New("game not found")
+
+// ParseTextSearch validates a configuration name
+func ParseTextSearch(s string) (TextSearch, error) {
+	ts := TextSearch(strings.ToLower(strings.TrimSpace(s)))
+	for _, known := range TextSearches {
+		if ts == known {
+			return ts, nil
+		}
+	}
+	return "", fmt.Errorf("unknown text search configuration: %q", s)
+}
+
+// TextSearch returns the full-text search configuration of a game
+func (r *PostgresRetriever) TextSearch(ctx context.Context, gameID string) (TextSearch, error) {
+	var ts TextSearch
+	err := r.db.QueryRow(ctx, `SELECT search_config FROM games WHERE id = $1`, gameID).Scan(&ts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return DefaultTextSearch, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("loading text search configuration: %w", err)
+	}
+	return ts, nil
+}
+
+// SetTextSearch changes the full-text search configuration of a game.
+// A database trigger reindexes the documents of the game.
+func (r *PostgresRetriever) SetTextSearch(ctx context.Context, gameID string, ts TextSearch) error {
+	if _, err := ParseTextSearch(string(ts)); err != nil {
+		return err
+	}
+	tag, err := r.db.Exec(ctx, `UPDATE games SET search_config = $1 WHERE id = $2`, string(ts), gameID)
+	if err != nil {
+		return fmt.Errorf("saving text search configuration: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrGameNotFound
+	}
+	return nil
+}
+
+// keywordSearchSQL returns the keyword query of a game. The tsquery is built once from the
+// game configuration, so the GIN index on content_tsv is used.
+func keywordSearchSQL(table string) string {
+	query := `search_query(COALESCE((SELECT search_config FROM games WHERE id = $2::uuid), 'russian'), $1)`
+	return fmt.Sprintf(`
+		SELECT id, content, metadata, COALESCE(location_id::text, ''), embedding, COALESCE(created_at, NOW()),
+		       ts_rank(content_tsv, %[2]s) as rank
+		FROM %[1]s
+		WHERE game_id = $2::uuid
+		  AND ($3::bigint = 0 OR user_id = $3::bigint)
+		  AND content_tsv @@ %[2]s
+		ORDER BY rank DESC
+		LIMIT $4
+	`, table, query)
+}
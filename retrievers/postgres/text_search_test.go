@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTextSearch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected TextSearch
+		wantErr  bool
+	}{
+		{"russian", TextSearchRussian, false},
+		{"English", TextSearchEnglish, false},
+		{" simple ", TextSearchSimple, false},
+		{"multilingual", TextSearchMultilingual, false},
+		{"german", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseTextSearch(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTextSearch(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseTextSearch(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestKeywordSearchSQL(t *testing.T) {
+	query := keywordSearchSQL("context_items")
+	if strings.Contains(query, "'english'") || strings.Contains(query, "to_tsvector") {
+		t.Errorf("expected the generated tsvector column, got %s", query)
+	}
+	if !strings.Contains(query, "content_tsv @@") {
+		t.Errorf("expected a match on content_tsv, got %s", query)
+	}
+	if !strings.Contains(query, "FROM games WHERE id = $2::uuid") {
+		t.Errorf("expected the game configuration, got %s", query)
+	}
+	if !strings.Contains(query, "WHERE game_id = $2::uuid") || strings.Contains(query, "IS NULL") {
+		t.Errorf("expected every search scoped to a game, got %s", query)
+	}
+}
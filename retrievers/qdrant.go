@@ -12,18 +12,28 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
+	"strconv"
 
 	"github.com/rs/zerolog/log"
 	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/internal/uuid"
 )
 
 const (
 	defaultCollection = "game_collection"
 )
 
+// Retriever searches and maintains campaign memory. Documents are identified by their ID:
+// AddDocuments and UpsertDocuments assign one to documents without it, UpsertDocuments replaces
+// documents with a known ID, and deleting unknown IDs is not an error.
 type Retriever interface {
-	GetRelevantDocuments(ctx context.Context, query string) ([]interfaces.Document, error)
+	GetScopedDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.Document, error)
+	GetScoredDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.ScoredDocument, error)
 	AddDocuments(ctx context.Context, docs []interfaces.Document) error
+	UpsertDocuments(ctx context.Context, docs []interfaces.Document) error
+	DeleteDocuments(ctx context.Context, ids []string) error
+	DeleteByFilter(ctx context.Context, filter interfaces.DocumentFilter) error
 }
 
 type QdrantRetriever struct {
@@ -53,7 +63,21 @@ func NewQdrantRetriever(embedder interfaces.VectorEmbeddingProvider) (*QdrantRet
 	}, nil
 }
 
-func (r *QdrantRetriever) GetRelevantDocuments(ctx context.Context, query string) ([]interfaces.Document, error) {
+// GetScopedDocuments searches only points whose payload matches the game and player of the scope
+func (r *QdrantRetriever) GetScopedDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.Document, error) {
+	scored, err := r.GetScoredDocuments(ctx, query, scope)
+	if err != nil {
+		return nil, err
+	}
+	return interfaces.Documents(scored), nil
+}
+
+// GetScoredDocuments works like GetScopedDocuments and reports the similarity score of each point.
+// Qdrant searches vectors only, so keyword ranks are always 0.
+func (r *QdrantRetriever) GetScoredDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.ScoredDocument, error) {
+	if scope.GameID == "" {
+		return nil, interfaces.ErrEmptyScope
+	}
 	embeddings, err := r.embedder.EmbedDocuments(ctx, []string{query})
 	if err != nil {
 		return nil, fmt.Errorf("embed query: %w", err)
@@ -64,8 +88,12 @@ func (r *QdrantRetriever) GetRelevantDocuments(ctx context.Context, query string
 	}
 
 	reqBody := map[string]interface{}{
-		"vector": embeddings[0],
-		"limit":  10,
+		"vector":       embeddings[0],
+		"limit":        10,
+		"with_payload": true,
+	}
+	if filter := scopeFilter(scope); filter != nil {
+		reqBody["filter"] = filter
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -89,6 +117,7 @@ func (r *QdrantRetriever) GetRelevantDocuments(ctx context.Context, query string
 
 	var searchResp struct {
 		Result []struct {
+			ID      json.RawMessage        `json:"id"`
 			Payload map[string]interface{} `json:"payload"`
 			Score   float32                `json:"score"`
 		} `json:"result"`
@@ -98,18 +127,105 @@ func (r *QdrantRetriever) GetRelevantDocuments(ctx context.Context, query string
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
 
-	docs := make([]interfaces.Document, 0, len(searchResp.Result))
-	for _, result := range searchResp.Result {
+	docs := make([]interfaces.ScoredDocument, 0, len(searchResp.Result))
+	for i, result := range searchResp.Result {
 		content, _ := result.Payload["content"].(string)
-		docs = append(docs, interfaces.Document{
-			PageContent: content,
-			Metadata:    result.Payload,
+		docs = append(docs, interfaces.ScoredDocument{
+			Document: interfaces.Document{
+				ID:          documentID(result.ID),
+				PageContent: content,
+				Metadata:    result.Payload,
+			},
+			Score:        float64(result.Score),
+			SemanticRank: i + 1,
+			Source:       "qdrant",
 		})
 	}
 
 	return docs, nil
 }
 
+// scopeFilter builds a Qdrant payload filter, or nil for an empty scope
+func scopeFilter(scope interfaces.SearchScope) map[string]interface{} {
+	var must []map[string]interface{}
+	if scope.GameID != "" {
+		must = append(must, map[string]interface{}{
+			"key":   "game_id",
+			"match": map[string]interface{}{"value": scope.GameID},
+		})
+	}
+	if scope.UserID != 0 {
+		must = append(must, map[string]interface{}{
+			"key":   "user_id",
+			"match": map[string]interface{}{"value": scope.UserID},
+		})
+	}
+	if len(must) == 0 {
+		return nil
+	}
+	return map[string]interface{}{"must": must}
+}
+
+// documentFilter builds a Qdrant payload filter of a non-empty document filter
+func documentFilter(filter interfaces.DocumentFilter) map[string]interface{} {
+	payload := scopeFilter(interfaces.SearchScope{GameID: filter.GameID, UserID: filter.UserID})
+	if payload == nil {
+		payload = map[string]interface{}{"must": []map[string]interface{}{}}
+	}
+	must := payload["must"].([]map[string]interface{})
+
+	keys := make([]string, 0, len(filter.Metadata))
+	for key := range filter.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		must = append(must, metadataCondition(key, filter.Metadata[key]))
+	}
+	payload["must"] = must
+	return payload
+}
+
+// metadataCondition matches a payload value compared as text. Qdrant matches by type,
+// so a value that reads as an integer or a boolean also matches its typed form.
+func metadataCondition(key, value string) map[string]interface{} {
+	condition := func(value interface{}) map[string]interface{} {
+		return map[string]interface{}{"key": key, "match": map[string]interface{}{"value": value}}
+	}
+
+	var typed interface{}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(n, 10) == value {
+		typed = n
+	} else if b, err := strconv.ParseBool(value); err == nil && strconv.FormatBool(b) == value {
+		typed = b
+	}
+	if typed == nil {
+		return condition(value)
+	}
+	return map[string]interface{}{
+		"should": []map[string]interface{}{condition(value), condition(typed)},
+	}
+}
+
+// documentID reads a point ID, which Qdrant returns as a UUID string or an unsigned integer
+func documentID(raw json.RawMessage) string {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+	return string(raw)
+}
+
+// pointID converts a document ID back to a Qdrant point ID, sending integer IDs as JSON numbers
+func pointID(id string) interface{} {
+	if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+		return n
+	}
+	return id
+}
+
+// AddDocuments stores documents as points. Documents without an ID get one.
+// Points with an existing ID are replaced, so adding and upserting are the same in Qdrant.
 func (r *QdrantRetriever) AddDocuments(ctx context.Context, docs []interfaces.Document) error {
 	texts := make([]string, len(docs))
 	for i, doc := range docs {
@@ -123,23 +239,59 @@ func (r *QdrantRetriever) AddDocuments(ctx context.Context, docs []interfaces.Do
 
 	points := make([]map[string]interface{}, len(docs))
 	for i, doc := range docs {
+		if doc.ID == "" {
+			docs[i].ID = uuid.New()
+		}
+		payload := make(map[string]interface{}, len(doc.Metadata)+1)
+		for key, value := range doc.Metadata {
+			payload[key] = value
+		}
+		payload["content"] = doc.PageContent
+
 		points[i] = map[string]interface{}{
+			"id":      pointID(docs[i].ID),
 			"vector":  embeddings[i],
-			"payload": doc.Metadata,
+			"payload": payload,
 		}
 	}
 
-	reqBody := map[string]interface{}{
-		"points": points,
+	return r.send(ctx, "PUT", "points", map[string]interface{}{"points": points})
+}
+
+// UpsertDocuments replaces the documents with the same IDs and adds the others
+func (r *QdrantRetriever) UpsertDocuments(ctx context.Context, docs []interfaces.Document) error {
+	return r.AddDocuments(ctx, docs)
+}
+
+// DeleteDocuments removes the points with the given IDs
+func (r *QdrantRetriever) DeleteDocuments(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	points := make([]interface{}, len(ids))
+	for i, id := range ids {
+		points[i] = pointID(id)
 	}
+	return r.send(ctx, "POST", "points/delete", map[string]interface{}{"points": points})
+}
 
-	jsonBody, err := json.Marshal(reqBody)
+// DeleteByFilter removes the points whose payload matches the filter
+func (r *QdrantRetriever) DeleteByFilter(ctx context.Context, filter interfaces.DocumentFilter) error {
+	if filter.IsEmpty() {
+		return interfaces.ErrEmptyFilter
+	}
+	return r.send(ctx, "POST", "points/delete", map[string]interface{}{"filter": documentFilter(filter)})
+}
+
+// send makes a request to a points endpoint of the collection
+func (r *QdrantRetriever) send(ctx context.Context, method, endpoint string, body interface{}) error {
+	jsonBody, err := json.Marshal(body)
 	if err != nil {
 		return fmt.Errorf("marshal request: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/collections/%s/points", r.qdrantURL, r.collection)
-	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(jsonBody))
+	url := fmt.Sprintf("%s/collections/%s/%s", r.qdrantURL, r.collection, endpoint)
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
@@ -152,5 +304,8 @@ func (r *QdrantRetriever) AddDocuments(ctx context.Context, docs []interfaces.Do
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("qdrant %s %s: status %d", method, endpoint, resp.StatusCode)
+	}
 	return nil
 }
@@ -0,0 +1,85 @@
+package retrievers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+func TestScopeFilter(t *testing.T) {
+	t.Run("empty scope", func(t *testing.T) {
+		if filter := scopeFilter(interfaces.SearchScope{}); filter != nil {
+			t.Errorf("expected no filter, got %v", filter)
+		}
+	})
+
+	t.Run("game only", func(t *testing.T) {
+		filter := scopeFilter(interfaces.SearchScope{GameID: "game-1"})
+		must := filter["must"].([]map[string]interface{})
+		if len(must) != 1 || must[0]["key"] != "game_id" {
+			t.Errorf("unexpected filter: %v", filter)
+		}
+	})
+
+	t.Run("game and player", func(t *testing.T) {
+		filter := scopeFilter(interfaces.SearchScope{GameID: "game-1", UserID: 42})
+		must := filter["must"].([]map[string]interface{})
+		if len(must) != 2 || must[1]["key"] != "user_id" {
+			t.Fatalf("unexpected filter: %v", filter)
+		}
+		match := must[1]["match"].(map[string]interface{})
+		if match["value"] != int64(42) {
+			t.Errorf("expected user id 42, got %v", match["value"])
+		}
+	})
+}
+
+func TestQdrantRetriever_EmptyScope(t *testing.T) {
+	r := &QdrantRetriever{}
+	if _, err := r.GetScoredDocuments(context.Background(), "dragon", interfaces.SearchScope{}); !errors.Is(err, interfaces.ErrEmptyScope) {
+		t.Errorf("expected ErrEmptyScope, got %v", err)
+	}
+}
+
+func TestDocumentFilter(t *testing.T) {
+	filter := documentFilter(interfaces.DocumentFilter{
+		GameID:   "game-1",
+		Metadata: map[string]string{"type": "summary", "chat_id": "7"},
+	})
+	must := filter["must"].([]map[string]interface{})
+	if len(must) != 3 {
+		t.Fatalf("expected game and two metadata conditions, got %v", filter)
+	}
+	if must[0]["key"] != "game_id" || must[2]["key"] != "type" {
+		t.Errorf("unexpected conditions: %v", must)
+	}
+
+	should, ok := must[1]["should"].([]map[string]interface{})
+	if !ok || len(should) != 2 || should[0]["key"] != "chat_id" {
+		t.Fatalf("expected chat_id matched as text and as a number, got %v", must[1])
+	}
+	values := []interface{}{
+		should[0]["match"].(map[string]interface{})["value"],
+		should[1]["match"].(map[string]interface{})["value"],
+	}
+	if values[0] != "7" || values[1] != int64(7) {
+		t.Errorf("expected \"7\" and 7, got %v", values)
+	}
+}
+
+func TestPointID(t *testing.T) {
+	if id := documentID(json.RawMessage(`1000000`)); id != "1000000" {
+		t.Errorf("expected a numeric ID as an integer, got %q", id)
+	}
+	if id := documentID(json.RawMessage(`"5f0c6a3e-8d2b-4c1a-9e7f-2b3c4d5e6f70"`)); id != "5f0c6a3e-8d2b-4c1a-9e7f-2b3c4d5e6f70" {
+		t.Errorf("expected a UUID unquoted, got %q", id)
+	}
+
+	body, _ := json.Marshal([]interface{}{pointID("1000000"), pointID("5f0c6a3e-8d2b-4c1a-9e7f-2b3c4d5e6f70")})
+	if string(body) != `[1000000,"5f0c6a3e-8d2b-4c1a-9e7f-2b3c4d5e6f70"]` {
+		t.Errorf("expected an integer and a string ID, got %s", body)
+	}
+}
@@ -0,0 +1,73 @@
+package rank
+
+import (
+	"math"
+	"time"
+)
+
+// Decay returns the recency factor of an item: 1 when new, 0.5 after one half-life, 0.25 after two.
+// Items from the future count as new.
+func Decay(age, halfLife time.Duration) float64 {
+	if age <= 0 || halfLife <= 0 {
+		return 1
+	}
+	return math.Exp2(-float64(age) / float64(halfLife))
+}
+
+// MMR selects up to limit items with Maximal Marginal Relevance and returns their indexes in
+// selection order. Each step picks the item maximizing
+//
+//	lambda*relevance - (1-lambda)*max similarity to the items already selected
+//
+// where relevance is scaled to [0, 1] and similarity is the cosine of the embeddings.
+// Lambda 1 keeps the relevance order; lower values favour items unlike the ones chosen.
+// Items without an embedding are never considered similar to others.
+func MMR(relevance []float64, embeddings [][]float32, lambda float64, limit int) []int {
+	n := len(relevance)
+	if limit <= 0 || limit > n {
+		limit = n
+	}
+
+	maxRelevance := 0.0
+	for _, r := range relevance {
+		maxRelevance = math.Max(maxRelevance, r)
+	}
+
+	// maxSimilarity[i] is the highest similarity of item i to a selected item
+	maxSimilarity := make([]float64, n)
+	taken := make([]bool, n)
+	selected := make([]int, 0, limit)
+	for len(selected) < limit {
+		best, bestScore := -1, math.Inf(-1)
+		for i := 0; i < n; i++ {
+			if taken[i] {
+				continue
+			}
+			r := relevance[i]
+			if maxRelevance > 0 {
+				r /= maxRelevance
+			}
+			score := lambda*r - (1-lambda)*maxSimilarity[i]
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		taken[best] = true
+		selected = append(selected, best)
+		for i := 0; i < n; i++ {
+			if !taken[i] {
+				maxSimilarity[i] = math.Max(maxSimilarity[i], cosine(embeddings, best, i))
+			}
+		}
+	}
+	return selected
+}
+
+// cosine returns the similarity of two embeddings, 0 when either is missing
+func cosine(embeddings [][]float32, i, j int) float64 {
+	if i >= len(embeddings) || j >= len(embeddings) {
+		return 0
+	}
+	return Cosine(embeddings[i], embeddings[j])
+}
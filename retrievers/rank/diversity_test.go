@@ -0,0 +1,62 @@
+package rank
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecay(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		age  time.Duration
+		want float64
+	}{
+		{0, 1},
+		{-day, 1},
+		{day, 0.5},
+		{2 * day, 0.25},
+	}
+	for _, tt := range tests {
+		if got := Decay(tt.age, day); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("Decay(%v): got %f, want %f", tt.age, got, tt.want)
+		}
+	}
+	if got := Decay(day, 0); got != 1 {
+		t.Errorf("expected no decay without a half-life, got %f", got)
+	}
+}
+
+func TestMMR(t *testing.T) {
+	// a and b are paraphrases, c is about something else
+	relevance := []float64{0.03, 0.029, 0.02}
+	embeddings := [][]float32{{1, 0}, {0.99, 0.1}, {0, 1}}
+
+	t.Run("paraphrases are pushed down", func(t *testing.T) {
+		if got := MMR(relevance, embeddings, 0.5, 3); !reflect.DeepEqual(got, []int{0, 2, 1}) {
+			t.Errorf("expected the distinct item second, got %v", got)
+		}
+	})
+
+	t.Run("lambda 1 keeps the relevance order", func(t *testing.T) {
+		if got := MMR(relevance, embeddings, 1, 2); !reflect.DeepEqual(got, []int{0, 1}) {
+			t.Errorf("expected the relevance order, got %v", got)
+		}
+	})
+
+	t.Run("items without embeddings are not similar", func(t *testing.T) {
+		if got := MMR(relevance, [][]float32{{1, 0}, nil, {0, 1}}, 0.5, 3); !reflect.DeepEqual(got, []int{0, 1, 2}) {
+			t.Errorf("expected the relevance order, got %v", got)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		if got := MMR(relevance, embeddings, 0.5, 10); len(got) != 3 {
+			t.Errorf("expected every item, got %v", got)
+		}
+		if got := MMR(nil, nil, 0.5, 3); len(got) != 0 {
+			t.Errorf("expected no items, got %v", got)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package rank
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// MetadataString reads a metadata value as text. Numbers decoded from JSON are formatted without an exponent.
+func MetadataString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// MetadataInt64 reads a numeric metadata value that may have been decoded from JSON or stored as text
+func MetadataInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case int:
+		return int64(n)
+	case float64:
+		return int64(n)
+	case string:
+		parsed, _ := strconv.ParseInt(n, 10, 64)
+		return parsed
+	default:
+		return 0
+	}
+}
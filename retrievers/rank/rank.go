@@ -0,0 +1,74 @@
+// Package rank combines the ranked result lists of hybrid retrievers and holds
+// the tokenizing, similarity and metadata helpers the retrievers share.
+package rank
+
+import "sort"
+
+const (
+	// DefaultK is the Reciprocal Rank Fusion constant used when none is configured
+	DefaultK = 60
+
+	// DefaultLocationBoost raises the score of documents tagged with the party location by 50%
+	DefaultLocationBoost = 0.5
+)
+
+// Ranking maps document IDs to their 1-based rank in one result list
+type Ranking map[string]int
+
+// Fused is a document of a fused ranking
+type Fused struct {
+	ID    string
+	Score float64
+	Ranks []int // Rank in each input ranking, 0 when the document is absent from it
+}
+
+// RRF fuses rankings with Reciprocal Rank Fusion: score = sum of 1/(k+rank).
+// Results are sorted by descending score, ties by ID so the order is deterministic.
+func RRF(k int, rankings ...Ranking) []Fused {
+	return WeightedRRF(k, nil, rankings...)
+}
+
+// WeightedRRF works like RRF with the contribution of ranking i scaled by weights[i]:
+// score = sum of weight/(k+rank). Rankings without a weight count fully.
+func WeightedRRF(k int, weights []float64, rankings ...Ranking) []Fused {
+	if k <= 0 {
+		k = DefaultK
+	}
+
+	byID := make(map[string]*Fused)
+	for i, ranking := range rankings {
+		for id, r := range ranking {
+			if r <= 0 {
+				continue
+			}
+			f, ok := byID[id]
+			if !ok {
+				f = &Fused{ID: id, Ranks: make([]int, len(rankings))}
+				byID[id] = f
+			}
+			weight := 1.0
+			if i < len(weights) {
+				weight = weights[i]
+			}
+			f.Ranks[i] = r
+			f.Score += weight / float64(k+r)
+		}
+	}
+
+	fused := make([]Fused, 0, len(byID))
+	for _, f := range byID {
+		fused = append(fused, *f)
+	}
+	Sort(fused)
+	return fused
+}
+
+// Sort orders fused results by descending score, ties by ID
+func Sort(fused []Fused) {
+	sort.Slice(fused, func(i, j int) bool {
+		if fused[i].Score != fused[j].Score {
+			return fused[i].Score > fused[j].Score
+		}
+		return fused[i].ID < fused[j].ID
+	})
+}
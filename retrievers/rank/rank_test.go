@@ -0,0 +1,60 @@
+package rank
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRRF(t *testing.T) {
+	t.Run("documents in both lists rank first", func(t *testing.T) {
+		semantic := Ranking{"a": 1, "b": 2}
+		keyword := Ranking{"b": 1, "c": 2}
+
+		fused := RRF(60, semantic, keyword)
+		if len(fused) != 3 {
+			t.Fatalf("expected 3 results, got %d", len(fused))
+		}
+		if fused[0].ID != "b" {
+			t.Errorf("expected b first, got %+v", fused)
+		}
+		want := 1.0/62.0 + 1.0/61.0
+		if math.Abs(fused[0].Score-want) > 1e-12 {
+			t.Errorf("score: got %f, want %f", fused[0].Score, want)
+		}
+		if fused[0].Ranks[0] != 2 || fused[0].Ranks[1] != 1 {
+			t.Errorf("unexpected ranks %v", fused[0].Ranks)
+		}
+	})
+
+	t.Run("ties are ordered by ID", func(t *testing.T) {
+		fused := RRF(0, Ranking{"z": 1}, Ranking{"a": 1})
+		if fused[0].ID != "a" || fused[1].ID != "z" {
+			t.Errorf("expected deterministic order, got %+v", fused)
+		}
+		if fused[0].Score != 1.0/float64(DefaultK+1) {
+			t.Errorf("expected the default k, got score %f", fused[0].Score)
+		}
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		if fused := RRF(60); len(fused) != 0 {
+			t.Errorf("expected no results, got %+v", fused)
+		}
+	})
+}
+
+func TestWeightedRRF(t *testing.T) {
+	fused := WeightedRRF(60, []float64{1, 0.5}, Ranking{"a": 1}, Ranking{"b": 1})
+	if fused[0].ID != "a" {
+		t.Fatalf("expected the heavier channel first, got %+v", fused)
+	}
+	if want := 0.5 / 61.0; math.Abs(fused[1].Score-want) > 1e-12 {
+		t.Errorf("score: got %f, want %f", fused[1].Score, want)
+	}
+
+	// Rankings without a weight count fully
+	fused = WeightedRRF(60, []float64{2}, Ranking{"a": 1}, Ranking{"a": 1})
+	if want := 3.0 / 61.0; math.Abs(fused[0].Score-want) > 1e-12 {
+		t.Errorf("score: got %f, want %f", fused[0].Score, want)
+	}
+}
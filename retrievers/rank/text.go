@@ -0,0 +1,68 @@
+package rank
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	// StemLength is the prefix that stands in for a stem, so "трактирщик" matches "трактирщика"
+	StemLength = 5
+
+	// minStemTerm skips short words, which are mostly prepositions and pronouns
+	minStemTerm = 3
+)
+
+// Terms splits text into distinct lowercase words of letters and digits
+func Terms(text string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// Stems returns the distinct StemLength prefixes of the words of text, skipping words shorter than three runes
+func Stems(text string) []string {
+	seen := make(map[string]bool)
+	var stems []string
+	for _, term := range Terms(text) {
+		runes := []rune(term)
+		if len(runes) < minStemTerm {
+			continue
+		}
+		if len(runes) > StemLength {
+			runes = runes[:StemLength]
+		}
+		stem := string(runes)
+		if !seen[stem] {
+			seen[stem] = true
+			stems = append(stems, stem)
+		}
+	}
+	return stems
+}
+
+// Cosine returns the cosine similarity of two embeddings, 0 when either is empty or their sizes differ
+func Cosine(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,66 @@
+package rank
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	got := Terms("Что обещал трактирщик? Трактирщик, 50 золотых!")
+	want := []string{"что", "обещал", "трактирщик", "50", "золотых"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestStems(t *testing.T) {
+	got := Stems("Трактирщика зовут Борин, он и трактирщик")
+	want := []string{"тракт", "зовут", "борин"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCosine(t *testing.T) {
+	if got := Cosine([]float32{1, 0}, []float32{1, 0}); math.Abs(got-1) > 1e-9 {
+		t.Errorf("expected identical vectors to score 1, got %v", got)
+	}
+	if got := Cosine([]float32{1, 0}, []float32{0, 1}); got != 0 {
+		t.Errorf("expected orthogonal vectors to score 0, got %v", got)
+	}
+	if Cosine([]float32{1, 0}, []float32{1}) != 0 || Cosine(nil, nil) != 0 || Cosine([]float32{0, 0}, []float32{1, 0}) != 0 {
+		t.Error("expected mismatched, missing and zero vectors to score 0")
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	int64Tests := []struct {
+		name     string
+		value    interface{}
+		expected int64
+	}{
+		{"int64", int64(42), 42},
+		{"int", 42, 42},
+		{"json number", float64(42), 42},
+		{"string", "42", 42},
+		{"invalid string", "abc", 0},
+		{"nil", nil, 0},
+	}
+	for _, tt := range int64Tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MetadataInt64(tt.value); got != tt.expected {
+				t.Errorf("MetadataInt64(%v): got %d, want %d", tt.value, got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("strings", func(t *testing.T) {
+		cases := map[interface{}]string{nil: "", "summary": "summary", float64(1000000): "1000000", true: "true"}
+		for value, want := range cases {
+			if got := MetadataString(value); got != want {
+				t.Errorf("MetadataString(%v): got %q, want %q", value, got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package rerank
+
+import (
+	"context"
+	"math"
+
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/retrievers/rank"
+)
+
+// LexicalReranker scores candidates by the share of query terms they contain, weighting
+// terms rare among the candidates higher. It needs no network, which suits offline games and CI.
+type LexicalReranker struct{}
+
+// NewLexicalReranker creates a lexical reranker
+func NewLexicalReranker() *LexicalReranker {
+	return &LexicalReranker{}
+}
+
+// Name identifies the reranker in logs
+func (r *LexicalReranker) Name() string {
+	return "lexical"
+}
+
+// Rerank scores candidates by IDF-weighted query term coverage. Without query terms every candidate scores 0.
+func (r *LexicalReranker) Rerank(ctx context.Context, query string, docs []interfaces.ScoredDocument) ([]float64, error) {
+	scores := make([]float64, len(docs))
+	terms := rank.Stems(query)
+	if len(terms) == 0 || len(docs) == 0 {
+		return scores, nil
+	}
+
+	contents := make([]map[string]bool, len(docs))
+	frequency := make(map[string]int, len(terms))
+	for i, doc := range docs {
+		contents[i] = make(map[string]bool)
+		for _, stem := range rank.Stems(doc.PageContent) {
+			contents[i][stem] = true
+		}
+		for _, term := range terms {
+			if contents[i][term] {
+				frequency[term]++
+			}
+		}
+	}
+
+	weights := make(map[string]float64, len(terms))
+	total := 0.0
+	for _, term := range terms {
+		weights[term] = math.Log(1 + float64(len(docs))/float64(1+frequency[term]))
+		total += weights[term]
+	}
+
+	for i := range docs {
+		for _, term := range terms {
+			if contents[i][term] {
+				scores[i] += weights[term]
+			}
+		}
+		scores[i] /= total
+	}
+	return scores, nil
+}
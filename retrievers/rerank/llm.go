@@ -0,0 +1,118 @@
+package rerank
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// DefaultLLMPrompt instructs the model that grades candidates
+const DefaultLLMPrompt = `Ты помогаешь ведущему текстовой ролевой игры выбрать воспоминания из памяти кампании.
+Оцени, насколько каждый фрагмент помогает ответить на реплику игрока: 0 — не относится к ней, 10 — прямо отвечает на неё.
+Для каждого фрагмента выведи отдельную строку вида «номер: оценка». Больше ничего не пиши.`
+
+// LLMConfig contains settings of the LLM reranker
+type LLMConfig struct {
+	Prompt      string
+	MaxChars    int // Characters of each candidate shown to the model
+	MaxTokens   int
+	Temperature float64
+}
+
+// DefaultLLMConfig returns the default LLM reranker settings
+func DefaultLLMConfig() *LLMConfig {
+	return &LLMConfig{
+		Prompt:      DefaultLLMPrompt,
+		MaxChars:    500,
+		MaxTokens:   200,
+		Temperature: 0,
+	}
+}
+
+// LLMReranker asks the inference provider to grade every candidate against the query
+type LLMReranker struct {
+	provider interfaces.InferenceProvider
+	config   *LLMConfig
+}
+
+// NewLLMReranker creates a reranker that grades candidates with a model
+func NewLLMReranker(provider interfaces.InferenceProvider, config *LLMConfig) (*LLMReranker, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("inference provider cannot be nil")
+	}
+	if config == nil {
+		config = DefaultLLMConfig()
+	}
+	return &LLMReranker{provider: provider, config: config}, nil
+}
+
+// Name identifies the reranker in logs
+func (r *LLMReranker) Name() string {
+	return "llm"
+}
+
+// Rerank grades the candidates in a single request. Candidates the model skips score 0.
+func (r *LLMReranker) Rerank(ctx context.Context, query string, docs []interfaces.ScoredDocument) ([]float64, error) {
+	if len(docs) == 0 {
+		return nil, nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Реплика игрока: %s\n\nФрагменты:", query)
+	for i, doc := range docs {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, shorten(doc.PageContent, r.config.MaxChars))
+	}
+
+	response, err := r.provider.GenerateResponse(ctx, []interfaces.Message{
+		{Role: "system", Content: r.config.Prompt},
+		{Role: "user", Content: b.String()},
+	}, r.config.Temperature, r.config.MaxTokens)
+	if err != nil {
+		return nil, fmt.Errorf("grading candidates: %w", err)
+	}
+
+	grades, err := parseGrades(response, len(docs))
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(docs))
+	for i := range scores {
+		scores[i] = grades[i+1] / 10
+	}
+	return scores, nil
+}
+
+var gradeLine = regexp.MustCompile(`(?m)^\D*?(\d+)\.?\s*[:=—–-]\s*(\d+(?:[.,]\d+)?)`)
+
+// parseGrades reads "number: grade" lines. Grades are clamped to 0-10, unknown numbers are ignored.
+func parseGrades(response string, count int) (map[int]float64, error) {
+	grades := make(map[int]float64)
+	for _, match := range gradeLine.FindAllStringSubmatch(response, -1) {
+		number, err := strconv.Atoi(match[1])
+		if err != nil || number < 1 || number > count {
+			continue
+		}
+		grade, err := strconv.ParseFloat(strings.Replace(match[2], ",", ".", 1), 64)
+		if err != nil {
+			continue
+		}
+		grades[number] = min(max(grade, 0), 10)
+	}
+	if len(grades) == 0 {
+		return nil, fmt.Errorf("no grades in response %q", shorten(response, 200))
+	}
+	return grades, nil
+}
+
+func shorten(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if n <= 0 || len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,6 @@
+// Package rerank rescores retrieval candidates after hybrid search.
+//
+// RRF only knows where each channel ranked a document, so nuanced queries such as
+// "what did the innkeeper promise us" often rank the wrong memory first. A reranker looks at
+// the query and the candidates together and scores each of them in [0, 1], higher is better.
+package rerank
@@ -0,0 +1,116 @@
+package rerank
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// MockProvider answers with its reply or fails with err and records the prompt
+type MockProvider struct {
+	reply    string
+	err      error
+	messages []interfaces.Message
+}
+
+func (m *MockProvider) GenerateResponse(ctx context.Context, messages []interfaces.Message, temperature float64, maxTokens int) (string, error) {
+	m.messages = messages
+	return m.reply, m.err
+}
+
+func (m *MockProvider) Name() string {
+	return "mock"
+}
+
+func candidates(contents ...string) []interfaces.ScoredDocument {
+	docs := make([]interfaces.ScoredDocument, len(contents))
+	for i, content := range contents {
+		docs[i] = interfaces.ScoredDocument{Document: interfaces.Document{PageContent: content}}
+	}
+	return docs
+}
+
+func TestParseGrades(t *testing.T) {
+	t.Run("reads grade lines", func(t *testing.T) {
+		grades, err := parseGrades("1: 3\n2 — 9.5\n3. = 7,5\nЗамечание\n7: 10\n4: 42", 4)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := map[int]float64{1: 3, 2: 9.5, 3: 7.5, 4: 10}
+		if len(grades) != len(expected) {
+			t.Fatalf("expected %v, got %v", expected, grades)
+		}
+		for number, grade := range expected {
+			if grades[number] != grade {
+				t.Errorf("candidate %d: expected %v, got %v", number, grade, grades[number])
+			}
+		}
+	})
+
+	t.Run("no grades", func(t *testing.T) {
+		if _, err := parseGrades("Не могу оценить", 3); err == nil {
+			t.Error("expected error without grades")
+		}
+	})
+}
+
+func TestLLMReranker(t *testing.T) {
+	if _, err := NewLLMReranker(nil, nil); err == nil {
+		t.Error("expected error for nil provider")
+	}
+
+	t.Run("scores by grades", func(t *testing.T) {
+		provider := &MockProvider{reply: "1: 2\n2: 9"}
+		r, _ := NewLLMReranker(provider, nil)
+
+		scores, err := r.Rerank(context.Background(), "что обещал трактирщик?", candidates(
+			"Мэр должен отряду 50 золотых",
+			"Трактирщик обещал бесплатный ночлег",
+			"В лесу тихо",
+		))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(scores) != 3 || scores[0] != 0.2 || scores[1] != 0.9 || scores[2] != 0 {
+			t.Errorf("expected [0.2 0.9 0], got %v", scores)
+		}
+		prompt := provider.messages[len(provider.messages)-1].Content
+		if !strings.Contains(prompt, "что обещал трактирщик?") || !strings.Contains(prompt, "2. Трактирщик обещал") {
+			t.Errorf("expected query and numbered candidates in prompt, got %q", prompt)
+		}
+	})
+
+	t.Run("provider failure", func(t *testing.T) {
+		r, _ := NewLLMReranker(&MockProvider{err: errors.New("timeout")}, nil)
+		if _, err := r.Rerank(context.Background(), "q", candidates("a")); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestLexicalReranker(t *testing.T) {
+	r := NewLexicalReranker()
+
+	scores, err := r.Rerank(context.Background(), "Что нам обещал трактирщик?", candidates(
+		"Мэр обещал награду за волков",
+		"Трактирщика зовут Борин, он обещал нам комнату",
+		"В лесу тихо",
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !(scores[1] > scores[0] && scores[0] > scores[2]) {
+		t.Errorf("expected the innkeeper first and the forest last, got %v", scores)
+	}
+	if scores[1] > 1 || scores[2] != 0 {
+		t.Errorf("expected scores within [0, 1], got %v", scores)
+	}
+
+	scores, _ = r.Rerank(context.Background(), "и в", candidates("a", "b"))
+	if len(scores) != 2 || scores[0] != 0 || scores[1] != 0 {
+		t.Errorf("expected zero scores without query terms, got %v", scores)
+	}
+}
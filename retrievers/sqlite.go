@@ -3,27 +3,69 @@ package retrievers
 import (
 	"context"
 	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/rs/zerolog/log"
 	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/internal/uuid"
+	"go-llm-rpggamemaster/retrievers/rank"
 )
 
+const sqliteSearchLimit = 10
+
+// sqliteSchema mirrors the context_items table of the PostgreSQL schema.
+// Embeddings are stored as little-endian float32 blobs.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS context_items (
+    id TEXT PRIMARY KEY,
+    game_id TEXT NOT NULL DEFAULT '',
+    user_id INTEGER NOT NULL DEFAULT 0,
+    character_id TEXT,
+    location_id TEXT,
+    quest_id TEXT,
+    content TEXT NOT NULL,
+    embedding BLOB,
+    metadata TEXT,
+    created_at TEXT DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_context_tenant ON context_items(game_id, user_id);
+`
+
+// sqliteFTSSchema needs the driver to be built with the sqlite_fts5 tag
+const sqliteFTSSchema = `CREATE VIRTUAL TABLE IF NOT EXISTS context_items_fts USING fts5(item_id UNINDEXED, content)`
+
+// SQLiteRetriever is an embedded retriever for small groups and CI.
+// Vectors are searched by brute-force cosine similarity, keywords with FTS5 when
+// available and by term matching otherwise. Both rankings are fused with RRF.
 type SQLiteRetriever struct {
 	db       *sql.DB
 	embedder interfaces.VectorEmbeddingProvider
+
+	schemaMu    sync.Mutex
+	schemaReady bool
+	fts         bool
 }
 
 func NewSQLiteRetriever(embedder interfaces.VectorEmbeddingProvider) (*SQLiteRetriever, error) {
 	return NewSQLiteRetrieverWithPath(embedder, "base.db")
 }
 
+// NewSQLiteRetrieverWithPath opens the database. Tables are created on first use.
 func NewSQLiteRetrieverWithPath(embedder interfaces.VectorEmbeddingProvider, dbPath string) (*SQLiteRetriever, error) {
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		log.Error().Err(err).Str("dbPath", dbPath).Msg("failed to open SQLite database")
 		return nil, err
 	}
+	// SQLite allows a single writer, one connection avoids "database is locked" errors
+	db.SetMaxOpenConns(1)
 
 	return &SQLiteRetriever{
 		db:       db,
@@ -31,10 +73,501 @@ func NewSQLiteRetrieverWithPath(embedder interfaces.VectorEmbeddingProvider, dbP
 	}, nil
 }
 
-func (r *SQLiteRetriever) GetRelevantDocuments(ctx context.Context, query string) ([]interfaces.Document, error) {
-	return nil, nil
+// Close closes the database
+func (r *SQLiteRetriever) Close() {
+	if err := r.db.Close(); err != nil {
+		log.Error().Err(err).Msg("failed to close SQLite database")
+	}
+}
+
+// GetScopedDocuments runs a hybrid search over the documents of the scope
+func (r *SQLiteRetriever) GetScopedDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.Document, error) {
+	scored, err := r.GetScoredDocuments(ctx, query, scope)
+	if err != nil {
+		return nil, err
+	}
+	return interfaces.Documents(scored), nil
+}
+
+// GetScoredDocuments works like GetScopedDocuments and reports the RRF score and ranks of each document
+func (r *SQLiteRetriever) GetScoredDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.ScoredDocument, error) {
+	if scope.GameID == "" {
+		return nil, interfaces.ErrEmptyScope
+	}
+	if err := r.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	embedding, err := r.embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := r.candidates(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	semantic := semanticRanking(candidates, embedding[0], sqliteSearchLimit*2)
+	keyword, err := r.keywordRanking(ctx, query, scope, candidates, sqliteSearchLimit*2)
+	if err != nil {
+		return nil, err
+	}
+
+	fused := rank.RRF(rank.DefaultK, semantic, keyword)
+	if scope.LocationID != "" {
+		for i := range fused {
+			if candidates[fused[i].ID].locationID == scope.LocationID {
+				fused[i].Score *= 1 + rank.DefaultLocationBoost
+			}
+		}
+		rank.Sort(fused)
+	}
+	if len(fused) > sqliteSearchLimit {
+		fused = fused[:sqliteSearchLimit]
+	}
+
+	docs := make([]interfaces.ScoredDocument, 0, len(fused))
+	for _, f := range fused {
+		if c, ok := candidates[f.ID]; ok {
+			docs = append(docs, interfaces.ScoredDocument{
+				Document:     interfaces.Document{ID: f.ID, PageContent: c.content, Metadata: c.metadata},
+				Score:        f.Score,
+				SemanticRank: f.Ranks[0],
+				KeywordRank:  f.Ranks[1],
+				Source:       "sqlite",
+			})
+		}
+	}
+
+	log.Debug().
+		Str("game_id", scope.GameID).
+		Int64("user_id", scope.UserID).
+		Int("result_count", len(docs)).
+		Msg("Documents retrieved from SQLite")
+
+	return docs, nil
 }
 
+// AddDocuments embeds and stores documents. Metadata keys matching context_items columns fill those columns.
+// Documents without an ID get one.
 func (r *SQLiteRetriever) AddDocuments(ctx context.Context, docs []interfaces.Document) error {
+	return r.write(ctx, docs, false)
+}
+
+// UpsertDocuments replaces the documents with the same IDs and adds the others
+func (r *SQLiteRetriever) UpsertDocuments(ctx context.Context, docs []interfaces.Document) error {
+	return r.write(ctx, docs, true)
+}
+
+func (r *SQLiteRetriever) write(ctx context.Context, docs []interfaces.Document, upsert bool) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	if err := r.ensureSchema(ctx); err != nil {
+		return err
+	}
+
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.PageContent
+	}
+	embeddings, err := r.embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+	if len(embeddings) != len(docs) {
+		return fmt.Errorf("expected %d embeddings, got %d", len(docs), len(embeddings))
+	}
+
+	insertSQL := `
+		INSERT INTO context_items (id, game_id, user_id, character_id, location_id, quest_id, content, embedding, metadata)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	if upsert {
+		insertSQL += `
+		ON CONFLICT (id) DO UPDATE SET
+			game_id = excluded.game_id,
+			user_id = excluded.user_id,
+			character_id = excluded.character_id,
+			location_id = excluded.location_id,
+			quest_id = excluded.quest_id,
+			content = excluded.content,
+			embedding = excluded.embedding,
+			metadata = excluded.metadata`
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for i, doc := range docs {
+		metadata, err := json.Marshal(doc.Metadata)
+		if err != nil {
+			return fmt.Errorf("encoding metadata: %w", err)
+		}
+
+		if doc.ID == "" {
+			docs[i].ID = uuid.New()
+		}
+		id := docs[i].ID
+		_, err = tx.ExecContext(ctx, insertSQL, id,
+			rank.MetadataString(doc.Metadata["game_id"]),
+			rank.MetadataInt64(doc.Metadata["user_id"]),
+			nullString(rank.MetadataString(doc.Metadata["character_id"])),
+			nullString(rank.MetadataString(doc.Metadata["location_id"])),
+			nullString(rank.MetadataString(doc.Metadata["quest_id"])),
+			doc.PageContent,
+			encodeVector(embeddings[i]),
+			string(metadata),
+		)
+		if err != nil {
+			return fmt.Errorf("inserting document: %w", err)
+		}
+
+		if r.fts {
+			if upsert {
+				if _, err := tx.ExecContext(ctx, `DELETE FROM context_items_fts WHERE item_id = ?`, id); err != nil {
+					return fmt.Errorf("unindexing document: %w", err)
+				}
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO context_items_fts (item_id, content) VALUES (?, ?)`, id, doc.PageContent); err != nil {
+				return fmt.Errorf("indexing document: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing documents: %w", err)
+	}
+
+	log.Debug().Int("document_count", len(docs)).Bool("upsert", upsert).Msg("Documents added to SQLite")
+	return nil
+}
+
+// DeleteDocuments removes the documents with the given IDs
+func (r *SQLiteRetriever) DeleteDocuments(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := r.ensureSchema(ctx); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := r.deleteIDs(ctx, tx, ids); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing deletion: %w", err)
+	}
+
+	log.Debug().Int("document_count", len(ids)).Msg("Documents deleted from SQLite")
+	return nil
+}
+
+// DeleteByFilter removes the documents matching the filter
+func (r *SQLiteRetriever) DeleteByFilter(ctx context.Context, filter interfaces.DocumentFilter) error {
+	if filter.IsEmpty() {
+		return interfaces.ErrEmptyFilter
+	}
+	if err := r.ensureSchema(ctx); err != nil {
+		return err
+	}
+
+	query, args := sqliteFilterSQL(filter)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("selecting documents: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("scanning document: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("selecting documents: %w", err)
+	}
+
+	if err := r.deleteIDs(ctx, tx, ids); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing deletion: %w", err)
+	}
+
+	log.Debug().
+		Str("game_id", filter.GameID).
+		Int64("user_id", filter.UserID).
+		Int("document_count", len(ids)).
+		Msg("Documents deleted from SQLite by filter")
+	return nil
+}
+
+func (r *SQLiteRetriever) deleteIDs(ctx context.Context, tx *sql.Tx, ids []string) error {
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM context_items WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("deleting document: %w", err)
+		}
+		if r.fts {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM context_items_fts WHERE item_id = ?`, id); err != nil {
+				return fmt.Errorf("unindexing document: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// sqliteFilterSQL selects the IDs of the documents matching a non-empty filter.
+// Metadata values are compared as text, like the other retrievers do.
+func sqliteFilterSQL(filter interfaces.DocumentFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if filter.GameID != "" {
+		conditions = append(conditions, "game_id = ?")
+		args = append(args, filter.GameID)
+	}
+	if filter.UserID != 0 {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	keys := make([]string, 0, len(filter.Metadata))
+	for key := range filter.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		conditions = append(conditions, "CAST(json_extract(metadata, ?) AS TEXT) = ?")
+		args = append(args, `$."`+key+`"`, filter.Metadata[key])
+	}
+	return "SELECT id FROM context_items WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// ensureSchema creates the tables on first use, so opening a database has no side effects
+func (r *SQLiteRetriever) ensureSchema(ctx context.Context) error {
+	r.schemaMu.Lock()
+	defer r.schemaMu.Unlock()
+
+	if r.schemaReady {
+		return nil
+	}
+	if _, err := r.db.ExecContext(ctx, sqliteSchema); err != nil {
+		return fmt.Errorf("creating schema: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, sqliteFTSSchema); err != nil {
+		log.Warn().Err(err).Msg("FTS5 is not available, build with -tags sqlite_fts5 for ranked keyword search")
+	} else {
+		// Documents added by a build without FTS5 are indexed now
+		_, err := r.db.ExecContext(ctx, `
+			INSERT INTO context_items_fts (item_id, content)
+			SELECT id, content FROM context_items
+			WHERE id NOT IN (SELECT item_id FROM context_items_fts)
+		`)
+		if err != nil {
+			return fmt.Errorf("indexing existing documents: %w", err)
+		}
+		r.fts = true
+	}
+
+	r.schemaReady = true
 	return nil
 }
+
+func (r *SQLiteRetriever) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if r.embedder == nil {
+		return nil, fmt.Errorf("embedding provider is not configured")
+	}
+	embeddings, err := r.embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embed documents: %w", err)
+	}
+	if len(embeddings) == 0 || len(embeddings[0]) == 0 {
+		return nil, fmt.Errorf("empty embedding returned")
+	}
+	return embeddings, nil
+}
+
+type sqliteCandidate struct {
+	content    string
+	metadata   map[string]interface{}
+	embedding  []float32
+	locationID string
+}
+
+// candidates loads every document of the scope
+func (r *SQLiteRetriever) candidates(ctx context.Context, scope interfaces.SearchScope) (map[string]sqliteCandidate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, content, COALESCE(metadata, ''), embedding, COALESCE(location_id, '')
+		FROM context_items
+		WHERE (? = '' OR game_id = ?)
+		  AND (? = 0 OR user_id = ?)
+	`, scope.GameID, scope.GameID, scope.UserID, scope.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("loading documents: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := make(map[string]sqliteCandidate)
+	for rows.Next() {
+		var id, metadata string
+		var embedding []byte
+		var c sqliteCandidate
+		if err := rows.Scan(&id, &c.content, &metadata, &embedding, &c.locationID); err != nil {
+			return nil, fmt.Errorf("scanning document: %w", err)
+		}
+		if metadata != "" {
+			if err := json.Unmarshal([]byte(metadata), &c.metadata); err != nil {
+				return nil, fmt.Errorf("decoding metadata: %w", err)
+			}
+		}
+		c.embedding = decodeVector(embedding)
+		candidates[id] = c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("loading documents: %w", err)
+	}
+	return candidates, nil
+}
+
+// keywordRanking ranks documents with FTS5 BM25, or by the number of matching query terms without FTS5
+func (r *SQLiteRetriever) keywordRanking(ctx context.Context, query string, scope interfaces.SearchScope, candidates map[string]sqliteCandidate, limit int) (rank.Ranking, error) {
+	terms := rank.Terms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	if !r.fts {
+		return termRanking(candidates, terms, limit), nil
+	}
+
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		// Terms hold only letters and digits, so quoting cannot break the FTS5 syntax
+		quoted[i] = `"` + term + `"`
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT f.item_id
+		FROM context_items_fts f
+		JOIN context_items c ON c.id = f.item_id
+		WHERE context_items_fts MATCH ?
+		  AND (? = '' OR c.game_id = ?)
+		  AND (? = 0 OR c.user_id = ?)
+		ORDER BY bm25(context_items_fts)
+		LIMIT ?
+	`, strings.Join(quoted, " OR "), scope.GameID, scope.GameID, scope.UserID, scope.UserID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("keyword search query: %w", err)
+	}
+	defer rows.Close()
+
+	ranking := make(rank.Ranking)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning keyword result: %w", err)
+		}
+		ranking[id] = len(ranking) + 1
+	}
+	return ranking, rows.Err()
+}
+
+// semanticRanking ranks documents by cosine similarity to the query embedding
+func semanticRanking(candidates map[string]sqliteCandidate, query []float32, limit int) rank.Ranking {
+	type scored struct {
+		id    string
+		score float64
+	}
+	var results []scored
+	for id, c := range candidates {
+		if len(c.embedding) != len(query) {
+			continue
+		}
+		results = append(results, scored{id, rank.Cosine(query, c.embedding)})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].id < results[j].id
+	})
+
+	ranking := make(rank.Ranking)
+	for i := 0; i < len(results) && i < limit; i++ {
+		ranking[results[i].id] = i + 1
+	}
+	return ranking
+}
+
+// termRanking ranks documents by the number of distinct query terms they contain
+func termRanking(candidates map[string]sqliteCandidate, terms []string, limit int) rank.Ranking {
+	type scored struct {
+		id      string
+		matches int
+	}
+	var results []scored
+	for id, c := range candidates {
+		content := strings.ToLower(c.content)
+		matches := 0
+		for _, term := range terms {
+			if strings.Contains(content, term) {
+				matches++
+			}
+		}
+		if matches > 0 {
+			results = append(results, scored{id, matches})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].matches != results[j].matches {
+			return results[i].matches > results[j].matches
+		}
+		return results[i].id < results[j].id
+	})
+
+	ranking := make(rank.Ranking)
+	for i := 0; i < len(results) && i < limit; i++ {
+		ranking[results[i].id] = i + 1
+	}
+	return ranking
+}
+
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -1,9 +1,15 @@
 package retrievers
 
 import (
+	"context"
+	"errors"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"go-llm-rpggamemaster/interfaces"
 )
 
 func TestSQLiteRetrieverConnectionWithoutTables(t *testing.T) {
@@ -52,3 +58,165 @@ func TestSQLiteRetrieverConnectionWithoutTables(t *testing.T) {
 
 	log.Printf("Confirmed no tables exist in the database")
 }
+
+// MockEmbedder embeds texts as bag-of-words vectors over a fixed vocabulary
+type MockEmbedder struct {
+	vocabulary []string
+}
+
+func (m *MockEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, len(m.vocabulary))
+		for j, word := range m.vocabulary {
+			if strings.Contains(strings.ToLower(text), word) {
+				vector[j] = 1
+			}
+		}
+		embeddings[i] = vector
+	}
+	return embeddings, nil
+}
+
+func (m *MockEmbedder) Name() string {
+	return "mock"
+}
+
+func TestSQLiteRetriever_Search(t *testing.T) {
+	ctx := context.Background()
+	embedder := &MockEmbedder{vocabulary: []string{"dragon", "tavern", "forest", "gold"}}
+	retriever, err := NewSQLiteRetrieverWithPath(embedder, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating retriever: %v", err)
+	}
+	defer retriever.Close()
+
+	docs := []interfaces.Document{
+		{PageContent: "A dragon sleeps on a pile of gold", Metadata: map[string]interface{}{"game_id": "g1", "user_id": int64(1)}},
+		{PageContent: "The tavern keeper fears the dragon", Metadata: map[string]interface{}{"game_id": "g1", "user_id": int64(2), "location_id": "tavern"}},
+		{PageContent: "The forest is quiet", Metadata: map[string]interface{}{"game_id": "g1", "user_id": int64(1)}},
+		{PageContent: "Another dragon in another game", Metadata: map[string]interface{}{"game_id": "g2", "user_id": float64(1)}},
+	}
+	if err := retriever.AddDocuments(ctx, docs); err != nil {
+		t.Fatalf("adding documents: %v", err)
+	}
+
+	t.Run("results are scoped to the game", func(t *testing.T) {
+		found, err := retriever.GetScopedDocuments(ctx, "dragon gold", interfaces.SearchScope{GameID: "g1"})
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if len(found) == 0 || found[0].PageContent != docs[0].PageContent {
+			t.Fatalf("expected the dragon hoard first, got %+v", found)
+		}
+		for _, doc := range found {
+			if doc.Metadata["game_id"] != "g1" {
+				t.Errorf("document from another game: %+v", doc)
+			}
+		}
+	})
+
+	t.Run("results are scoped to the player", func(t *testing.T) {
+		found, _ := retriever.GetScopedDocuments(ctx, "dragon", interfaces.SearchScope{GameID: "g1", UserID: 2})
+		if len(found) != 1 || found[0].PageContent != docs[1].PageContent {
+			t.Errorf("expected only the second player's document, got %+v", found)
+		}
+	})
+
+	t.Run("party location is boosted", func(t *testing.T) {
+		found, _ := retriever.GetScopedDocuments(ctx, "dragon", interfaces.SearchScope{GameID: "g1", LocationID: "tavern"})
+		if len(found) == 0 || found[0].PageContent != docs[1].PageContent {
+			t.Errorf("expected the tavern document first, got %+v", found)
+		}
+	})
+
+	t.Run("empty scope is rejected", func(t *testing.T) {
+		if _, err := retriever.GetScopedDocuments(ctx, "dragon", interfaces.SearchScope{}); !errors.Is(err, interfaces.ErrEmptyScope) {
+			t.Errorf("expected ErrEmptyScope, got %v", err)
+		}
+	})
+}
+
+func TestSQLiteRetriever_UpsertAndDelete(t *testing.T) {
+	ctx := context.Background()
+	embedder := &MockEmbedder{vocabulary: []string{"dragon", "tavern", "forest", "gold"}}
+	retriever, err := NewSQLiteRetrieverWithPath(embedder, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating retriever: %v", err)
+	}
+	defer retriever.Close()
+
+	docs := []interfaces.Document{
+		{PageContent: "A dragon sleeps on a pile of gold", Metadata: map[string]interface{}{"game_id": "g1", "user_id": int64(1)}},
+		{PageContent: "The tavern keeper fears the dragon", Metadata: map[string]interface{}{"game_id": "g1", "user_id": int64(2), "type": "summary"}},
+		{PageContent: "Another dragon in another game", Metadata: map[string]interface{}{"game_id": "g2", "user_id": int64(1)}},
+	}
+	if err := retriever.AddDocuments(ctx, docs); err != nil {
+		t.Fatalf("adding documents: %v", err)
+	}
+	for _, doc := range docs {
+		if doc.ID == "" {
+			t.Fatalf("expected IDs assigned on add, got %+v", docs)
+		}
+	}
+
+	t.Run("results carry their IDs", func(t *testing.T) {
+		found, _ := retriever.GetScopedDocuments(ctx, "gold", interfaces.SearchScope{GameID: "g1"})
+		if len(found) == 0 || found[0].ID != docs[0].ID {
+			t.Errorf("expected the hoard with ID %s, got %+v", docs[0].ID, found)
+		}
+	})
+
+	t.Run("upsert replaces a document", func(t *testing.T) {
+		retcon := interfaces.Document{ID: docs[0].ID, PageContent: "The forest hides the gold", Metadata: docs[0].Metadata}
+		if err := retriever.UpsertDocuments(ctx, []interfaces.Document{retcon}); err != nil {
+			t.Fatalf("upserting: %v", err)
+		}
+		found, _ := retriever.GetScopedDocuments(ctx, "gold", interfaces.SearchScope{GameID: "g1"})
+		if len(found) == 0 || found[0].ID != docs[0].ID || found[0].PageContent != retcon.PageContent {
+			t.Errorf("expected the replaced document, got %+v", found)
+		}
+		all, _ := retriever.GetScopedDocuments(ctx, "dragon", interfaces.SearchScope{GameID: "g1"})
+		if len(all) != 2 {
+			t.Errorf("expected upsert not to add a document, got %d", len(all))
+		}
+	})
+
+	t.Run("delete by ID", func(t *testing.T) {
+		if err := retriever.DeleteDocuments(ctx, []string{docs[0].ID, "unknown"}); err != nil {
+			t.Fatalf("deleting: %v", err)
+		}
+		found, _ := retriever.GetScopedDocuments(ctx, "gold forest", interfaces.SearchScope{GameID: "g1"})
+		for _, doc := range found {
+			if doc.ID == docs[0].ID {
+				t.Errorf("expected the document deleted, got %+v", found)
+			}
+		}
+	})
+
+	t.Run("delete by filter", func(t *testing.T) {
+		if err := retriever.DeleteByFilter(ctx, interfaces.DocumentFilter{}); err != interfaces.ErrEmptyFilter {
+			t.Errorf("expected an empty filter refused, got %v", err)
+		}
+		if err := retriever.DeleteByFilter(ctx, interfaces.DocumentFilter{GameID: "g1", Metadata: map[string]string{"type": "summary"}}); err != nil {
+			t.Fatalf("deleting: %v", err)
+		}
+		if found, _ := retriever.GetScopedDocuments(ctx, "dragon", interfaces.SearchScope{GameID: "g1"}); len(found) != 0 {
+			t.Errorf("expected the summary deleted, got %+v", found)
+		}
+		found, _ := retriever.GetScopedDocuments(ctx, "dragon", interfaces.SearchScope{GameID: "g2"})
+		if len(found) != 1 || found[0].ID != docs[2].ID {
+			t.Errorf("expected only the other game left, got %+v", found)
+		}
+	})
+}
+
+func TestVectorEncoding(t *testing.T) {
+	v := []float32{0, 1.5, -2.25, 3e-8}
+	decoded := decodeVector(encodeVector(v))
+	for i := range v {
+		if decoded[i] != v[i] {
+			t.Fatalf("expected %v, got %v", v, decoded)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+const contextHeader = "Сведения из памяти кампании (лор, NPC, прошлые события). Используй их, если они уместны, и не противоречь им:"
+
+// DocumentRetriever finds campaign documents relevant to a player's message
+type DocumentRetriever interface {
+	GetScopedDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.Document, error)
+}
+
+// ScoredRetriever is a DocumentRetriever that reports the scores that ranked each document
+type ScoredRetriever interface {
+	GetScoredDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.ScoredDocument, error)
+}
+
+// AssemblerConfig contains limits for the retrieved context block
+type AssemblerConfig struct {
+	MaxChars     int // Character budget of the whole block, header included
+	MaxDocuments int
+	Timeout      time.Duration
+
+	// MinScores drops documents scored lower than the threshold of their score source: the Source
+	// of a ScoredRetriever, such as "postgres" or "qdrant", or RerankSource once a reranker scored them.
+	// Scales differ by source, so a source without a threshold keeps every document.
+	MinScores map[string]float64
+
+	// RerankTopN candidates are reranked when a reranker is set, the rest are dropped
+	RerankTopN    int
+	RerankTimeout time.Duration
+}
+
+// SkipReason tells why a retrieved document was left out of the prompt
+type SkipReason string
+
+const (
+	SkipBelowThreshold SkipReason = "below_threshold"
+	SkipBudget         SkipReason = "budget"
+	SkipEmpty          SkipReason = "empty"
+)
+
+// RetrievedDocument is a retrieved document and whether it made it into the prompt
+type RetrievedDocument struct {
+	interfaces.ScoredDocument
+	Used          bool
+	Skipped       SkipReason // Empty for used documents
+	RetrievalRank int        // 1-based position before reranking, 0 when not reranked
+	Text          string     // Text included in the prompt, truncated to the MaxChars budget
+}
+
+// Retrieval records what campaign memory returned for a turn
+type Retrieval struct {
+	Query     string
+	Scope     interfaces.SearchScope
+	At        time.Time
+	Duration  time.Duration
+	Scored    bool // Whether the retriever or the reranker reported scores
+	Documents []RetrievedDocument
+	Err       error
+
+	Reranker  string // Reranker that ordered the documents, empty when none did
+	RerankErr error  // Reranking failure, the retrieval order is kept then
+}
+
+// Used returns the documents included in the prompt
+func (r *Retrieval) Used() []interfaces.Document {
+	var used []interfaces.Document
+	for _, doc := range r.Documents {
+		if doc.Used {
+			used = append(used, doc.Document)
+		}
+	}
+	return used
+}
+
+// ScoreSource names the scale of a document score: RerankSource for reranked retrievals, the retriever source otherwise
+func (r *Retrieval) ScoreSource(doc RetrievedDocument) string {
+	if r.Reranker != "" {
+		return RerankSource
+	}
+	return doc.Source
+}
+
+// Texts returns the texts of the documents as included in the prompt, truncated to the budget
+func (r *Retrieval) Texts() []string {
+	var texts []string
+	for _, doc := range r.Documents {
+		if doc.Used {
+			texts = append(texts, doc.Text)
+		}
+	}
+	return texts
+}
+
+// DefaultAssemblerConfig returns the default context limits
+func DefaultAssemblerConfig() *AssemblerConfig {
+	return &AssemblerConfig{
+		MaxChars:      4000,
+		MaxDocuments:  8,
+		Timeout:       5 * time.Second,
+		RerankTopN:    10,
+		RerankTimeout: 10 * time.Second,
+	}
+}
+
+// Assembler turns retriever results into a bounded context message for the game master
+type Assembler struct {
+	retriever DocumentRetriever
+	reranker  Reranker
+	config    *AssemblerConfig
+}
+
+// NewAssembler creates a context assembler
+func NewAssembler(retriever DocumentRetriever, config *AssemblerConfig) (*Assembler, error) {
+	if retriever == nil {
+		return nil, fmt.Errorf("retriever cannot be nil")
+	}
+	if config == nil {
+		config = DefaultAssemblerConfig()
+	}
+	return &Assembler{
+		retriever: retriever,
+		config:    config,
+	}, nil
+}
+
+// Assemble queries the retriever within the scope and formats the results into a system message.
+// It returns nil when nothing relevant was found.
+func (a *Assembler) Assemble(ctx context.Context, scope interfaces.SearchScope, query string) (*interfaces.Message, []interfaces.Document, error) {
+	msg, retrieval := a.Retrieve(ctx, scope, query)
+	if retrieval.Err != nil {
+		return nil, nil, retrieval.Err
+	}
+	if msg == nil {
+		return nil, nil, nil
+	}
+	return msg, retrieval.Used(), nil
+}
+
+// Retrieve works like Assemble and records every retrieved document with its scores and
+// whether it was used. The message is nil when nothing was used or retrieval failed.
+func (a *Assembler) Retrieve(ctx context.Context, scope interfaces.SearchScope, query string) (*interfaces.Message, *Retrieval) {
+	start := time.Now()
+	retrieval := &Retrieval{Query: query, Scope: scope, At: start}
+
+	docs, scored, err := a.retrieve(ctx, scope, query)
+	retrieval.Duration = time.Since(start)
+	if err != nil {
+		retrieval.Err = fmt.Errorf("retrieving documents: %w", err)
+		return nil, retrieval
+	}
+	retrieval.Scored = scored
+
+	retrieval.Documents = make([]RetrievedDocument, len(docs))
+	for i, doc := range docs {
+		retrieval.Documents[i] = RetrievedDocument{ScoredDocument: doc}
+	}
+	a.rerank(ctx, retrieval)
+	for i := range retrieval.Documents {
+		doc := &retrieval.Documents[i]
+		if !retrieval.Scored {
+			break
+		}
+		if threshold := a.config.MinScores[retrieval.ScoreSource(*doc)]; threshold > 0 && doc.Score < threshold {
+			doc.Skipped = SkipBelowThreshold
+		}
+	}
+
+	content, used := a.format(retrieval.Documents)
+	if used == 0 {
+		return nil, retrieval
+	}
+
+	event := log.Debug().
+		Str("game_id", scope.GameID).
+		Dur("retrieval_duration", retrieval.Duration).
+		Int("retrieved_count", len(retrieval.Documents)).
+		Int("used_count", used).
+		Int("context_chars", utf8.RuneCountInString(content))
+	for i, doc := range retrieval.Used() {
+		event = event.Str(fmt.Sprintf("doc_%d", i), documentLabel(doc))
+	}
+	event.Msg("Context assembled")
+
+	return &interfaces.Message{Role: "system", Content: content}, retrieval
+}
+
+// retrieve queries scores when the retriever reports them and plain documents otherwise
+func (a *Assembler) retrieve(ctx context.Context, scope interfaces.SearchScope, query string) ([]interfaces.ScoredDocument, bool, error) {
+	if a.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.config.Timeout)
+		defer cancel()
+	}
+
+	if scorer, ok := a.retriever.(ScoredRetriever); ok {
+		docs, err := scorer.GetScoredDocuments(ctx, query, scope)
+		return docs, true, err
+	}
+
+	docs, err := a.retriever.GetScopedDocuments(ctx, query, scope)
+	if err != nil {
+		return nil, false, err
+	}
+	scored := make([]interfaces.ScoredDocument, len(docs))
+	for i, doc := range docs {
+		scored[i] = interfaces.ScoredDocument{Document: doc}
+	}
+	return scored, false, nil
+}
+
+// format renders documents until the character budget is exhausted, marking which were used.
+// Documents already skipped are left out.
+func (a *Assembler) format(docs []RetrievedDocument) (string, int) {
+	var b strings.Builder
+	b.WriteString(contextHeader)
+	remaining := a.config.MaxChars - utf8.RuneCountInString(contextHeader)
+
+	used := 0
+	for i := range docs {
+		doc := &docs[i]
+		if doc.Skipped != "" {
+			continue
+		}
+		text := strings.TrimSpace(doc.PageContent)
+		if text == "" {
+			doc.Skipped = SkipEmpty
+			continue
+		}
+		if a.config.MaxDocuments > 0 && used >= a.config.MaxDocuments {
+			doc.Skipped = SkipBudget
+			continue
+		}
+
+		entry := "\n- " + text
+		if a.config.MaxChars > 0 {
+			if remaining <= len("\n- ...") {
+				doc.Skipped = SkipBudget
+				continue
+			}
+			if utf8.RuneCountInString(entry) > remaining {
+				entry = truncateRunes(entry, remaining-len("...")) + "..."
+			}
+			remaining -= utf8.RuneCountInString(entry)
+		}
+
+		b.WriteString(entry)
+		doc.Text = strings.TrimPrefix(entry, "\n- ")
+		doc.Used = true
+		used++
+	}
+
+	return b.String(), used
+}
+
+// documentLabel identifies a document in logs
+func documentLabel(doc interfaces.Document) string {
+	if doc.ID != "" {
+		return doc.ID
+	}
+	if id, ok := doc.Metadata["id"]; ok {
+		return fmt.Sprint(id)
+	}
+	return truncateRunes(doc.PageContent, 60)
+}
+
+func truncateRunes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,340 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// MockRetriever returns fixed documents and records the queries it receives
+type MockRetriever struct {
+	docs    []interfaces.Document
+	err     error
+	queries []string
+	scopes  []interfaces.SearchScope
+}
+
+func (m *MockRetriever) GetScopedDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.Document, error) {
+	m.queries = append(m.queries, query)
+	m.scopes = append(m.scopes, scope)
+	return m.docs, m.err
+}
+
+// MockScoredRetriever returns fixed scored documents
+type MockScoredRetriever struct {
+	MockRetriever
+	scored []interfaces.ScoredDocument
+}
+
+func (m *MockScoredRetriever) GetScoredDocuments(ctx context.Context, query string, scope interfaces.SearchScope) ([]interfaces.ScoredDocument, error) {
+	m.queries = append(m.queries, query)
+	m.scopes = append(m.scopes, scope)
+	return m.scored, m.err
+}
+
+func TestNewAssembler(t *testing.T) {
+	if _, err := NewAssembler(nil, nil); err == nil {
+		t.Error("expected error for nil retriever")
+	}
+}
+
+func TestAssembler_Assemble(t *testing.T) {
+	t.Run("formats documents", func(t *testing.T) {
+		retriever := &MockRetriever{docs: []interfaces.Document{
+			{PageContent: "The innkeeper is named Borin"},
+			{PageContent: "  "},
+			{PageContent: "The mayor owes the party 50 gold"},
+		}}
+		a, _ := NewAssembler(retriever, nil)
+
+		msg, used, err := a.Assemble(context.Background(), interfaces.SearchScope{}, "who runs the inn?")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if retriever.queries[0] != "who runs the inn?" {
+			t.Errorf("expected player message as query, got %q", retriever.queries[0])
+		}
+		if len(used) != 2 {
+			t.Errorf("expected 2 used documents, got %d", len(used))
+		}
+		if msg.Role != "system" {
+			t.Errorf("expected system role, got %q", msg.Role)
+		}
+		if !strings.Contains(msg.Content, "- The innkeeper is named Borin") || !strings.Contains(msg.Content, "- The mayor owes") {
+			t.Errorf("documents missing from context: %q", msg.Content)
+		}
+	})
+
+	t.Run("respects character budget", func(t *testing.T) {
+		retriever := &MockRetriever{docs: []interfaces.Document{
+			{PageContent: strings.Repeat("а", 300)},
+			{PageContent: strings.Repeat("б", 300)},
+		}}
+		budget := utf8.RuneCountInString(contextHeader) + 200
+		a, _ := NewAssembler(retriever, &AssemblerConfig{MaxChars: budget})
+
+		msg, used, err := a.Assemble(context.Background(), interfaces.SearchScope{}, "q")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if n := utf8.RuneCountInString(msg.Content); n > budget {
+			t.Errorf("context has %d chars, budget is %d", n, budget)
+		}
+		if len(used) != 1 {
+			t.Errorf("expected only the truncated first document, got %d", len(used))
+		}
+	})
+
+	t.Run("respects document limit", func(t *testing.T) {
+		retriever := &MockRetriever{docs: []interfaces.Document{
+			{PageContent: "one"}, {PageContent: "two"}, {PageContent: "three"},
+		}}
+		a, _ := NewAssembler(retriever, &AssemblerConfig{MaxDocuments: 2})
+
+		_, used, _ := a.Assemble(context.Background(), interfaces.SearchScope{}, "q")
+		if len(used) != 2 {
+			t.Errorf("expected 2 documents, got %d", len(used))
+		}
+	})
+
+	t.Run("no documents", func(t *testing.T) {
+		a, _ := NewAssembler(&MockRetriever{}, nil)
+
+		msg, used, err := a.Assemble(context.Background(), interfaces.SearchScope{}, "q")
+		if err != nil || msg != nil || used != nil {
+			t.Errorf("expected empty result, got %v %v %v", msg, used, err)
+		}
+	})
+
+	t.Run("retriever error", func(t *testing.T) {
+		a, _ := NewAssembler(&MockRetriever{err: errors.New("db down")}, nil)
+
+		if _, _, err := a.Assemble(context.Background(), interfaces.SearchScope{}, "q"); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestAssembler_Retrieve(t *testing.T) {
+	scored := func(id, content string, score float64) interfaces.ScoredDocument {
+		return interfaces.ScoredDocument{
+			Document:     interfaces.Document{ID: id, PageContent: content},
+			Score:        score,
+			SemanticRank: 1,
+			Source:       "memory",
+		}
+	}
+
+	t.Run("threshold and budget are recorded", func(t *testing.T) {
+		retriever := &MockScoredRetriever{scored: []interfaces.ScoredDocument{
+			scored("a", "The innkeeper is named Borin", 0.03),
+			scored("b", " ", 0.025),
+			scored("c", "The mayor owes the party 50 gold", 0.02),
+			scored("d", "The forest is quiet", 0.01),
+		}}
+		config := DefaultAssemblerConfig()
+		config.MinScores = map[string]float64{"memory": 0.015, "qdrant": 0.5}
+		config.MaxDocuments = 1
+		a, _ := NewAssembler(retriever, config)
+
+		msg, retrieval := a.Retrieve(context.Background(), interfaces.SearchScope{GameID: "g1"}, "inn")
+		if msg == nil || !strings.Contains(msg.Content, "Borin") || strings.Contains(msg.Content, "mayor") {
+			t.Fatalf("expected only the first document in context, got %+v", msg)
+		}
+		if !retrieval.Scored || retrieval.Query != "inn" || retrieval.Scope.GameID != "g1" {
+			t.Errorf("unexpected retrieval: %+v", retrieval)
+		}
+
+		expected := []SkipReason{"", SkipEmpty, SkipBudget, SkipBelowThreshold}
+		for i, doc := range retrieval.Documents {
+			if doc.Skipped != expected[i] || doc.Used != (expected[i] == "") {
+				t.Errorf("document %s: expected skip %q, got %+v", doc.ID, expected[i], doc)
+			}
+		}
+		if used := retrieval.Used(); len(used) != 1 || used[0].ID != "a" {
+			t.Errorf("expected document a used, got %+v", used)
+		}
+	})
+
+	t.Run("threshold ignores retrievers without scores", func(t *testing.T) {
+		config := DefaultAssemblerConfig()
+		config.MinScores = map[string]float64{"": 0.5}
+		a, _ := NewAssembler(&MockRetriever{docs: []interfaces.Document{{PageContent: "lore"}}}, config)
+
+		msg, retrieval := a.Retrieve(context.Background(), interfaces.SearchScope{}, "q")
+		if msg == nil || retrieval.Scored || !retrieval.Documents[0].Used {
+			t.Errorf("expected the unscored document used, got %+v", retrieval)
+		}
+	})
+
+	t.Run("threshold of another source does not apply", func(t *testing.T) {
+		config := DefaultAssemblerConfig()
+		config.MinScores = map[string]float64{"qdrant": 0.5}
+		a, _ := NewAssembler(&MockScoredRetriever{scored: []interfaces.ScoredDocument{scored("a", "lore", 0.03)}}, config)
+
+		_, retrieval := a.Retrieve(context.Background(), interfaces.SearchScope{}, "q")
+		if !retrieval.Documents[0].Used {
+			t.Errorf("expected the RRF-scored document kept by a qdrant threshold, got %+v", retrieval.Documents[0])
+		}
+	})
+
+	t.Run("failure is recorded", func(t *testing.T) {
+		a, _ := NewAssembler(&MockRetriever{err: errors.New("db down")}, nil)
+
+		msg, retrieval := a.Retrieve(context.Background(), interfaces.SearchScope{}, "q")
+		if msg != nil || retrieval.Err == nil {
+			t.Errorf("expected the error recorded, got %+v", retrieval)
+		}
+	})
+}
+
+// MockReranker returns fixed scores or fails with err
+type MockReranker struct {
+	scores []float64
+	err    error
+	docs   []interfaces.ScoredDocument
+}
+
+func (m *MockReranker) Name() string {
+	return "mock"
+}
+
+func (m *MockReranker) Rerank(ctx context.Context, query string, docs []interfaces.ScoredDocument) ([]float64, error) {
+	m.docs = docs
+	return m.scores, m.err
+}
+
+func TestAssembler_Rerank(t *testing.T) {
+	retriever := &MockRetriever{docs: []interfaces.Document{
+		{ID: "a", PageContent: "The mayor owes the party 50 gold"},
+		{ID: "b", PageContent: "The innkeeper promised a free room"},
+		{ID: "c", PageContent: "The forest is quiet"},
+	}}
+
+	t.Run("reorders the top candidates", func(t *testing.T) {
+		config := DefaultAssemblerConfig()
+		config.RerankTopN = 2
+		config.MinScores = map[string]float64{RerankSource: 0.5, "": 0.95}
+		a, _ := NewAssembler(retriever, config)
+		reranker := &MockReranker{scores: []float64{0.3, 0.9}}
+		a.SetReranker(reranker)
+
+		msg, retrieval := a.Retrieve(context.Background(), interfaces.SearchScope{}, "what did the innkeeper promise?")
+		if len(reranker.docs) != 2 {
+			t.Errorf("expected the top 2 candidates reranked, got %d", len(reranker.docs))
+		}
+		if retrieval.Reranker != "mock" || !retrieval.Scored || len(retrieval.Documents) != 2 {
+			t.Fatalf("unexpected retrieval: %+v", retrieval)
+		}
+		first, second := retrieval.Documents[0], retrieval.Documents[1]
+		if first.ID != "b" || first.RetrievalRank != 2 || first.Score != 0.9 {
+			t.Errorf("expected b first, found second by retrieval, got %+v", first)
+		}
+		if second.ID != "a" || second.RetrievalRank != 1 || second.Skipped != SkipBelowThreshold {
+			t.Errorf("expected a second and below the threshold, got %+v", second)
+		}
+		if msg == nil || !strings.Contains(msg.Content, "innkeeper") || strings.Contains(msg.Content, "mayor") {
+			t.Errorf("expected only the innkeeper in context, got %+v", msg)
+		}
+	})
+
+	t.Run("failure keeps the retrieval order", func(t *testing.T) {
+		a, _ := NewAssembler(retriever, nil)
+		a.SetReranker(&MockReranker{err: errors.New("timeout")})
+
+		msg, retrieval := a.Retrieve(context.Background(), interfaces.SearchScope{}, "q")
+		if msg == nil || retrieval.RerankErr == nil || retrieval.Reranker != "" {
+			t.Fatalf("expected the failure recorded, got %+v", retrieval)
+		}
+		if len(retrieval.Documents) != 3 || retrieval.Documents[0].ID != "a" || retrieval.Documents[0].RetrievalRank != 0 {
+			t.Errorf("expected the retrieval order kept, got %+v", retrieval.Documents)
+		}
+	})
+
+	t.Run("wrong number of scores fails", func(t *testing.T) {
+		a, _ := NewAssembler(retriever, nil)
+		a.SetReranker(&MockReranker{scores: []float64{1}})
+
+		_, retrieval := a.Retrieve(context.Background(), interfaces.SearchScope{}, "q")
+		if retrieval.RerankErr == nil || len(retrieval.Documents) != 3 {
+			t.Errorf("expected the mismatch recorded, got %+v", retrieval)
+		}
+	})
+}
+
+func TestRankChanges(t *testing.T) {
+	docs := []RetrievedDocument{{RetrievalRank: 3}, {RetrievalRank: 2}, {RetrievalRank: 1}, {RetrievalRank: 4}}
+	moved, shift := rankChanges(docs)
+	if moved != 2 || shift != 1 {
+		t.Errorf("expected 2 moved with mean shift 1, got %d and %v", moved, shift)
+	}
+}
+
+func TestManager_PlayWithAssembler(t *testing.T) {
+	t.Run("context is sent before the player message", func(t *testing.T) {
+		provider := &MockProvider{}
+		store := NewMemoryStore()
+		m, _ := NewManager(provider, store, nil)
+		retriever := &MockRetriever{docs: []interfaces.Document{{PageContent: "lore"}}}
+		a, _ := NewAssembler(retriever, nil)
+		m.SetAssembler(a)
+
+		if _, err := m.Play(context.Background(), 1, 10, "look around"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		sess, _ := store.Load(context.Background(), 1)
+		if retriever.scopes[0].GameID != sess.GameID || retriever.scopes[0].UserID != 0 {
+			t.Errorf("expected game-wide scope %q, got %+v", sess.GameID, retriever.scopes[0])
+		}
+
+		messages := provider.calls[0]
+		if len(messages) != 3 {
+			t.Fatalf("expected 3 messages, got %d", len(messages))
+		}
+		if !strings.Contains(messages[1].Content, "lore") {
+			t.Errorf("expected retrieved context before the player message, got %q", messages[1].Content)
+		}
+
+		retrieval := m.LastRetrieval(1)
+		if retrieval == nil || retrieval.Query != "look around" || len(retrieval.Used()) != 1 {
+			t.Errorf("expected the retrieval of the turn recorded, got %+v", retrieval)
+		}
+		if m.LastRetrieval(2) != nil {
+			t.Error("expected no retrieval for another chat")
+		}
+	})
+
+	t.Run("retrieval failure does not block the turn", func(t *testing.T) {
+		provider := &MockProvider{}
+		m, _ := NewManager(provider, NewMemoryStore(), nil)
+		a, _ := NewAssembler(&MockRetriever{err: errors.New("db down")}, nil)
+		m.SetAssembler(a)
+
+		if _, err := m.Play(context.Background(), 1, 10, "look around"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(provider.calls[0]) != 2 {
+			t.Errorf("expected no context message, got %d messages", len(provider.calls[0]))
+		}
+	})
+
+	t.Run("party location is passed to retrieval", func(t *testing.T) {
+		m, _ := NewManager(&MockProvider{}, NewMemoryStore(), nil)
+		retriever := &MockRetriever{}
+		a, _ := NewAssembler(retriever, nil)
+		m.SetAssembler(a)
+		m.SetLocationFunc(func(ctx context.Context, gameID string) string {
+			return "tavern"
+		})
+
+		_, _ = m.Play(context.Background(), 1, 10, "look around")
+		if retriever.scopes[0].LocationID != "tavern" {
+			t.Errorf("expected location in scope, got %+v", retriever.scopes[0])
+		}
+	})
+}
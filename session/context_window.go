@@ -0,0 +1,257 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/internal/uuid"
+)
+
+// DefaultSummaryPrompt instructs the model that compresses old turns
+const DefaultSummaryPrompt = `Ты — летописец текстовой ролевой игры.
+Обнови краткое содержание кампании «история до сих пор», добавив к нему новые события.
+Сохрани ключевые события, решения игроков, имена персонажей, места, незавершённые сюжетные линии и важные числа.
+Пиши связным текстом в прошедшем времени, без выдумок и без обращения к игрокам.
+Отвечай только текстом краткого содержания на русском языке.`
+
+// DocumentTypeSummary marks documents that store the story so far of a game
+const DocumentTypeSummary = "summary"
+
+// Token estimation constants. Cyrillic text takes about one token per 2-3 characters
+// and English about one per 4, so 3 keeps the estimate on the safe side for both.
+const (
+	charsPerToken   = 3
+	messageOverhead = 4 // role and separators of each message
+)
+
+// DocumentDeleter removes documents from campaign memory
+type DocumentDeleter interface {
+	DeleteDocuments(ctx context.Context, ids []string) error
+}
+
+// ContextConfig contains settings of the context window
+type ContextConfig struct {
+	ContextWindow    int // Context limit of the model in tokens
+	ReserveTokens    int // Tokens kept free for the reply
+	KeepRecent       int // Newest messages that are always kept verbatim
+	SummaryMaxTokens int
+	SummaryPrompt    string
+	StoreTimeout     time.Duration // Writing a summary to campaign memory in the background
+}
+
+// DefaultContextConfig returns context window settings for a 16k model
+func DefaultContextConfig() *ContextConfig {
+	return &ContextConfig{
+		ContextWindow:    16000,
+		ReserveTokens:    1024,
+		KeepRecent:       6,
+		SummaryMaxTokens: 512,
+		SummaryPrompt:    DefaultSummaryPrompt,
+		StoreTimeout:     time.Minute,
+	}
+}
+
+// ContextWindow keeps prompts within the model context limit. When a prompt outgrows it,
+// the oldest turns are compressed into a "story so far" summary that replaces them.
+type ContextWindow struct {
+	provider interfaces.InferenceProvider
+	writer   DocumentWriter
+	config   *ContextConfig
+}
+
+// NewContextWindow creates a context window. The writer is optional; when set,
+// StoreSummary also stores summaries in campaign memory so they can be retrieved later.
+// A writer that is also a DocumentDeleter keeps only the latest summary of a game.
+func NewContextWindow(provider interfaces.InferenceProvider, writer DocumentWriter, config *ContextConfig) (*ContextWindow, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("inference provider cannot be nil")
+	}
+	if config == nil {
+		config = DefaultContextConfig()
+	}
+	if config.ContextWindow <= config.ReserveTokens+config.SummaryMaxTokens {
+		return nil, fmt.Errorf("context window of %d tokens leaves no room for history", config.ContextWindow)
+	}
+	return &ContextWindow{provider: provider, writer: writer, config: config}, nil
+}
+
+// EstimateTokens approximates the prompt size of messages without a tokenizer
+func EstimateTokens(messages []interfaces.Message) int {
+	total := 0
+	for _, msg := range messages {
+		chars := utf8.RuneCountInString(msg.Content)
+		for _, call := range msg.ToolCalls {
+			chars += utf8.RuneCountInString(call.Name) + utf8.RuneCountInString(call.Arguments)
+		}
+		total += messageOverhead + (chars+charsPerToken-1)/charsPerToken
+	}
+	return total
+}
+
+// Fit compacts the history of sess when prompt, which is built from it, exceeds the budget.
+// It reports whether the history changed, in which case the prompt must be rebuilt and,
+// once the session is saved, the summary stored with StoreSummary.
+func (w *ContextWindow) Fit(ctx context.Context, sess *Session, prompt []interfaces.Message) (bool, error) {
+	budget := w.config.ContextWindow - w.config.ReserveTokens
+	total := EstimateTokens(prompt)
+	if total <= budget {
+		return false, nil
+	}
+
+	// Compact down to half of the room left for history, so the next turns do not compact again at once
+	history := EstimateTokens(sess.History)
+	available := budget - (total - history) - w.config.SummaryMaxTokens
+	cut := w.splitPoint(sess.History, available/2, available)
+	if cut == 0 {
+		log.Warn().
+			Int64("chat_id", sess.ChatID).
+			Int("prompt_tokens", total).
+			Int("budget", budget).
+			Msg("Prompt exceeds the context window, but there are no old turns to summarize")
+		return false, nil
+	}
+
+	summary, err := w.summarize(ctx, sess.Summary, sess.History[:cut])
+	if err != nil {
+		return false, fmt.Errorf("summarizing history: %w", err)
+	}
+
+	log.Info().
+		Int64("chat_id", sess.ChatID).
+		Str("game_id", sess.GameID).
+		Int("summarized_messages", cut).
+		Int("prompt_tokens", total).
+		Int("budget", budget).
+		Msg("History compacted into summary")
+
+	sess.Summary = summary
+	sess.History = append([]interfaces.Message(nil), sess.History[cut:]...)
+	return true, nil
+}
+
+// splitPoint returns the index of the first message kept verbatim. The newest messages are kept
+// while they fit the target, at least KeepRecent of them as long as they fit the limit.
+// The kept part starts at a player message, so a reply is never separated from its action.
+func (w *ContextWindow) splitPoint(history []interfaces.Message, target, limit int) int {
+	kept, tokens := 0, 0
+	for i := len(history) - 1; i >= 0; i-- {
+		next := tokens + EstimateTokens(history[i:i+1])
+		if next > limit || (next > target && kept >= w.config.KeepRecent) {
+			break
+		}
+		kept++
+		tokens = next
+	}
+
+	cut := len(history) - kept
+	for cut < len(history) && history[cut].Role != "user" {
+		cut++
+	}
+	return cut
+}
+
+func (w *ContextWindow) summarize(ctx context.Context, previous string, messages []interfaces.Message) (string, error) {
+	var b strings.Builder
+	if previous != "" {
+		fmt.Fprintf(&b, "Краткое содержание до сих пор:\n%s\n\n", previous)
+	}
+	b.WriteString("Новые события:")
+	for _, msg := range messages {
+		fmt.Fprintf(&b, "\n%s: %s", speaker(msg.Role), msg.Content)
+	}
+
+	summary, err := w.provider.GenerateResponse(ctx, []interfaces.Message{
+		{Role: "system", Content: w.config.SummaryPrompt},
+		{Role: "user", Content: b.String()},
+	}, 0.3, w.config.SummaryMaxTokens)
+	if err != nil {
+		return "", err
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return summary, nil
+}
+
+// ErrStaleSummary is returned by a SummaryRecorder when the session was summarized again meanwhile
+var ErrStaleSummary = errors.New("session has a newer summary")
+
+// SummaryRecorder records the document ID of a stored summary in its session and returns the ID it replaces
+type SummaryRecorder func(ctx context.Context, id string) (previous string, err error)
+
+// StoreSummary writes the summary of a saved session to campaign memory in place of the previous one.
+// The new document is written and recorded with record before the previous one is deleted,
+// so a failure never leaves the campaign without a summary. Failures are logged, the session keeps the summary anyway.
+func (w *ContextWindow) StoreSummary(ctx context.Context, gameID string, chatID int64, summary string, record SummaryRecorder) {
+	if w.writer == nil || summary == "" {
+		return
+	}
+	doc := interfaces.Document{
+		ID:          uuid.New(),
+		PageContent: "История до сих пор: " + summary,
+		Metadata: map[string]interface{}{
+			MetadataGameID: gameID,
+			MetadataChatID: chatID,
+			MetadataType:   DocumentTypeSummary,
+		},
+	}
+	if err := w.writer.AddDocuments(ctx, []interfaces.Document{doc}); err != nil {
+		log.Error().
+			Err(err).
+			Str("game_id", gameID).
+			Msg("Failed to write summary to campaign memory")
+		return
+	}
+
+	// Each summary includes the previous one, so the replaced summary only duplicates it
+	previous, err := record(ctx, doc.ID)
+	if errors.Is(err, ErrStaleSummary) {
+		previous = doc.ID
+	} else if err != nil {
+		log.Error().
+			Err(err).
+			Str("game_id", gameID).
+			Str("document_id", doc.ID).
+			Msg("Failed to record the summary document in the session")
+		return
+	}
+
+	deleter, ok := w.writer.(DocumentDeleter)
+	if !ok || previous == "" {
+		return
+	}
+	if err := deleter.DeleteDocuments(ctx, []string{previous}); err != nil {
+		log.Warn().
+			Err(err).
+			Str("game_id", gameID).
+			Str("document_id", previous).
+			Msg("Failed to delete the previous summary from campaign memory")
+	}
+}
+
+// summaryMessage returns the summary as a prompt message, or nil when there is none
+func summaryMessage(sess *Session) *interfaces.Message {
+	if sess.Summary == "" {
+		return nil
+	}
+	return &interfaces.Message{Role: "system", Content: "Краткое содержание предыдущих событий кампании:\n" + sess.Summary}
+}
+
+func speaker(role string) string {
+	switch role {
+	case "user":
+		return "Игрок"
+	case "assistant":
+		return "Ведущий"
+	default:
+		return "Заметка"
+	}
+}
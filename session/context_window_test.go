@@ -0,0 +1,265 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+func testContextConfig() *ContextConfig {
+	return &ContextConfig{
+		ContextWindow:    200,
+		ReserveTokens:    20,
+		KeepRecent:       2,
+		SummaryMaxTokens: 20,
+		SummaryPrompt:    DefaultSummaryPrompt,
+	}
+}
+
+func TestNewContextWindow(t *testing.T) {
+	if _, err := NewContextWindow(nil, nil, nil); err == nil {
+		t.Error("expected error for nil provider")
+	}
+	if _, err := NewContextWindow(&MockProvider{}, nil, &ContextConfig{ContextWindow: 100, ReserveTokens: 90, SummaryMaxTokens: 10}); err == nil {
+		t.Error("expected error for a window without room for history")
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	messages := []interfaces.Message{
+		{Role: "user", Content: "Привет"}, // 6 runes, not 12 bytes
+		{Role: "assistant", ToolCalls: []interfaces.ToolCall{{Name: "roll", Arguments: `{"n":1}`}}},
+	}
+	if got, want := EstimateTokens(messages), (4+2)+(4+4); got != want {
+		t.Errorf("EstimateTokens() = %d, want %d", got, want)
+	}
+	if got := EstimateTokens(nil); got != 0 {
+		t.Errorf("expected 0 for no messages, got %d", got)
+	}
+}
+
+func TestContextWindow_Fit(t *testing.T) {
+	ctx := context.Background()
+	long := strings.Repeat("x", 150)
+
+	history := func() []interfaces.Message {
+		return []interfaces.Message{
+			{Role: "user", Content: long},
+			{Role: "assistant", Content: long},
+			{Role: "user", Content: long},
+			{Role: "assistant", Content: long},
+			{Role: "user", Content: "short"},
+			{Role: "assistant", Content: "short"},
+		}
+	}
+
+	t.Run("prompt within budget is untouched", func(t *testing.T) {
+		provider := &MockProvider{}
+		w, _ := NewContextWindow(provider, nil, testContextConfig())
+		sess := &Session{History: history()[4:]}
+
+		compacted, err := w.Fit(ctx, sess, sess.History)
+		if err != nil || compacted {
+			t.Errorf("expected no compaction, got %v, %v", compacted, err)
+		}
+		if len(provider.calls) != 0 {
+			t.Errorf("expected no summary call, got %d", len(provider.calls))
+		}
+	})
+
+	t.Run("oldest turns become the summary", func(t *testing.T) {
+		provider := &MockProvider{}
+		writer := &MockWriter{}
+		w, _ := NewContextWindow(provider, writer, testContextConfig())
+		sess := &Session{GameID: "game", ChatID: 1, Summary: "Герои встретились в таверне.", History: history()}
+
+		compacted, err := w.Fit(ctx, sess, sess.History)
+		if err != nil || !compacted {
+			t.Fatalf("expected compaction, got %v, %v", compacted, err)
+		}
+		if sess.Summary != "reply 1" {
+			t.Errorf("expected the generated summary, got %q", sess.Summary)
+		}
+		if len(sess.History) == 0 || sess.History[0].Role != "user" || sess.History[len(sess.History)-1].Content != "short" {
+			t.Errorf("expected recent turns kept from a player message, got %+v", sess.History)
+		}
+
+		request := provider.calls[0]
+		if request[0].Content != DefaultSummaryPrompt || !strings.Contains(request[1].Content, "Герои встретились в таверне.") {
+			t.Errorf("expected the previous summary in the request, got %+v", request)
+		}
+		if !strings.Contains(request[1].Content, "Игрок: "+long) {
+			t.Errorf("expected the old turns in the request, got %q", request[1].Content)
+		}
+
+		if len(writer.docs) != 0 {
+			t.Errorf("expected the summary stored only after the session is saved, got %+v", writer.docs)
+		}
+	})
+
+	t.Run("failed summary keeps the history", func(t *testing.T) {
+		w, _ := NewContextWindow(&MockProvider{err: errors.New("boom")}, nil, testContextConfig())
+		sess := &Session{History: history()}
+
+		if _, err := w.Fit(ctx, sess, sess.History); err == nil {
+			t.Error("expected error")
+		}
+		if len(sess.History) != 6 || sess.Summary != "" {
+			t.Errorf("expected the session untouched, got %+v", sess)
+		}
+	})
+}
+
+func TestManager_PlayWithContextWindow(t *testing.T) {
+	provider := &MockProvider{}
+	m, _ := NewManager(provider, NewMemoryStore(), &Config{MaxHistory: 2})
+	config := testContextConfig()
+	writer := &MockWriter{}
+	w, _ := NewContextWindow(provider, writer, config)
+	m.SetContextWindow(w)
+
+	long := strings.Repeat("x", 90)
+	for i := 0; i < 8; i++ {
+		if _, err := m.Play(context.Background(), 1, 10, long); err != nil {
+			t.Fatalf("turn %d: %v", i, err)
+		}
+	}
+
+	summaries := 0
+	for _, call := range provider.calls {
+		if call[0].Content == DefaultSummaryPrompt {
+			summaries++
+			continue
+		}
+		if tokens := EstimateTokens(call); tokens > config.ContextWindow-config.ReserveTokens {
+			t.Errorf("prompt of %d tokens exceeds the budget", tokens)
+		}
+	}
+	if summaries == 0 {
+		t.Fatal("expected the history to be summarized")
+	}
+
+	m.Wait()
+	sess, _ := m.store.Load(context.Background(), 1)
+	if sess.Summary == "" || len(sess.History) <= 2 {
+		t.Errorf("expected a summary and history beyond MaxHistory, got %q with %d messages", sess.Summary, len(sess.History))
+	}
+	last := provider.calls[len(provider.calls)-1]
+	if !strings.Contains(last[0].Content, sess.Summary) {
+		t.Errorf("expected the summary at the start of the prompt, got %+v", last[0])
+	}
+
+	if len(writer.docs) != summaries || len(writer.deleted) != summaries-1 {
+		t.Fatalf("expected %d summaries stored and all but the last deleted, got %d and %d", summaries, len(writer.docs), len(writer.deleted))
+	}
+	// Summaries are stored in the background, so they may be written out of order
+	var recorded *interfaces.Document
+	for i := range writer.docs {
+		if writer.docs[i].ID == sess.SummaryID {
+			recorded = &writer.docs[i]
+		}
+	}
+	if recorded == nil || !strings.HasSuffix(recorded.PageContent, sess.Summary) || recorded.Metadata[MetadataType] != DocumentTypeSummary {
+		t.Errorf("expected the session to record the document of its summary, got %q", sess.SummaryID)
+	}
+	for _, id := range writer.deleted {
+		if id == sess.SummaryID {
+			t.Errorf("expected the latest summary kept, got it deleted")
+		}
+	}
+}
+
+func TestContextWindow_StoreSummary(t *testing.T) {
+	ctx := context.Background()
+	// recorder records the new summary ID like the manager does and returns the replaced one
+	recorder := func(recorded *string, err error) SummaryRecorder {
+		return func(ctx context.Context, id string) (string, error) {
+			if err != nil {
+				return "", err
+			}
+			previous := *recorded
+			*recorded = id
+			return previous, nil
+		}
+	}
+
+	t.Run("failed write keeps the previous summary", func(t *testing.T) {
+		writer := &MockWriter{failures: 1}
+		w, _ := NewContextWindow(&MockProvider{}, writer, testContextConfig())
+		recorded := "old"
+
+		w.StoreSummary(ctx, "game", 1, "Новое", recorder(&recorded, nil))
+		if recorded != "old" || len(writer.deleted) != 0 {
+			t.Errorf("expected the previous summary kept, got %q and deleted %v", recorded, writer.deleted)
+		}
+	})
+
+	t.Run("failed save keeps the previous summary", func(t *testing.T) {
+		writer := &MockWriter{}
+		w, _ := NewContextWindow(&MockProvider{}, writer, testContextConfig())
+		recorded := "old"
+
+		w.StoreSummary(ctx, "game", 1, "Новое", recorder(&recorded, errors.New("db down")))
+		if len(writer.docs) != 1 || len(writer.deleted) != 0 {
+			t.Errorf("expected the new summary written and the previous one kept, got deleted %v", writer.deleted)
+		}
+	})
+
+	t.Run("stale summary is deleted", func(t *testing.T) {
+		writer := &MockWriter{}
+		w, _ := NewContextWindow(&MockProvider{}, writer, testContextConfig())
+		recorded := "newer"
+
+		w.StoreSummary(ctx, "game", 1, "Новое", recorder(&recorded, ErrStaleSummary))
+		if len(writer.docs) != 1 || len(writer.deleted) != 1 || writer.deleted[0] != writer.docs[0].ID {
+			t.Errorf("expected the stale summary deleted, got deleted %v", writer.deleted)
+		}
+	})
+
+	t.Run("previous summary is replaced", func(t *testing.T) {
+		writer := &MockWriter{}
+		w, _ := NewContextWindow(&MockProvider{}, writer, testContextConfig())
+		recorded := "old"
+
+		w.StoreSummary(ctx, "game", 1, "Новое", recorder(&recorded, nil))
+		if len(writer.docs) != 1 || recorded != writer.docs[0].ID || recorded == "" {
+			t.Errorf("expected the new summary recorded, got %q", recorded)
+		}
+		if len(writer.deleted) != 1 || writer.deleted[0] != "old" {
+			t.Errorf("expected the previous summary deleted by ID, got %v", writer.deleted)
+		}
+		if doc := writer.docs[0]; doc.Metadata[MetadataGameID] != "game" || doc.Metadata[MetadataType] != DocumentTypeSummary {
+			t.Errorf("unexpected summary metadata %v", doc.Metadata)
+		}
+	})
+}
+
+func TestManager_SummaryInBackground(t *testing.T) {
+	provider := &MockProvider{}
+	m, _ := NewManager(provider, NewMemoryStore(), &Config{MaxHistory: 2})
+	writer := &MockWriter{block: make(chan struct{})}
+	w, _ := NewContextWindow(provider, writer, testContextConfig())
+	m.SetContextWindow(w)
+
+	// Turns are not held up by summaries waiting on campaign memory
+	long := strings.Repeat("x", 90)
+	for i := 0; i < 8; i++ {
+		if _, err := m.Play(context.Background(), 1, 10, long); err != nil {
+			t.Fatalf("turn %d: %v", i, err)
+		}
+	}
+	sess, _ := m.store.Load(context.Background(), 1)
+	if sess.Summary == "" || sess.SummaryID != "" {
+		t.Fatalf("expected a summary not yet stored, got %q with ID %q", sess.Summary, sess.SummaryID)
+	}
+
+	close(writer.block)
+	m.Wait()
+	sess, _ = m.store.Load(context.Background(), 1)
+	if sess.SummaryID == "" {
+		t.Error("expected the summary recorded once written")
+	}
+}
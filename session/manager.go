@@ -0,0 +1,466 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/tools"
+)
+
+// DefaultSystemPrompt is the game-master persona used when none is configured
+const DefaultSystemPrompt = `Ты — ведущий (гейм-мастер) текстовой ролевой игры.
+Описывай мир, персонажей и последствия действий игроков живо и последовательно.
+Помни предыдущие события кампании и не противоречь им.
+Не принимай решения за игроков: заканчивай ход вопросом или ситуацией, требующей их действия.
+Отвечай на русском языке.`
+
+// Config contains game session settings
+type Config struct {
+	SystemPrompt  string
+	Temperature   float64
+	MaxTokens     int
+	MaxHistory    int // Maximum number of stored messages, oldest are dropped first; unused with a context window
+	MaxToolRounds int // Maximum tool-calling rounds per turn
+}
+
+// DefaultConfig returns the default session settings
+func DefaultConfig() *Config {
+	return &Config{
+		SystemPrompt:  DefaultSystemPrompt,
+		Temperature:   0.7,
+		MaxTokens:     0,
+		MaxHistory:    50,
+		MaxToolRounds: 5,
+	}
+}
+
+// ContextSource adds game state, such as the acting player's character sheet, to every turn
+type ContextSource interface {
+	// TurnContext returns text for the game master or an empty string when there is nothing to add
+	TurnContext(ctx context.Context, gameID string, userID int64) (string, error)
+}
+
+// UsageRecorder accounts the tokens spent by provider calls
+type UsageRecorder interface {
+	Record(ctx context.Context, chatID, userID int64, gameID string, u interfaces.Usage)
+}
+
+// LocationFunc returns the party location of a game or an empty string
+type LocationFunc func(ctx context.Context, gameID string) string
+
+// Manager runs game-master turns for Telegram chats
+type Manager struct {
+	provider interfaces.InferenceProvider
+	store    Store
+	config   *Config
+
+	assembler *Assembler
+	memory    *MemoryWriter
+	tools     *tools.Registry
+	sources   []ContextSource
+	locate    LocationFunc
+	usage     UsageRecorder
+	window    *ContextWindow
+	prompts   PromptRenderer
+
+	mu         sync.Mutex
+	locks      map[int64]*sync.Mutex
+	retrievals map[int64]*Retrieval // last retrieval of each chat, for debugging
+	background sync.WaitGroup       // summaries being written to campaign memory
+}
+
+// NewManager creates a session manager
+func NewManager(provider interfaces.InferenceProvider, store Store, config *Config) (*Manager, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("inference provider cannot be nil")
+	}
+	if store == nil {
+		return nil, fmt.Errorf("session store cannot be nil")
+	}
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Manager{
+		provider:   provider,
+		store:      store,
+		config:     config,
+		locks:      make(map[int64]*sync.Mutex),
+		retrievals: make(map[int64]*Retrieval),
+	}, nil
+}
+
+// SetAssembler enables retrieval-augmented turns. It must be called before the bot starts.
+func (m *Manager) SetAssembler(assembler *Assembler) {
+	m.assembler = assembler
+}
+
+// SetMemoryWriter enables writing resolved turns to campaign memory. It must be called before the bot starts.
+func (m *Manager) SetMemoryWriter(memory *MemoryWriter) {
+	m.memory = memory
+}
+
+// SetTools offers game mechanics to providers that support tool calling. It must be called before the bot starts.
+func (m *Manager) SetTools(registry *tools.Registry) {
+	m.tools = registry
+}
+
+// AddContextSource adds game state to every turn. It must be called before the bot starts.
+func (m *Manager) AddContextSource(source ContextSource) {
+	m.sources = append(m.sources, source)
+}
+
+// SetLocationFunc boosts retrieval toward the party location. It must be called before the bot starts.
+func (m *Manager) SetLocationFunc(locate LocationFunc) {
+	m.locate = locate
+}
+
+// SetUsageRecorder accounts the tokens of every turn to its game and player. It must be called before the bot starts.
+func (m *Manager) SetUsageRecorder(recorder UsageRecorder) {
+	m.usage = recorder
+}
+
+// SetContextWindow summarizes old turns instead of dropping them once prompts outgrow the model context.
+// It replaces the MaxHistory limit. It must be called before the bot starts.
+func (m *Manager) SetContextWindow(window *ContextWindow) {
+	m.window = window
+}
+
+// GameID returns the game bound to a chat, starting a new game on first contact
+func (m *Manager) GameID(ctx context.Context, chatID int64) (string, error) {
+	lock := m.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	sess, err := m.session(ctx, chatID)
+	if err != nil {
+		return "", err
+	}
+	return sess.GameID, nil
+}
+
+// Play sends the player's message to the game master with the accumulated campaign history
+func (m *Manager) Play(ctx context.Context, chatID, userID int64, text string) (string, error) {
+	return m.PlayStream(ctx, chatID, userID, text, nil)
+}
+
+// PlayStream works like Play and reports narration deltas to onDelta as they arrive.
+// Providers without streaming support return the whole response without calling onDelta.
+func (m *Manager) PlayStream(ctx context.Context, chatID, userID int64, text string, onDelta func(delta string) error) (string, error) {
+	lock := m.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	start := time.Now()
+
+	sess, err := m.session(ctx, chatID)
+	if err != nil {
+		return "", err
+	}
+	ctx = withUsage(ctx, m.usage, chatID, userID, sess.GameID)
+
+	userMessage := interfaces.Message{Role: "user", Content: text}
+	turn := turnContext{state: m.stateContext(ctx, sess, userID)}
+	turn.retrieved, turn.documents = m.retrieveContext(ctx, sess, text)
+	messages := m.buildMessages(sess, turn, userMessage)
+	compacted := false
+	if m.window != nil {
+		compacted, err = m.window.Fit(ctx, sess, messages)
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Int64("chat_id", chatID).
+				Msg("Context compaction failed, continuing with the full history")
+		}
+		if compacted {
+			messages = m.buildMessages(sess, turn, userMessage)
+		}
+	}
+
+	inv := tools.Invocation{GameID: sess.GameID, ChatID: chatID, UserID: userID}
+	response, calls, err := m.generate(ctx, inv, messages, onDelta)
+	if err != nil {
+		return "", fmt.Errorf("generating response: %w", err)
+	}
+
+	sess.History = append(sess.History, userMessage)
+	if len(calls) > 0 {
+		// Tool results are kept as a note so later turns see the real numbers
+		sess.History = append(sess.History, toolResultsMessage(calls))
+	}
+	sess.History = append(sess.History, interfaces.Message{Role: "assistant", Content: response})
+	sess.History = m.trimHistory(sess.History)
+
+	if err := m.store.Save(ctx, sess); err != nil {
+		log.Error().
+			Err(err).
+			Int64("chat_id", chatID).
+			Str("game_id", sess.GameID).
+			Msg("Failed to save session")
+	} else if compacted {
+		m.storeSummary(sess.GameID, chatID, sess.Summary)
+	}
+
+	if m.memory != nil {
+		m.memory.Write(TurnRecord{
+			GameID:  sess.GameID,
+			ChatID:  chatID,
+			UserID:  userID,
+			Action:  text,
+			Outcome: response,
+		})
+	}
+
+	log.Debug().
+		Int64("chat_id", chatID).
+		Int64("user_id", userID).
+		Str("game_id", sess.GameID).
+		Int("history_len", len(sess.History)).
+		Dur("turn_duration", time.Since(start)).
+		Msg("Turn completed")
+
+	return response, nil
+}
+
+// Wait blocks until the summaries being written in the background are stored. Call it on shutdown.
+func (m *Manager) Wait() {
+	m.background.Wait()
+}
+
+// storeSummary writes a new summary to campaign memory in the background, so the reply is not delayed
+// and the chat is not locked meanwhile. The chat lock is taken only to record the document in the session.
+func (m *Manager) storeSummary(gameID string, chatID int64, summary string) {
+	m.background.Add(1)
+	go func() {
+		defer m.background.Done()
+		ctx := context.Background()
+		if m.window.config.StoreTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, m.window.config.StoreTimeout)
+			defer cancel()
+		}
+
+		m.window.StoreSummary(ctx, gameID, chatID, summary, func(ctx context.Context, id string) (string, error) {
+			lock := m.chatLock(chatID)
+			lock.Lock()
+			defer lock.Unlock()
+
+			sess, err := m.store.Load(ctx, chatID)
+			if err != nil {
+				return "", fmt.Errorf("loading session: %w", err)
+			}
+			if sess.GameID != gameID || sess.Summary != summary {
+				return "", ErrStaleSummary
+			}
+			previous := sess.SummaryID
+			sess.SummaryID = id
+			if err := m.store.Save(ctx, sess); err != nil {
+				return "", fmt.Errorf("saving session: %w", err)
+			}
+			return previous, nil
+		})
+	}()
+}
+
+// AppendNote records an out-of-turn event, such as a dice roll, into the chat history
+// so the game master sees it on the next turn
+func (m *Manager) AppendNote(ctx context.Context, chatID int64, text string) error {
+	lock := m.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	sess, err := m.session(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	sess.History = append(sess.History, interfaces.Message{Role: "system", Content: text})
+	sess.History = m.trimHistory(sess.History)
+
+	if err := m.store.Save(ctx, sess); err != nil {
+		return fmt.Errorf("saving session: %w", err)
+	}
+	return nil
+}
+
+// generate runs the tool-calling loop when tools are available, otherwise
+// it streams the response when requested and supported by the provider
+func (m *Manager) generate(ctx context.Context, inv tools.Invocation, messages []interfaces.Message, onDelta func(delta string) error) (string, []tools.CallRecord, error) {
+	if m.tools != nil && m.tools.Len() > 0 {
+		if toolCalling, ok := m.provider.(interfaces.ToolCallingProvider); ok {
+			result, err := tools.Run(ctx, toolCalling, m.tools, inv, messages, tools.RunOptions{
+				Temperature: m.config.Temperature,
+				MaxTokens:   m.config.MaxTokens,
+				MaxRounds:   m.config.MaxToolRounds,
+				OnDelta:     onDelta,
+			})
+			return result.Content, result.Calls, err
+		}
+	}
+
+	if onDelta != nil {
+		if streaming, ok := m.provider.(interfaces.StreamingInferenceProvider); ok {
+			response, err := streaming.GenerateResponseStream(ctx, messages, m.config.Temperature, m.config.MaxTokens, onDelta)
+			return response, nil, err
+		}
+	}
+
+	response, err := m.provider.GenerateResponse(ctx, messages, m.config.Temperature, m.config.MaxTokens)
+	return response, nil, err
+}
+
+// session loads the chat session, starting a new game on first contact
+func (m *Manager) session(ctx context.Context, chatID int64) (*Session, error) {
+	sess, err := m.store.Load(ctx, chatID)
+	if errors.Is(err, ErrNotFound) {
+		sess, err = m.store.Create(ctx, chatID, fmt.Sprintf("Chat %d", chatID))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading session: %w", err)
+	}
+	return sess, nil
+}
+
+// retrieveContext returns the retrieved context message and the texts of its documents, or nil. Retrieval failures never block a turn.
+func (m *Manager) retrieveContext(ctx context.Context, sess *Session, text string) (*interfaces.Message, []string) {
+	if m.assembler == nil {
+		return nil, nil
+	}
+
+	// Lore is shared by every player of the game, so the scope is not narrowed to the player
+	scope := interfaces.SearchScope{GameID: sess.GameID}
+	if m.locate != nil {
+		scope.LocationID = m.locate(ctx, sess.GameID)
+	}
+	contextMessage, retrieval := m.assembler.Retrieve(ctx, scope, text)
+
+	m.mu.Lock()
+	m.retrievals[sess.ChatID] = retrieval
+	m.mu.Unlock()
+
+	if retrieval.Err != nil {
+		log.Warn().
+			Err(retrieval.Err).
+			Int64("chat_id", sess.ChatID).
+			Msg("Context assembly failed, continuing without retrieved context")
+		return nil, nil
+	}
+	if contextMessage == nil {
+		return nil, nil
+	}
+	return contextMessage, retrieval.Texts()
+}
+
+// LastRetrieval returns what campaign memory returned for the last turn of a chat since the bot started, or nil
+func (m *Manager) LastRetrieval(chatID int64) *Retrieval {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.retrievals[chatID]
+}
+
+// stateContext collects the context sources of a turn. A failing source is skipped.
+func (m *Manager) stateContext(ctx context.Context, sess *Session, userID int64) []interfaces.Message {
+	var messages []interfaces.Message
+	for _, source := range m.sources {
+		text, err := source.TurnContext(ctx, sess.GameID, userID)
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Int64("chat_id", sess.ChatID).
+				Msg("Context source failed, continuing without it")
+			continue
+		}
+		if text != "" {
+			messages = append(messages, interfaces.Message{Role: "system", Content: text})
+		}
+	}
+	return messages
+}
+
+// buildMessages puts the system prompt first, then the history and the player message.
+// With prompt templates the game state goes into the rendered system prompt; otherwise
+// it is added as separate messages right before the player message.
+func (m *Manager) buildMessages(sess *Session, turn turnContext, userMessage interfaces.Message) []interfaces.Message {
+	messages := make([]interfaces.Message, 0, len(sess.History)+len(turn.state)+4)
+
+	if m.prompts != nil {
+		prompt, err := m.prompts.Render(sess.Setting, promptData(sess, turn))
+		if err == nil {
+			messages = append(messages, interfaces.Message{Role: "system", Content: prompt})
+			messages = append(messages, sess.History...)
+			return append(messages, userMessage)
+		}
+		log.Warn().
+			Err(err).
+			Int64("chat_id", sess.ChatID).
+			Str("setting", sess.Setting).
+			Msg("Prompt template failed, falling back to the built-in system prompt")
+	}
+
+	if m.config.SystemPrompt != "" {
+		messages = append(messages, interfaces.Message{Role: "system", Content: m.config.SystemPrompt})
+	}
+	if summary := summaryMessage(sess); summary != nil {
+		messages = append(messages, *summary)
+	}
+	messages = append(messages, sess.History...)
+	messages = append(messages, turn.state...)
+	if turn.retrieved != nil {
+		messages = append(messages, *turn.retrieved)
+	}
+	return append(messages, userMessage)
+}
+
+func (m *Manager) chatLock(chatID int64) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lock, ok := m.locks[chatID]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.locks[chatID] = lock
+	}
+	return lock
+}
+
+// withUsage reports the provider calls made with ctx to the recorder
+func withUsage(ctx context.Context, recorder UsageRecorder, chatID, userID int64, gameID string) context.Context {
+	if recorder == nil {
+		return ctx
+	}
+	return interfaces.WithUsageReporter(ctx, func(u interfaces.Usage) {
+		recorder.Record(ctx, chatID, userID, gameID, u)
+	})
+}
+
+// toolResultsMessage summarizes the tool calls of a turn
+func toolResultsMessage(calls []tools.CallRecord) interfaces.Message {
+	var b strings.Builder
+	b.WriteString("Результаты игровых механик этого хода:")
+	for _, call := range calls {
+		fmt.Fprintf(&b, "\n- %s(%s): %s", call.Name, call.Arguments, call.Result)
+	}
+	return interfaces.Message{Role: "system", Content: b.String()}
+}
+
+// trimHistory drops the oldest messages over MaxHistory. With a context window
+// the history is compacted by Fit instead, so nothing is dropped here.
+func (m *Manager) trimHistory(history []interfaces.Message) []interfaces.Message {
+	if m.window != nil {
+		return history
+	}
+	return trimHistory(history, m.config.MaxHistory)
+}
+
+func trimHistory(history []interfaces.Message, max int) []interfaces.Message {
+	if max <= 0 || len(history) <= max {
+		return history
+	}
+	return history[len(history)-max:]
+}
@@ -0,0 +1,377 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/tools"
+)
+
+// MockProvider records the messages it receives and answers with a numbered reply
+type MockProvider struct {
+	calls [][]interfaces.Message
+	err   error
+}
+
+func (m *MockProvider) GenerateResponse(ctx context.Context, messages []interfaces.Message, temperature float64, maxTokens int) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	m.calls = append(m.calls, append([]interfaces.Message(nil), messages...))
+	interfaces.ReportUsage(ctx, interfaces.Usage{Provider: "mock", Kind: interfaces.UsageChat, PromptTokens: len(messages), CompletionTokens: 2})
+	return fmt.Sprintf("reply %d", len(m.calls)), nil
+}
+
+func (m *MockProvider) Name() string {
+	return "mock"
+}
+
+func TestNewManager(t *testing.T) {
+	t.Run("nil provider", func(t *testing.T) {
+		_, err := NewManager(nil, NewMemoryStore(), nil)
+		if err == nil {
+			t.Error("expected error for nil provider")
+		}
+	})
+
+	t.Run("nil store", func(t *testing.T) {
+		_, err := NewManager(&MockProvider{}, nil, nil)
+		if err == nil {
+			t.Error("expected error for nil store")
+		}
+	})
+
+	t.Run("nil config uses defaults", func(t *testing.T) {
+		m, err := NewManager(&MockProvider{}, NewMemoryStore(), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if m.config.SystemPrompt != DefaultSystemPrompt {
+			t.Error("expected default system prompt")
+		}
+	})
+}
+
+func TestManager_Play(t *testing.T) {
+	t.Run("history is carried between turns", func(t *testing.T) {
+		provider := &MockProvider{}
+		m, _ := NewManager(provider, NewMemoryStore(), nil)
+		ctx := context.Background()
+
+		if _, err := m.Play(ctx, 1, 10, "I open the door"); err != nil {
+			t.Fatalf("first turn: %v", err)
+		}
+		if _, err := m.Play(ctx, 1, 10, "I step inside"); err != nil {
+			t.Fatalf("second turn: %v", err)
+		}
+
+		second := provider.calls[1]
+		if len(second) != 4 {
+			t.Fatalf("expected 4 messages in second turn, got %d", len(second))
+		}
+		if second[0].Role != "system" {
+			t.Errorf("expected system prompt first, got %q", second[0].Role)
+		}
+		if second[1].Content != "I open the door" || second[2].Content != "reply 1" {
+			t.Errorf("previous turn missing from context: %+v", second)
+		}
+		if second[3].Content != "I step inside" {
+			t.Errorf("expected current message last, got %q", second[3].Content)
+		}
+	})
+
+	t.Run("chats are isolated", func(t *testing.T) {
+		provider := &MockProvider{}
+		m, _ := NewManager(provider, NewMemoryStore(), nil)
+		ctx := context.Background()
+
+		_, _ = m.Play(ctx, 1, 10, "chat one")
+		_, _ = m.Play(ctx, 2, 20, "chat two")
+
+		if len(provider.calls[1]) != 2 {
+			t.Errorf("expected fresh context for second chat, got %d messages", len(provider.calls[1]))
+		}
+	})
+
+	t.Run("failed turn is not stored", func(t *testing.T) {
+		provider := &MockProvider{err: errors.New("boom")}
+		store := NewMemoryStore()
+		m, _ := NewManager(provider, store, nil)
+
+		if _, err := m.Play(context.Background(), 1, 10, "hello"); err == nil {
+			t.Fatal("expected error")
+		}
+
+		sess, err := store.Load(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("loading session: %v", err)
+		}
+		if len(sess.History) != 0 {
+			t.Errorf("expected empty history, got %d messages", len(sess.History))
+		}
+	})
+
+	t.Run("history is trimmed", func(t *testing.T) {
+		provider := &MockProvider{}
+		store := NewMemoryStore()
+		m, _ := NewManager(provider, store, &Config{MaxHistory: 4})
+		ctx := context.Background()
+
+		for i := 0; i < 5; i++ {
+			_, _ = m.Play(ctx, 1, 10, fmt.Sprintf("turn %d", i))
+		}
+
+		sess, _ := store.Load(ctx, 1)
+		if len(sess.History) != 4 {
+			t.Fatalf("expected 4 stored messages, got %d", len(sess.History))
+		}
+		if sess.History[0].Content != "turn 3" {
+			t.Errorf("expected oldest kept message 'turn 3', got %q", sess.History[0].Content)
+		}
+	})
+}
+
+// MockStreamingProvider streams its reply word by word
+type MockStreamingProvider struct {
+	MockProvider
+	reply string
+}
+
+func (m *MockStreamingProvider) GenerateResponseStream(ctx context.Context, messages []interfaces.Message, temperature float64, maxTokens int, onDelta func(delta string) error) (string, error) {
+	for _, word := range strings.SplitAfter(m.reply, " ") {
+		if err := onDelta(word); err != nil {
+			return "", err
+		}
+	}
+	return m.reply, nil
+}
+
+func TestManager_PlayStream(t *testing.T) {
+	t.Run("streams deltas", func(t *testing.T) {
+		provider := &MockStreamingProvider{reply: "the door creaks open"}
+		store := NewMemoryStore()
+		m, _ := NewManager(provider, store, nil)
+
+		var deltas []string
+		response, err := m.PlayStream(context.Background(), 1, 10, "open", func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(deltas) != 4 || strings.Join(deltas, "") != response {
+			t.Errorf("unexpected deltas: %q", deltas)
+		}
+
+		sess, _ := store.Load(context.Background(), 1)
+		if sess.History[1].Content != "the door creaks open" {
+			t.Errorf("streamed response not stored: %+v", sess.History)
+		}
+	})
+
+	t.Run("aborted stream is not stored", func(t *testing.T) {
+		provider := &MockStreamingProvider{reply: "the door creaks open"}
+		store := NewMemoryStore()
+		m, _ := NewManager(provider, store, nil)
+
+		_, err := m.PlayStream(context.Background(), 1, 10, "open", func(delta string) error {
+			return context.Canceled
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected cancellation error, got %v", err)
+		}
+
+		sess, _ := store.Load(context.Background(), 1)
+		if len(sess.History) != 0 {
+			t.Errorf("expected empty history, got %+v", sess.History)
+		}
+	})
+
+	t.Run("falls back to non-streaming providers", func(t *testing.T) {
+		provider := &MockProvider{}
+		m, _ := NewManager(provider, NewMemoryStore(), nil)
+
+		called := false
+		response, err := m.PlayStream(context.Background(), 1, 10, "open", func(delta string) error {
+			called = true
+			return nil
+		})
+		if err != nil || response != "reply 1" {
+			t.Errorf("unexpected result: %q, %v", response, err)
+		}
+		if called {
+			t.Error("onDelta must not be called for non-streaming providers")
+		}
+	})
+}
+
+// MockToolProvider requests a single tool call, then narrates
+type MockToolProvider struct {
+	MockProvider
+	rounds int
+}
+
+func (m *MockToolProvider) GenerateWithTools(ctx context.Context, messages []interfaces.Message, defs []interfaces.ToolDefinition, temperature float64, maxTokens int, onDelta func(delta string) error) (interfaces.Message, error) {
+	m.rounds++
+	if m.rounds == 1 {
+		return interfaces.Message{ToolCalls: []interfaces.ToolCall{{ID: "1", Name: "roll", Arguments: `{}`}}}, nil
+	}
+	return interfaces.Message{Role: "assistant", Content: "You rolled 4."}, nil
+}
+
+func TestManager_PlayWithTools(t *testing.T) {
+	provider := &MockToolProvider{}
+	store := NewMemoryStore()
+	m, _ := NewManager(provider, store, nil)
+
+	registry := tools.NewRegistry()
+	_ = registry.Register(tools.NewFunc(interfaces.ToolDefinition{Name: "roll"}, func(ctx context.Context, inv tools.Invocation, arguments json.RawMessage) (string, error) {
+		if inv.ChatID != 1 || inv.UserID != 10 || inv.GameID == "" {
+			t.Errorf("unexpected invocation: %+v", inv)
+		}
+		return "4", nil
+	}))
+	m.SetTools(registry)
+
+	response, err := m.Play(context.Background(), 1, 10, "I roll")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response != "You rolled 4." {
+		t.Errorf("unexpected response: %q", response)
+	}
+
+	sess, _ := store.Load(context.Background(), 1)
+	if len(sess.History) != 3 {
+		t.Fatalf("expected player message, tool note and narration, got %+v", sess.History)
+	}
+	if sess.History[1].Role != "system" || !strings.Contains(sess.History[1].Content, "roll({}): 4") {
+		t.Errorf("expected tool results note, got %+v", sess.History[1])
+	}
+}
+
+func TestManager_AppendNote(t *testing.T) {
+	provider := &MockProvider{}
+	m, _ := NewManager(provider, NewMemoryStore(), nil)
+	ctx := context.Background()
+
+	if err := m.AppendNote(ctx, 1, "Бросок 1d20: [17] = 17"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.Play(ctx, 1, 10, "I attack"); err != nil {
+		t.Fatalf("play: %v", err)
+	}
+
+	messages := provider.calls[0]
+	if len(messages) != 3 || messages[1].Role != "system" || messages[1].Content != "Бросок 1d20: [17] = 17" {
+		t.Errorf("expected the note before the player message, got %+v", messages)
+	}
+}
+
+// MockSource returns fixed turn context and records who asked for it
+type MockSource struct {
+	text   string
+	err    error
+	userID int64
+}
+
+func (m *MockSource) TurnContext(ctx context.Context, gameID string, userID int64) (string, error) {
+	m.userID = userID
+	return m.text, m.err
+}
+
+func TestManager_ContextSources(t *testing.T) {
+	provider := &MockProvider{}
+	m, _ := NewManager(provider, NewMemoryStore(), nil)
+	sheet := &MockSource{text: "Лист персонажа"}
+	m.AddContextSource(&MockSource{err: errors.New("boom")})
+	m.AddContextSource(&MockSource{})
+	m.AddContextSource(sheet)
+
+	if _, err := m.Play(context.Background(), 1, 10, "I look around"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := provider.calls[0]
+	if len(messages) != 3 || messages[1].Content != "Лист персонажа" {
+		t.Errorf("expected only the non-empty source before the player message, got %+v", messages)
+	}
+	if sheet.userID != 10 {
+		t.Errorf("expected the acting player, got %d", sheet.userID)
+	}
+}
+
+// MockUsageRecorder keeps the usage it is given
+type MockUsageRecorder struct {
+	mu      sync.Mutex
+	records []usageRecord
+}
+
+type usageRecord struct {
+	chatID, userID int64
+	gameID         string
+	usage          interfaces.Usage
+}
+
+func (m *MockUsageRecorder) Record(ctx context.Context, chatID, userID int64, gameID string, u interfaces.Usage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, usageRecord{chatID: chatID, userID: userID, gameID: gameID, usage: u})
+}
+
+func TestManager_UsageRecorder(t *testing.T) {
+	recorder := &MockUsageRecorder{}
+	m, _ := NewManager(&MockProvider{}, NewMemoryStore(), nil)
+	m.SetUsageRecorder(recorder)
+
+	if _, err := m.Play(context.Background(), 1, 10, "I look around"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gameID, _ := m.GameID(context.Background(), 1)
+
+	if len(recorder.records) != 1 {
+		t.Fatalf("expected one recorded call, got %+v", recorder.records)
+	}
+	r := recorder.records[0]
+	if r.chatID != 1 || r.userID != 10 || r.gameID != gameID {
+		t.Errorf("expected usage attributed to the turn, got %+v", r)
+	}
+	if r.usage.PromptTokens != 2 || r.usage.CompletionTokens != 2 {
+		t.Errorf("unexpected usage %+v", r.usage)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	if _, err := store.Load(ctx, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	sess, err := store.Create(ctx, 1, "test")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if len(sess.GameID) != 36 {
+		t.Errorf("expected UUID game id, got %q", sess.GameID)
+	}
+
+	sess.History = append(sess.History, interfaces.Message{Role: "user", Content: "hi"})
+	if err := store.Save(ctx, sess); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	sess.History[0].Content = "mutated"
+	loaded, _ := store.Load(ctx, 1)
+	if loaded.History[0].Content != "hi" {
+		t.Errorf("store must keep its own copy, got %q", loaded.History[0].Content)
+	}
+}
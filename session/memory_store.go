@@ -0,0 +1,70 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/internal/uuid"
+)
+
+// MemoryStore keeps sessions in process memory. Campaigns are lost on restart.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	sessions map[int64]*Session
+}
+
+// Compile-time interface check
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty in-memory session store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions: make(map[int64]*Session),
+	}
+}
+
+// Load returns a copy of the stored session
+func (s *MemoryStore) Load(ctx context.Context, chatID int64) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, ok := s.sessions[chatID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return cloneSession(stored), nil
+}
+
+// Create starts a new session for a chat, replacing any existing one
+func (s *MemoryStore) Create(ctx context.Context, chatID int64, name string) (*Session, error) {
+	sess := &Session{
+		GameID:    uuid.New(),
+		ChatID:    chatID,
+		Name:      name,
+		UpdatedAt: time.Now(),
+	}
+
+	s.mu.Lock()
+	s.sessions[chatID] = cloneSession(sess)
+	s.mu.Unlock()
+
+	return sess, nil
+}
+
+// Save stores a copy of the session
+func (s *MemoryStore) Save(ctx context.Context, sess *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess.UpdatedAt = time.Now()
+	s.sessions[sess.ChatID] = cloneSession(sess)
+	return nil
+}
+
+func cloneSession(s *Session) *Session {
+	clone := *s
+	clone.History = append([]interfaces.Message(nil), s.History...)
+	return &clone
+}
@@ -0,0 +1,264 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// Metadata keys written with every turn document. They map onto context_items columns.
+const (
+	MetadataGameID      = "game_id"
+	MetadataUserID      = "user_id"
+	MetadataChatID      = "chat_id"
+	MetadataCharacterID = "character_id"
+	MetadataLocationID  = "location_id"
+	MetadataQuestID     = "quest_id"
+	MetadataType        = "type"
+)
+
+// DocumentTypeTurn marks documents that store a resolved game turn
+const DocumentTypeTurn = "turn"
+
+// DocumentWriter stores documents in campaign memory
+type DocumentWriter interface {
+	AddDocuments(ctx context.Context, docs []interfaces.Document) error
+}
+
+// TagFunc returns optional tags of a turn, keyed by MetadataCharacterID, MetadataLocationID or MetadataQuestID
+type TagFunc func(ctx context.Context, gameID string, userID int64) map[string]string
+
+// TurnRecord is a resolved turn waiting to be written to campaign memory
+type TurnRecord struct {
+	GameID  string
+	ChatID  int64
+	UserID  int64
+	Action  string
+	Outcome string
+	Tags    map[string]string
+}
+
+// Document converts the turn into a retriever document
+func (r TurnRecord) Document() interfaces.Document {
+	metadata := map[string]interface{}{
+		MetadataGameID: r.GameID,
+		MetadataUserID: r.UserID,
+		MetadataChatID: r.ChatID,
+		MetadataType:   DocumentTypeTurn,
+	}
+	for key, value := range r.Tags {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
+
+	return interfaces.Document{
+		PageContent: fmt.Sprintf("Игрок: %s\nВедущий: %s", r.Action, r.Outcome),
+		Metadata:    metadata,
+	}
+}
+
+// MemoryWriterConfig contains settings for background memory writes
+type MemoryWriterConfig struct {
+	QueueSize  int
+	Timeout    time.Duration // Per attempt
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// DefaultMemoryWriterConfig returns the default memory writer settings
+func DefaultMemoryWriterConfig() *MemoryWriterConfig {
+	return &MemoryWriterConfig{
+		QueueSize:  100,
+		Timeout:    30 * time.Second,
+		MaxRetries: 3,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   10 * time.Second,
+	}
+}
+
+// MemoryWriter stores resolved turns in the retriever without delaying replies
+type MemoryWriter struct {
+	writer DocumentWriter
+	config *MemoryWriterConfig
+
+	mu      sync.RWMutex
+	taggers []TagFunc
+	usage   UsageRecorder
+	closed  bool
+
+	queue chan TurnRecord
+	done  chan struct{}
+
+	// shutdown is cancelled by Close, so retries stop waiting and the queue drains promptly
+	shutdown context.Context
+	stop     context.CancelFunc
+}
+
+// NewMemoryWriter creates a memory writer and starts its background worker
+func NewMemoryWriter(writer DocumentWriter, config *MemoryWriterConfig) (*MemoryWriter, error) {
+	if writer == nil {
+		return nil, fmt.Errorf("document writer cannot be nil")
+	}
+	if config == nil {
+		config = DefaultMemoryWriterConfig()
+	}
+
+	w := &MemoryWriter{
+		writer: writer,
+		config: config,
+		queue:  make(chan TurnRecord, config.QueueSize),
+		done:   make(chan struct{}),
+	}
+	w.shutdown, w.stop = context.WithCancel(context.Background())
+	go w.run()
+
+	return w, nil
+}
+
+// AddTagger registers a source of optional turn tags
+func (w *MemoryWriter) AddTagger(tagger TagFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.taggers = append(w.taggers, tagger)
+}
+
+// SetUsageRecorder accounts the embedding tokens of written turns to their game and player
+func (w *MemoryWriter) SetUsageRecorder(recorder UsageRecorder) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.usage = recorder
+}
+
+// Write queues a turn. It never blocks; the turn is dropped when the queue is full.
+func (w *MemoryWriter) Write(record TurnRecord) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		log.Warn().Str("game_id", record.GameID).Msg("Memory writer closed, turn dropped")
+		return
+	}
+
+	select {
+	case w.queue <- record:
+	default:
+		log.Error().
+			Str("game_id", record.GameID).
+			Int64("chat_id", record.ChatID).
+			Msg("Memory write queue is full, turn dropped")
+	}
+}
+
+// Close stops accepting turns and waits until queued turns are written.
+// Failed writes are no longer retried, so shutdown does not wait for backoff delays.
+func (w *MemoryWriter) Close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.queue)
+	w.stop()
+	w.mu.Unlock()
+
+	<-w.done
+}
+
+func (w *MemoryWriter) run() {
+	defer close(w.done)
+
+	for record := range w.queue {
+		start := time.Now()
+		if err := w.store(record); err != nil {
+			log.Error().
+				Err(err).
+				Str("game_id", record.GameID).
+				Int64("chat_id", record.ChatID).
+				Int64("user_id", record.UserID).
+				Msg("Failed to write turn to campaign memory")
+			continue
+		}
+
+		log.Debug().
+			Str("game_id", record.GameID).
+			Int64("chat_id", record.ChatID).
+			Dur("write_duration", time.Since(start)).
+			Msg("Turn written to campaign memory")
+	}
+}
+
+// tag fills the turn tags from the registered taggers
+func (w *MemoryWriter) tag(record *TurnRecord) {
+	w.mu.RLock()
+	taggers := w.taggers
+	w.mu.RUnlock()
+
+	if len(taggers) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.config.Timeout)
+	defer cancel()
+
+	if record.Tags == nil {
+		record.Tags = make(map[string]string)
+	}
+	for _, tagger := range taggers {
+		for key, value := range tagger(ctx, record.GameID, record.UserID) {
+			record.Tags[key] = value
+		}
+	}
+}
+
+// store writes a turn, retrying with exponential backoff
+func (w *MemoryWriter) store(record TurnRecord) error {
+	w.tag(&record)
+	docs := []interfaces.Document{record.Document()}
+
+	w.mu.RLock()
+	recorder := w.usage
+	w.mu.RUnlock()
+
+	var lastErr error
+	for attempt := 0; attempt <= w.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			delay := w.config.BaseDelay * time.Duration(1<<uint(attempt-1))
+			if delay > w.config.MaxDelay {
+				delay = w.config.MaxDelay
+			}
+
+			log.Warn().
+				Int("attempt", attempt).
+				Dur("delay", delay).
+				Err(lastErr).
+				Msg("Retrying memory write")
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-w.shutdown.Done():
+				timer.Stop()
+				return fmt.Errorf("retry abandoned on shutdown: %w", lastErr)
+			case <-timer.C:
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), w.config.Timeout)
+		ctx = withUsage(ctx, recorder, record.ChatID, record.UserID, record.GameID)
+		lastErr = w.writer.AddDocuments(ctx, docs)
+		cancel()
+
+		if lastErr == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("max retries (%d) exceeded: %w", w.config.MaxRetries, lastErr)
+}
@@ -0,0 +1,203 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// MockWriter stores documents and fails the first failures calls. A non-nil block holds writes until it is closed.
+type MockWriter struct {
+	block    chan struct{}
+	mu       sync.Mutex
+	docs     []interfaces.Document
+	failures int
+	calls    int
+	deleted  []string
+}
+
+func (m *MockWriter) AddDocuments(ctx context.Context, docs []interfaces.Document) error {
+	if m.block != nil {
+		<-m.block
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls++
+	if m.calls <= m.failures {
+		return errors.New("connection refused")
+	}
+	m.docs = append(m.docs, docs...)
+	return nil
+}
+
+func (m *MockWriter) DeleteDocuments(ctx context.Context, ids []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleted = append(m.deleted, ids...)
+	return nil
+}
+
+func testMemoryWriterConfig() *MemoryWriterConfig {
+	return &MemoryWriterConfig{
+		QueueSize:  10,
+		Timeout:    time.Second,
+		MaxRetries: 2,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   5 * time.Millisecond,
+	}
+}
+
+func TestNewMemoryWriter(t *testing.T) {
+	if _, err := NewMemoryWriter(nil, nil); err == nil {
+		t.Error("expected error for nil writer")
+	}
+}
+
+func TestTurnRecord_Document(t *testing.T) {
+	doc := TurnRecord{
+		GameID:  "game",
+		ChatID:  1,
+		UserID:  10,
+		Action:  "I attack",
+		Outcome: "The goblin falls",
+		Tags:    map[string]string{MetadataLocationID: "loc", MetadataQuestID: ""},
+	}.Document()
+
+	if !strings.Contains(doc.PageContent, "I attack") || !strings.Contains(doc.PageContent, "The goblin falls") {
+		t.Errorf("turn content missing: %q", doc.PageContent)
+	}
+	if doc.Metadata[MetadataGameID] != "game" || doc.Metadata[MetadataUserID] != int64(10) {
+		t.Errorf("unexpected tenancy metadata: %v", doc.Metadata)
+	}
+	if doc.Metadata[MetadataLocationID] != "loc" {
+		t.Errorf("expected location tag, got %v", doc.Metadata[MetadataLocationID])
+	}
+	if _, ok := doc.Metadata[MetadataQuestID]; ok {
+		t.Error("empty tags must be omitted")
+	}
+}
+
+func TestMemoryWriter(t *testing.T) {
+	t.Run("retries failed writes", func(t *testing.T) {
+		writer := &MockWriter{failures: 2}
+		w, _ := NewMemoryWriter(writer, testMemoryWriterConfig())
+
+		w.Write(TurnRecord{GameID: "game", Action: "a", Outcome: "o"})
+		waitForCalls(t, writer, 3)
+		w.Close()
+
+		if len(writer.docs) != 1 {
+			t.Errorf("expected document written after retries, got %d", len(writer.docs))
+		}
+		if writer.calls != 3 {
+			t.Errorf("expected 3 attempts, got %d", writer.calls)
+		}
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		writer := &MockWriter{failures: 10}
+		w, _ := NewMemoryWriter(writer, testMemoryWriterConfig())
+
+		w.Write(TurnRecord{GameID: "game"})
+		waitForCalls(t, writer, 3)
+		w.Close()
+
+		if writer.calls != 3 {
+			t.Errorf("expected 3 attempts, got %d", writer.calls)
+		}
+	})
+
+	t.Run("close does not wait for backoff", func(t *testing.T) {
+		writer := &MockWriter{failures: 10}
+		config := testMemoryWriterConfig()
+		config.BaseDelay = time.Minute
+		config.MaxDelay = time.Minute
+		w, _ := NewMemoryWriter(writer, config)
+
+		w.Write(TurnRecord{GameID: "game"})
+		w.Write(TurnRecord{GameID: "game"})
+		waitForCalls(t, writer, 1)
+
+		start := time.Now()
+		w.Close()
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("expected close without waiting for the backoff, took %s", elapsed)
+		}
+		if writer.calls != 2 {
+			t.Errorf("expected one attempt per queued turn, got %d", writer.calls)
+		}
+	})
+
+	t.Run("applies taggers", func(t *testing.T) {
+		writer := &MockWriter{}
+		w, _ := NewMemoryWriter(writer, testMemoryWriterConfig())
+		w.AddTagger(func(ctx context.Context, gameID string, userID int64) map[string]string {
+			return map[string]string{MetadataCharacterID: "hero"}
+		})
+
+		w.Write(TurnRecord{GameID: "game"})
+		w.Close()
+
+		if writer.docs[0].Metadata[MetadataCharacterID] != "hero" {
+			t.Errorf("expected character tag, got %v", writer.docs[0].Metadata)
+		}
+	})
+
+	t.Run("write after close is dropped", func(t *testing.T) {
+		writer := &MockWriter{}
+		w, _ := NewMemoryWriter(writer, testMemoryWriterConfig())
+		w.Close()
+		w.Close()
+
+		w.Write(TurnRecord{GameID: "game"})
+		if writer.calls != 0 {
+			t.Errorf("expected no writes, got %d", writer.calls)
+		}
+	})
+}
+
+// waitForCalls waits until the writer received at least n calls
+func waitForCalls(t *testing.T, writer *MockWriter, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		writer.mu.Lock()
+		calls := writer.calls
+		writer.mu.Unlock()
+		if calls >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d writer calls", n)
+}
+
+func TestManager_PlayWritesMemory(t *testing.T) {
+	writer := &MockWriter{}
+	w, _ := NewMemoryWriter(writer, testMemoryWriterConfig())
+	m, _ := NewManager(&MockProvider{}, NewMemoryStore(), nil)
+	m.SetMemoryWriter(w)
+
+	if _, err := m.Play(context.Background(), 1, 10, "I search the room"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.Close()
+
+	if len(writer.docs) != 1 {
+		t.Fatalf("expected 1 document, got %d", len(writer.docs))
+	}
+	doc := writer.docs[0]
+	if doc.Metadata[MetadataChatID] != int64(1) || doc.Metadata[MetadataUserID] != int64(10) {
+		t.Errorf("unexpected metadata: %v", doc.Metadata)
+	}
+	if !strings.Contains(doc.PageContent, "reply 1") {
+		t.Errorf("expected narration in document, got %q", doc.PageContent)
+	}
+}
@@ -0,0 +1,105 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// PostgresStore persists sessions in the games table
+type PostgresStore struct {
+	db *pgxpool.Pool
+}
+
+// Compile-time interface check
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore creates a session store backed by PostgreSQL
+func NewPostgresStore(db *pgxpool.Pool) (*PostgresStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database pool cannot be nil")
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// Load reads the game bound to a chat
+func (s *PostgresStore) Load(ctx context.Context, chatID int64) (*Session, error) {
+	sess := &Session{ChatID: chatID}
+	var history []byte
+
+	err := s.db.QueryRow(ctx, `
+		SELECT id, name, history, summary, summary_id, setting, updated_at
+		FROM games
+		WHERE chat_id = $1
+	`, chatID).Scan(&sess.GameID, &sess.Name, &history, &sess.Summary, &sess.SummaryID, &sess.Setting, &sess.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading session: %w", err)
+	}
+
+	if err := json.Unmarshal(history, &sess.History); err != nil {
+		return nil, fmt.Errorf("decoding session history: %w", err)
+	}
+
+	return sess, nil
+}
+
+// Create inserts a new game for a chat. An existing game of the chat is unbound, not deleted.
+func (s *PostgresStore) Create(ctx context.Context, chatID int64, name string) (*Session, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `UPDATE games SET chat_id = NULL WHERE chat_id = $1`, chatID); err != nil {
+		return nil, fmt.Errorf("unbinding previous game: %w", err)
+	}
+
+	sess := &Session{ChatID: chatID, Name: name}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO games (name, chat_id, history)
+		VALUES ($1, $2, '[]'::jsonb)
+		RETURNING id, updated_at
+	`, name, chatID).Scan(&sess.GameID, &sess.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("creating game: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing game: %w", err)
+	}
+
+	log.Info().
+		Int64("chat_id", chatID).
+		Str("game_id", sess.GameID).
+		Msg("Game created")
+
+	return sess, nil
+}
+
+// Save writes the session history, summary and setting
+func (s *PostgresStore) Save(ctx context.Context, sess *Session) error {
+	history, err := json.Marshal(sess.History)
+	if err != nil {
+		return fmt.Errorf("encoding session history: %w", err)
+	}
+
+	err = s.db.QueryRow(ctx, `
+		UPDATE games
+		SET history = $1, summary = $2, summary_id = $3, setting = $4, updated_at = NOW()
+		WHERE id = $5
+		RETURNING updated_at
+	`, history, sess.Summary, sess.SummaryID, sess.Setting, sess.GameID).Scan(&sess.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("saving session: %w", err)
+	}
+	return nil
+}
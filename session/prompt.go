@@ -0,0 +1,70 @@
+package session
+
+import (
+	"context"
+	"fmt"
+
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/prompts"
+)
+
+// PromptRenderer renders the system prompt of a turn from the setting preset of a game
+type PromptRenderer interface {
+	Render(setting string, data prompts.Data) (string, error)
+}
+
+// turnContext is the game state gathered for a turn
+type turnContext struct {
+	state     []interfaces.Message // context sources, such as the character sheet
+	retrieved *interfaces.Message  // formatted campaign memory, nil when nothing was found
+	documents []string             // texts of the retrieved documents, truncated to the context budget
+}
+
+// SetPrompts renders system prompts from templates instead of Config.SystemPrompt. It must be called before the bot starts.
+func (m *Manager) SetPrompts(renderer PromptRenderer) {
+	m.prompts = renderer
+}
+
+// Setting returns the setting preset of the chat's game, empty for the default
+func (m *Manager) Setting(ctx context.Context, chatID int64) (string, error) {
+	lock := m.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	sess, err := m.session(ctx, chatID)
+	if err != nil {
+		return "", err
+	}
+	return sess.Setting, nil
+}
+
+// SetSetting chooses the setting preset of the chat's game
+func (m *Manager) SetSetting(ctx context.Context, chatID int64, setting string) error {
+	lock := m.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	sess, err := m.session(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	sess.Setting = setting
+	if err := m.store.Save(ctx, sess); err != nil {
+		return fmt.Errorf("saving session: %w", err)
+	}
+	return nil
+}
+
+func promptData(sess *Session, turn turnContext) prompts.Data {
+	data := prompts.Data{
+		Setting: sess.Setting,
+		Game:    sess.Name,
+		Summary: sess.Summary,
+	}
+	for _, msg := range turn.state {
+		data.State = append(data.State, msg.Content)
+	}
+	data.Documents = turn.documents
+	return data
+}
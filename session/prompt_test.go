@@ -0,0 +1,97 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"go-llm-rpggamemaster/interfaces"
+	"go-llm-rpggamemaster/prompts"
+)
+
+// MockRenderer renders a fixed prompt and records the data it is given
+type MockRenderer struct {
+	setting string
+	data    prompts.Data
+	err     error
+}
+
+func (m *MockRenderer) Render(setting string, data prompts.Data) (string, error) {
+	m.setting = setting
+	m.data = data
+	if m.err != nil {
+		return "", m.err
+	}
+	return "rendered " + setting, nil
+}
+
+func TestManager_PlayWithPrompts(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("rendered prompt carries the game state", func(t *testing.T) {
+		provider := &MockProvider{}
+		renderer := &MockRenderer{}
+		m, _ := NewManager(provider, NewMemoryStore(), DefaultConfig())
+		m.SetPrompts(renderer)
+		m.AddContextSource(&MockSource{text: "Лист персонажа"})
+		assembler, _ := NewAssembler(&MockRetriever{docs: []interfaces.Document{{PageContent: "Дракон спит на золоте"}}}, nil)
+		m.SetAssembler(assembler)
+
+		if err := m.SetSetting(ctx, 1, "horror"); err != nil {
+			t.Fatalf("set setting: %v", err)
+		}
+		if _, err := m.Play(ctx, 1, 10, "I look around"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		messages := provider.calls[0]
+		if len(messages) != 2 || messages[0].Content != "rendered horror" {
+			t.Errorf("expected the rendered prompt and the player message only, got %+v", messages)
+		}
+		if renderer.setting != "horror" || len(renderer.data.State) != 1 || renderer.data.State[0] != "Лист персонажа" {
+			t.Errorf("unexpected render input %q %+v", renderer.setting, renderer.data)
+		}
+		if len(renderer.data.Documents) != 1 || renderer.data.Documents[0] != "Дракон спит на золоте" {
+			t.Errorf("expected the retrieved documents, got %+v", renderer.data.Documents)
+		}
+		if setting, _ := m.Setting(ctx, 1); setting != "horror" {
+			t.Errorf("expected the stored setting, got %q", setting)
+		}
+	})
+
+	t.Run("documents are truncated to the context budget", func(t *testing.T) {
+		renderer := &MockRenderer{}
+		m, _ := NewManager(&MockProvider{}, NewMemoryStore(), DefaultConfig())
+		m.SetPrompts(renderer)
+		long := strings.Repeat("золото ", 100)
+		config := DefaultAssemblerConfig()
+		config.MaxChars = 200
+		assembler, _ := NewAssembler(&MockRetriever{docs: []interfaces.Document{{PageContent: long}}}, config)
+		m.SetAssembler(assembler)
+
+		if _, err := m.Play(ctx, 1, 10, "I look around"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(renderer.data.Documents) != 1 {
+			t.Fatalf("expected the retrieved document, got %+v", renderer.data.Documents)
+		}
+		if text := renderer.data.Documents[0]; utf8.RuneCountInString(text) > config.MaxChars || !strings.HasSuffix(text, "...") {
+			t.Errorf("expected the document truncated to %d characters, got %d", config.MaxChars, utf8.RuneCountInString(text))
+		}
+	})
+
+	t.Run("failed template falls back to the built-in prompt", func(t *testing.T) {
+		provider := &MockProvider{}
+		m, _ := NewManager(provider, NewMemoryStore(), DefaultConfig())
+		m.SetPrompts(&MockRenderer{err: errors.New("broken template")})
+
+		if _, err := m.Play(ctx, 1, 10, "I look around"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if messages := provider.calls[0]; messages[0].Content != DefaultSystemPrompt {
+			t.Errorf("expected the built-in prompt, got %+v", messages[0])
+		}
+	})
+}
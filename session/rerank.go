@@ -0,0 +1,109 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// RerankSource is the score source of reranked documents in AssemblerConfig.MinScores
+const RerankSource = "rerank"
+
+// Reranker rescores retrieval candidates by their relevance to the query.
+// It returns one score per document in [0, 1], higher is better.
+type Reranker interface {
+	Name() string
+	Rerank(ctx context.Context, query string, docs []interfaces.ScoredDocument) ([]float64, error)
+}
+
+// SetReranker adds a reranking stage after retrieval. It must be called before the bot starts.
+func (a *Assembler) SetReranker(reranker Reranker) {
+	a.reranker = reranker
+}
+
+// rerank orders the top candidates of a retrieval by the reranker scores, which replace the retrieval
+// scores. Candidates past RerankTopN are dropped. On failure the retrieval order is kept.
+func (a *Assembler) rerank(ctx context.Context, retrieval *Retrieval) {
+	if a.reranker == nil || len(retrieval.Documents) == 0 {
+		return
+	}
+	if a.config.RerankTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.config.RerankTimeout)
+		defer cancel()
+	}
+
+	candidates := retrieval.Documents
+	if a.config.RerankTopN > 0 && len(candidates) > a.config.RerankTopN {
+		candidates = candidates[:a.config.RerankTopN]
+	}
+	docs := make([]interfaces.ScoredDocument, len(candidates))
+	for i, candidate := range candidates {
+		docs[i] = candidate.ScoredDocument
+	}
+
+	start := time.Now()
+	scores, err := a.reranker.Rerank(ctx, retrieval.Query, docs)
+	if err == nil && len(scores) != len(docs) {
+		err = fmt.Errorf("expected %d scores, got %d", len(docs), len(scores))
+	}
+	duration := time.Since(start)
+	if err != nil {
+		retrieval.RerankErr = err
+		log.Warn().
+			Err(err).
+			Str("reranker", a.reranker.Name()).
+			Dur("rerank_duration", duration).
+			Msg("Reranking failed, keeping the retrieval order")
+		return
+	}
+
+	reranked := make([]RetrievedDocument, len(candidates))
+	for i, candidate := range candidates {
+		candidate.Score = scores[i]
+		candidate.RetrievalRank = i + 1
+		reranked[i] = candidate
+	}
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return reranked[i].Score > reranked[j].Score
+	})
+
+	moved, shift := rankChanges(reranked)
+	log.Info().
+		Str("reranker", a.reranker.Name()).
+		Dur("rerank_duration", duration).
+		Int("candidates", len(candidates)).
+		Int("dropped", len(retrieval.Documents)-len(candidates)).
+		Int("moved", moved).
+		Float64("mean_rank_shift", shift).
+		Bool("top_changed", reranked[0].RetrievalRank != 1).
+		Msg("Retrieval reranked")
+
+	retrieval.Documents = reranked
+	retrieval.Reranker = a.reranker.Name()
+	retrieval.Scored = true
+}
+
+// rankChanges counts the documents that changed position and their mean absolute shift
+func rankChanges(docs []RetrievedDocument) (int, float64) {
+	if len(docs) == 0 {
+		return 0, 0
+	}
+	moved, total := 0, 0
+	for i, doc := range docs {
+		shift := doc.RetrievalRank - (i + 1)
+		if shift < 0 {
+			shift = -shift
+		}
+		if shift > 0 {
+			moved++
+			total += shift
+		}
+	}
+	return moved, float64(total) / float64(len(docs))
+}
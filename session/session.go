@@ -0,0 +1,37 @@
+// Package session keeps the running game-master conversation for each Telegram chat.
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// ErrNotFound is returned by a Store when a chat has no session yet
+var ErrNotFound = errors.New("session not found")
+
+// Session is a campaign bound to a single Telegram chat
+type Session struct {
+	GameID    string
+	ChatID    int64
+	Name      string
+	History   []interfaces.Message
+	Summary   string // Story so far of the turns compacted out of History
+	SummaryID string // Campaign memory document that stores Summary, empty when it is not stored
+	Setting   string // Prompt preset of the game, empty for the default
+	UpdatedAt time.Time
+}
+
+// Store persists sessions so that a bot restart does not wipe a campaign
+type Store interface {
+	// Load returns the session of a chat or ErrNotFound
+	Load(ctx context.Context, chatID int64) (*Session, error)
+
+	// Create starts a new game for a chat and assigns its GameID
+	Create(ctx context.Context, chatID int64, name string) (*Session, error)
+
+	// Save stores the session history, summary and setting
+	Save(ctx context.Context, s *Session) error
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go-llm-rpggamemaster/config"
+	"go-llm-rpggamemaster/prompts"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+)
+
+const settingUsage = `Использование:
+/setting — текущий сеттинг и список доступных
+/setting <название> — выбрать сеттинг игры (администраторы чата)
+/setting reload — перечитать шаблоны (администраторы бота)`
+
+// newPromptLibrary loads the prompt templates. The bot can run without them on the built-in prompt.
+func newPromptLibrary(cfg config.PromptsConfig) (*prompts.Library, error) {
+	dir := cfg.Dir
+	if dir == "" {
+		dir = "templates"
+	}
+	preset := cfg.Default
+	if preset == "" {
+		preset = "fantasy"
+	}
+	return prompts.Load(dir, preset)
+}
+
+// settingHandler shows or changes the prompt preset of the chat's game
+func settingHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+	userID := update.Message.From.ID
+
+	if promptLibrary == nil {
+		reply(ctx, b, chatID, "Шаблоны промптов не загружены, сеттинги недоступны")
+		return
+	}
+
+	args := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/setting"))
+	switch {
+	case args == "":
+		showSetting(ctx, b, chatID)
+	case args == "reload":
+		if !botAdmins[userID] {
+			reply(ctx, b, chatID, "Перечитывать шаблоны могут только администраторы бота")
+			return
+		}
+		if err := promptLibrary.Reload(); err != nil {
+			log.Err(err).Int64("user_id", userID).Msg("failed to reload prompt templates")
+			reply(ctx, b, chatID, "Шаблоны не перечитаны, остались прежние: "+err.Error())
+			return
+		}
+		reply(ctx, b, chatID, fmt.Sprintf("Шаблоны перечитаны, сеттингов: %d", len(promptLibrary.Presets())))
+	default:
+		if !isChatAdmin(ctx, b, update.Message.Chat, userID) {
+			reply(ctx, b, chatID, "Сеттинг игры могут менять только администраторы чата")
+			return
+		}
+		preset := strings.ToLower(args)
+		if !promptLibrary.Has(preset) {
+			reply(ctx, b, chatID, fmt.Sprintf("Сеттинг %q не найден.\n\n%s", args, settingUsage))
+			return
+		}
+		if err := sessions.SetSetting(ctx, chatID, preset); err != nil {
+			log.Err(err).Int64("chat_id", chatID).Msg("failed to change setting")
+			reply(ctx, b, chatID, "Не удалось сменить сеттинг, попробуйте позже")
+			return
+		}
+		reply(ctx, b, chatID, "🎭 Сеттинг игры: "+promptLibrary.Title(preset))
+	}
+}
+
+// showSetting lists the presets and marks the one of the chat's game
+func showSetting(ctx context.Context, b *bot.Bot, chatID int64) {
+	current, err := sessions.Setting(ctx, chatID)
+	if err != nil {
+		log.Err(err).Int64("chat_id", chatID).Msg("failed to load setting")
+		reply(ctx, b, chatID, "Не удалось загрузить игру, попробуйте позже")
+		return
+	}
+	if !promptLibrary.Has(current) {
+		current = promptLibrary.Default()
+	}
+
+	var text strings.Builder
+	text.WriteString("🎭 Сеттинги:")
+	for _, preset := range promptLibrary.Presets() {
+		mark := "•"
+		if preset == current {
+			mark = "▶"
+		}
+		fmt.Fprintf(&text, "\n%s %s — %s", mark, preset, promptLibrary.Title(preset))
+	}
+	text.WriteString("\n\n" + settingUsage)
+	reply(ctx, b, chatID, text.String())
+}
+
+// isChatAdmin reports whether a user administers the chat. Everyone administers their private chat,
+// and bot admins administer every chat.
+func isChatAdmin(ctx context.Context, b *bot.Bot, chat models.Chat, userID int64) bool {
+	if chat.Type == models.ChatTypePrivate || botAdmins[userID] {
+		return true
+	}
+	member, err := b.GetChatMember(ctx, &bot.GetChatMemberParams{ChatID: chat.ID, UserID: userID})
+	if err != nil {
+		log.Err(err).Int64("chat_id", chat.ID).Int64("user_id", userID).Msg("failed to check chat admin")
+		return false
+	}
+	return member.Type == models.ChatMemberTypeOwner || member.Type == models.ChatMemberTypeAdministrator
+}
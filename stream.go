@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-telegram/bot"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	streamPlaceholder    = "✍️ Ведущий обдумывает ответ..."
+	streamEditInterval   = 1500 * time.Millisecond
+	telegramMessageLimit = 4096
+)
+
+// messageStreamer shows a streamed narration by progressively editing a placeholder message.
+// Edits are throttled to stay within Telegram rate limits.
+type messageStreamer struct {
+	b         *bot.Bot
+	chatID    int64
+	messageID int
+
+	text     strings.Builder
+	shown    string
+	lastEdit time.Time
+}
+
+// newMessageStreamer sends the placeholder message that will receive the narration
+func newMessageStreamer(ctx context.Context, b *bot.Bot, chatID int64) (*messageStreamer, error) {
+	msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   streamPlaceholder,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sending placeholder: %w", err)
+	}
+
+	return &messageStreamer{
+		b:         b,
+		chatID:    chatID,
+		messageID: msg.ID,
+		lastEdit:  time.Now(),
+	}, nil
+}
+
+// OnDelta returns a delta callback that edits the placeholder at most once per streamEditInterval
+func (s *messageStreamer) OnDelta(ctx context.Context) func(delta string) error {
+	return func(delta string) error {
+		s.text.WriteString(delta)
+		if time.Since(s.lastEdit) < streamEditInterval {
+			return nil
+		}
+
+		text := s.text.String()
+		if utf8.RuneCountInString(text) >= telegramMessageLimit {
+			text = string([]rune(text)[:telegramMessageLimit-1]) + "…"
+		}
+		s.edit(ctx, text)
+		return nil
+	}
+}
+
+// Finish replaces the placeholder with the final text, sending overflow as additional messages
+func (s *messageStreamer) Finish(ctx context.Context, text string) {
+	parts := splitMessage(text, telegramMessageLimit)
+	s.edit(ctx, parts[0])
+
+	for _, part := range parts[1:] {
+		_, err := s.b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: s.chatID,
+			Text:   part,
+		})
+		if err != nil {
+			log.Err(err).Msg("failed to send response message")
+			return
+		}
+	}
+}
+
+func (s *messageStreamer) edit(ctx context.Context, text string) {
+	if text == "" || text == s.shown {
+		return
+	}
+
+	_, err := s.b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    s.chatID,
+		MessageID: s.messageID,
+		Text:      text,
+	})
+	s.lastEdit = time.Now()
+	if err != nil {
+		log.Err(err).Int64("chat_id", s.chatID).Msg("failed to edit streamed message")
+		return
+	}
+	s.shown = text
+}
+
+// splitMessage splits text into parts of at most limit runes, preferring line breaks
+func splitMessage(text string, limit int) []string {
+	runes := []rune(text)
+	var parts []string
+	for len(runes) > limit {
+		cut := limit
+		for i := limit; i > limit/2; i-- {
+			if runes[i-1] == '\n' {
+				cut = i
+				break
+			}
+		}
+		parts = append(parts, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	return append(parts, string(runes))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	postgresretriever "go-llm-rpggamemaster/retrievers/postgres"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+)
+
+const textSearchUsage = `Использование:
+/textsearch — язык поиска по памяти кампании
+/textsearch <russian|english|simple|multilingual> — сменить язык (администраторы чата)`
+
+// textSearchConfigurer is implemented by retrievers with a per-game full-text search configuration
+type textSearchConfigurer interface {
+	TextSearch(ctx context.Context, gameID string) (postgresretriever.TextSearch, error)
+	SetTextSearch(ctx context.Context, gameID string, ts postgresretriever.TextSearch) error
+}
+
+// textSearchHandler shows or changes the full-text search configuration of the chat's game
+func textSearchHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	configurer, ok := retriever.(textSearchConfigurer)
+	if !ok {
+		reply(ctx, b, chatID, "Настройка поиска доступна только с хранилищем postgres")
+		return
+	}
+	gameID, ok := chatGame(ctx, b, chatID)
+	if !ok {
+		return
+	}
+
+	args := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/textsearch"))
+	if args == "" {
+		current, err := configurer.TextSearch(ctx, gameID)
+		if err != nil {
+			log.Err(err).Int64("chat_id", chatID).Msg("failed to load text search configuration")
+			reply(ctx, b, chatID, "Не удалось загрузить настройку поиска, попробуйте позже")
+			return
+		}
+		reply(ctx, b, chatID, fmt.Sprintf("🔎 Язык поиска: %s\n\n%s", current, textSearchUsage))
+		return
+	}
+
+	ts, err := postgresretriever.ParseTextSearch(args)
+	if err != nil {
+		reply(ctx, b, chatID, textSearchUsage)
+		return
+	}
+	if !isChatAdmin(ctx, b, update.Message.Chat, update.Message.From.ID) {
+		reply(ctx, b, chatID, "Язык поиска могут менять только администраторы чата")
+		return
+	}
+	if err := configurer.SetTextSearch(ctx, gameID, ts); err != nil {
+		log.Err(err).Int64("chat_id", chatID).Msg("failed to change text search configuration")
+		reply(ctx, b, chatID, "Не удалось сменить язык поиска, попробуйте позже")
+		return
+	}
+	reply(ctx, b, chatID, fmt.Sprintf("🔎 Язык поиска: %s. Память кампании переиндексирована.", ts))
+}
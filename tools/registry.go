@@ -0,0 +1,118 @@
+// Package tools lets the game master call game mechanics instead of inventing their results.
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// Invocation identifies the game and player on whose behalf a tool is called
+type Invocation struct {
+	GameID string
+	ChatID int64
+	UserID int64
+}
+
+// Tool is a game mechanic the model can call
+type Tool interface {
+	// Definition describes the tool and its JSON Schema arguments to the model
+	Definition() interfaces.ToolDefinition
+
+	// Call executes the tool and returns the result shown to the model
+	Call(ctx context.Context, inv Invocation, arguments json.RawMessage) (string, error)
+}
+
+// funcTool adapts a function to the Tool interface
+type funcTool struct {
+	definition interfaces.ToolDefinition
+	fn         func(ctx context.Context, inv Invocation, arguments json.RawMessage) (string, error)
+}
+
+// NewFunc creates a tool from a definition and a function
+func NewFunc(definition interfaces.ToolDefinition, fn func(ctx context.Context, inv Invocation, arguments json.RawMessage) (string, error)) Tool {
+	return &funcTool{definition: definition, fn: fn}
+}
+
+func (t *funcTool) Definition() interfaces.ToolDefinition {
+	return t.definition
+}
+
+func (t *funcTool) Call(ctx context.Context, inv Invocation, arguments json.RawMessage) (string, error) {
+	return t.fn(ctx, inv, arguments)
+}
+
+// Registry holds the tools offered to the model. It is safe for concurrent use.
+type Registry struct {
+	mu    sync.RWMutex
+	tools map[string]Tool
+	order []string
+}
+
+// NewRegistry creates an empty tool registry
+func NewRegistry() *Registry {
+	return &Registry{
+		tools: make(map[string]Tool),
+	}
+}
+
+// Register adds a tool. Tool names must be unique.
+func (r *Registry) Register(tool Tool) error {
+	name := tool.Definition().Name
+	if name == "" {
+		return fmt.Errorf("tool name is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tools[name]; exists {
+		return fmt.Errorf("tool %q is already registered", name)
+	}
+	r.tools[name] = tool
+	r.order = append(r.order, name)
+	return nil
+}
+
+// Len returns the number of registered tools
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.order)
+}
+
+// Definitions returns tool definitions in registration order
+func (r *Registry) Definitions() []interfaces.ToolDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	definitions := make([]interfaces.ToolDefinition, len(r.order))
+	for i, name := range r.order {
+		definitions[i] = r.tools[name].Definition()
+	}
+	return definitions
+}
+
+// Call executes a tool call requested by the model
+func (r *Registry) Call(ctx context.Context, inv Invocation, call interfaces.ToolCall) (string, error) {
+	r.mu.RLock()
+	tool, ok := r.tools[call.Name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", call.Name)
+	}
+
+	arguments := json.RawMessage(call.Arguments)
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	if !json.Valid(arguments) {
+		return "", fmt.Errorf("invalid JSON arguments for tool %q", call.Name)
+	}
+
+	return tool.Call(ctx, inv, arguments)
+}
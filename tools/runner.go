@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// RunOptions contains settings for a tool-calling loop
+type RunOptions struct {
+	Temperature float64
+	MaxTokens   int
+	MaxRounds   int // Rounds with tools offered; the model must narrate afterwards
+	OnDelta     func(delta string) error
+}
+
+// CallRecord is an executed tool call
+type CallRecord struct {
+	Name      string
+	Arguments string
+	Result    string
+	Failed    bool
+}
+
+// Result is the outcome of a tool-calling loop
+type Result struct {
+	Content string
+	Calls   []CallRecord
+}
+
+const defaultMaxRounds = 5
+
+// Run lets the model call registered tools until it produces a final narration
+func Run(ctx context.Context, provider interfaces.ToolCallingProvider, registry *Registry, inv Invocation, messages []interfaces.Message, opts RunOptions) (Result, error) {
+	if opts.MaxRounds <= 0 {
+		opts.MaxRounds = defaultMaxRounds
+	}
+
+	messages = append([]interfaces.Message(nil), messages...)
+	var result Result
+
+	for round := 0; ; round++ {
+		definitions := registry.Definitions()
+		if round >= opts.MaxRounds {
+			// Withholding the tools forces a narration
+			definitions = nil
+		}
+
+		msg, err := provider.GenerateWithTools(ctx, messages, definitions, opts.Temperature, opts.MaxTokens, opts.OnDelta)
+		if err != nil {
+			return Result{}, fmt.Errorf("round %d: %w", round, err)
+		}
+
+		if len(msg.ToolCalls) == 0 {
+			result.Content = msg.Content
+			return result, nil
+		}
+
+		msg.Role = "assistant"
+		messages = append(messages, msg)
+		for _, call := range msg.ToolCalls {
+			record := execute(ctx, registry, inv, call)
+			result.Calls = append(result.Calls, record)
+			messages = append(messages, interfaces.Message{
+				Role:       "tool",
+				ToolCallID: call.ID,
+				Content:    record.Result,
+			})
+		}
+	}
+}
+
+// execute runs a tool call. Failures become the result so the model can recover.
+func execute(ctx context.Context, registry *Registry, inv Invocation, call interfaces.ToolCall) CallRecord {
+	start := time.Now()
+	record := CallRecord{Name: call.Name, Arguments: call.Arguments}
+
+	output, err := registry.Call(ctx, inv, call)
+	if err != nil {
+		record.Failed = true
+		record.Result = fmt.Sprintf("error: %v", err)
+		log.Warn().
+			Err(err).
+			Str("tool", call.Name).
+			Str("arguments", call.Arguments).
+			Str("game_id", inv.GameID).
+			Msg("Tool call failed")
+		return record
+	}
+
+	record.Result = output
+	log.Debug().
+		Str("tool", call.Name).
+		Str("arguments", call.Arguments).
+		Str("game_id", inv.GameID).
+		Dur("call_duration", time.Since(start)).
+		Msg("Tool call executed")
+	return record
+}
@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// MockToolProvider answers with scripted messages and records what it receives
+type MockToolProvider struct {
+	replies     []interfaces.Message
+	calls       [][]interfaces.Message
+	definitions [][]interfaces.ToolDefinition
+}
+
+func (m *MockToolProvider) GenerateResponse(ctx context.Context, messages []interfaces.Message, temperature float64, maxTokens int) (string, error) {
+	return "", errors.New("not used")
+}
+
+func (m *MockToolProvider) GenerateWithTools(ctx context.Context, messages []interfaces.Message, tools []interfaces.ToolDefinition, temperature float64, maxTokens int, onDelta func(delta string) error) (interfaces.Message, error) {
+	m.calls = append(m.calls, messages)
+	m.definitions = append(m.definitions, tools)
+	if len(m.replies) == 0 {
+		return interfaces.Message{Role: "assistant", Content: "final"}, nil
+	}
+	reply := m.replies[0]
+	m.replies = m.replies[1:]
+	return reply, nil
+}
+
+func (m *MockToolProvider) Name() string {
+	return "mock"
+}
+
+func echoTool(name string) Tool {
+	return NewFunc(interfaces.ToolDefinition{Name: name}, func(ctx context.Context, inv Invocation, arguments json.RawMessage) (string, error) {
+		return inv.GameID + ":" + string(arguments), nil
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("register and list", func(t *testing.T) {
+		r := NewRegistry()
+		if err := r.Register(echoTool("b")); err != nil {
+			t.Fatalf("register: %v", err)
+		}
+		if err := r.Register(echoTool("a")); err != nil {
+			t.Fatalf("register: %v", err)
+		}
+
+		defs := r.Definitions()
+		if len(defs) != 2 || defs[0].Name != "b" || defs[1].Name != "a" {
+			t.Errorf("expected registration order, got %+v", defs)
+		}
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(echoTool("a"))
+		if err := r.Register(echoTool("a")); err == nil {
+			t.Error("expected error for duplicate tool")
+		}
+	})
+
+	t.Run("empty name", func(t *testing.T) {
+		if err := NewRegistry().Register(echoTool("")); err == nil {
+			t.Error("expected error for empty name")
+		}
+	})
+
+	t.Run("call", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(echoTool("echo"))
+		inv := Invocation{GameID: "g"}
+
+		out, err := r.Call(context.Background(), inv, interfaces.ToolCall{Name: "echo", Arguments: `{"x":1}`})
+		if err != nil || out != `g:{"x":1}` {
+			t.Errorf("unexpected result: %q, %v", out, err)
+		}
+
+		out, _ = r.Call(context.Background(), inv, interfaces.ToolCall{Name: "echo"})
+		if out != "g:{}" {
+			t.Errorf("expected empty arguments object, got %q", out)
+		}
+
+		if _, err := r.Call(context.Background(), inv, interfaces.ToolCall{Name: "missing"}); err == nil {
+			t.Error("expected error for unknown tool")
+		}
+		if _, err := r.Call(context.Background(), inv, interfaces.ToolCall{Name: "echo", Arguments: "{"}); err == nil {
+			t.Error("expected error for invalid arguments")
+		}
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Run("executes calls until narration", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(echoTool("echo"))
+		provider := &MockToolProvider{replies: []interfaces.Message{
+			{ToolCalls: []interfaces.ToolCall{{ID: "1", Name: "echo", Arguments: `{}`}, {ID: "2", Name: "missing"}}},
+		}}
+
+		result, err := Run(context.Background(), provider, r, Invocation{GameID: "g"}, []interfaces.Message{{Role: "user", Content: "go"}}, RunOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Content != "final" {
+			t.Errorf("unexpected content: %q", result.Content)
+		}
+		if len(result.Calls) != 2 || result.Calls[0].Failed || !result.Calls[1].Failed {
+			t.Errorf("unexpected call records: %+v", result.Calls)
+		}
+
+		second := provider.calls[1]
+		if len(second) != 4 {
+			t.Fatalf("expected user, assistant and two tool messages, got %d", len(second))
+		}
+		if second[1].Role != "assistant" || second[2].Role != "tool" || second[2].ToolCallID != "1" || second[2].Content != "g:{}" {
+			t.Errorf("unexpected transcript: %+v", second)
+		}
+		if !strings.HasPrefix(second[3].Content, "error:") {
+			t.Errorf("expected failed call reported to the model, got %q", second[3].Content)
+		}
+	})
+
+	t.Run("forces narration after max rounds", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Register(echoTool("echo"))
+		loop := interfaces.Message{ToolCalls: []interfaces.ToolCall{{ID: "1", Name: "echo"}}}
+		provider := &MockToolProvider{replies: []interfaces.Message{loop, loop}}
+
+		_, err := Run(context.Background(), provider, r, Invocation{}, nil, RunOptions{MaxRounds: 2})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(provider.definitions) != 3 || provider.definitions[2] != nil {
+			t.Errorf("expected tools withheld in the last round, got %+v", provider.definitions)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-llm-rpggamemaster/config"
+	"go-llm-rpggamemaster/usage"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+)
+
+const usageHelp = `Использование:
+/usage — расход токенов игры за текущий месяц
+/usage months — помесячная сводка`
+
+// usageMonths is the length of the monthly summary
+const usageMonths = 6
+
+// newUsageService keeps token accounting next to the game sessions
+func newUsageService(cfg config.UsageConfig) (*usage.Service, error) {
+	pricing := usage.Pricing{
+		Currency: cfg.Currency,
+		Models:   make(map[string]usage.Price, len(cfg.Prices)),
+	}
+	for _, p := range cfg.Prices {
+		pricing.Models[p.Model] = usage.Price{Prompt: p.Prompt, Completion: p.Completion}
+	}
+
+	if dbPool == nil {
+		return usage.NewService(usage.NewMemoryStore(), pricing)
+	}
+	store, err := usage.NewPostgresStore(dbPool)
+	if err != nil {
+		return nil, err
+	}
+	return usage.NewService(store, pricing)
+}
+
+// usageHandler shows the tokens and costs of the chat's game
+func usageHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	gameID, ok := chatGame(ctx, b, chatID)
+	if !ok {
+		return
+	}
+
+	var (
+		text string
+		err  error
+	)
+	switch args := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/usage")); args {
+	case "":
+		text, err = usageMonthText(ctx, gameID, time.Now())
+	case "months":
+		text, err = usageMonthsText(ctx, gameID, time.Now())
+	default:
+		reply(ctx, b, chatID, usageHelp)
+		return
+	}
+	if err != nil {
+		log.Err(err).Int64("chat_id", chatID).Msg("failed to summarize usage")
+		reply(ctx, b, chatID, "Не удалось загрузить расход, попробуйте позже")
+		return
+	}
+	reply(ctx, b, chatID, text)
+}
+
+// usageMonthText summarizes the current month per player
+func usageMonthText(ctx context.Context, gameID string, now time.Time) (string, error) {
+	since := usage.MonthStart(now)
+	filter := usage.Filter{GameID: gameID, Since: since}
+
+	totals, err := usageTracker.Totals(ctx, filter)
+	if err != nil {
+		return "", err
+	}
+	if totals.Requests == 0 {
+		return "В этом месяце запросов к моделям не было.", nil
+	}
+	players, err := usageTracker.ByUser(ctx, filter)
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "📊 Расход за %s:\n%s", since.Format("01.2006"), usageLine(totals))
+	text.WriteString("\n\nПо игрокам:")
+	for _, p := range players {
+		fmt.Fprintf(&text, "\n• %s: %s", playerName(ctx, gameID, p.Key), usageLine(p.Totals))
+	}
+	return text.String(), nil
+}
+
+// usageMonthsText summarizes the last months of a game
+func usageMonthsText(ctx context.Context, gameID string, now time.Time) (string, error) {
+	months, err := usageTracker.Monthly(ctx, gameID, usageMonths, now)
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	text.WriteString("📊 Расход по месяцам:")
+	for _, m := range months {
+		fmt.Fprintf(&text, "\n%s — %s", m.Key, usageLine(m.Totals))
+	}
+	return text.String(), nil
+}
+
+// usageLine formats totals, with the cost only when prices are configured
+func usageLine(t usage.Totals) string {
+	line := fmt.Sprintf("%d запр., %d токенов (%d вход, %d выход)", t.Requests, t.Tokens(), t.PromptTokens, t.CompletionTokens)
+	if t.Cost > 0 {
+		line += fmt.Sprintf(", %.4f %s", t.Cost, usageTracker.Currency())
+	}
+	return line
+}
+
+// playerName returns the character name of a user ID, or a fallback for players without one
+func playerName(ctx context.Context, gameID, key string) string {
+	userID, err := strconv.ParseInt(key, 10, 64)
+	if err != nil || userID == 0 {
+		return "без игрока"
+	}
+	if c, err := characters.Get(ctx, gameID, userID); err == nil {
+		return c.Name
+	}
+	return fmt.Sprintf("игрок %d", userID)
+}
@@ -0,0 +1,79 @@
+package usage
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-llm-rpggamemaster/internal/uuid"
+)
+
+// MemoryStore keeps usage records in process memory. Records are lost on restart.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records []Record
+}
+
+// Compile-time interface check
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty in-memory usage store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Add stores a copy of the record
+func (s *MemoryStore) Add(ctx context.Context, r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.ID = uuid.New()
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	s.records = append(s.records, *r)
+	return nil
+}
+
+// Summarize sums up the matching records per group
+func (s *MemoryStore) Summarize(ctx context.Context, filter Filter, by Grouping) ([]Group, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	totals := make(map[string]*Totals)
+	var keys []string
+	for i := range s.records {
+		r := &s.records[i]
+		if !filter.matches(r) {
+			continue
+		}
+		key := groupKey(r, by)
+		t, ok := totals[key]
+		if !ok {
+			t = &Totals{}
+			totals[key] = t
+			keys = append(keys, key)
+		}
+		t.add(r)
+	}
+
+	groups := make([]Group, len(keys))
+	for i, key := range keys {
+		groups[i] = Group{Key: key, Totals: *totals[key]}
+	}
+	return groups, nil
+}
+
+func groupKey(r *Record, by Grouping) string {
+	switch by {
+	case GroupUser:
+		return strconv.FormatInt(r.UserID, 10)
+	case GroupMonth:
+		return r.CreatedAt.UTC().Format(monthLayout)
+	case GroupModel:
+		return r.Model
+	default:
+		return ""
+	}
+}
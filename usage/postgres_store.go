@@ -0,0 +1,89 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresStore persists usage in the usage_records table
+type PostgresStore struct {
+	db *pgxpool.Pool
+}
+
+// Compile-time interface check
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore creates a usage store backed by PostgreSQL
+func NewPostgresStore(db *pgxpool.Pool) (*PostgresStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database pool cannot be nil")
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// Add inserts a record
+func (s *PostgresStore) Add(ctx context.Context, r *Record) error {
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO usage_records (game_id, chat_id, user_id, provider, model, kind, prompt_tokens, completion_tokens, cost)
+		VALUES (NULLIF($1, '')::uuid, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`, r.GameID, r.ChatID, r.UserID, r.Provider, r.Model, r.Kind, r.PromptTokens, r.CompletionTokens, r.Cost).Scan(&r.ID, &r.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("recording usage: %w", err)
+	}
+	return nil
+}
+
+// groupColumns maps groupings onto the SQL expression of the group key
+var groupColumns = map[Grouping]string{
+	GroupNone:  `''`,
+	GroupUser:  `user_id::text`,
+	GroupMonth: `to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM')`,
+	GroupModel: `model`,
+}
+
+// Summarize sums up the matching records per group
+func (s *PostgresStore) Summarize(ctx context.Context, filter Filter, by Grouping) ([]Group, error) {
+	column, ok := groupColumns[by]
+	if !ok {
+		return nil, fmt.Errorf("unknown grouping %d", by)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT `+column+` AS key, COUNT(*), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0), COALESCE(SUM(cost), 0)
+		FROM usage_records
+		WHERE ($1 = '' OR game_id = NULLIF($1, '')::uuid)
+		  AND ($2::bigint = 0 OR chat_id = $2)
+		  AND ($3::bigint = 0 OR user_id = $3)
+		  AND ($4::timestamptz IS NULL OR created_at >= $4)
+		  AND ($5::timestamptz IS NULL OR created_at < $5)
+		GROUP BY key
+	`, filter.GameID, filter.ChatID, filter.UserID, nullTime(filter.Since), nullTime(filter.Until))
+	if err != nil {
+		return nil, fmt.Errorf("summarizing usage: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []Group
+	for rows.Next() {
+		var g Group
+		if err := rows.Scan(&g.Key, &g.Requests, &g.PromptTokens, &g.CompletionTokens, &g.Cost); err != nil {
+			return nil, fmt.Errorf("scanning usage: %w", err)
+		}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("summarizing usage: %w", err)
+	}
+	return groups, nil
+}
+
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
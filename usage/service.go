@@ -0,0 +1,123 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// Service records usage with its cost and summarizes it
+type Service struct {
+	store   Store
+	pricing Pricing
+}
+
+// NewService creates a usage service
+func NewService(store Store, pricing Pricing) (*Service, error) {
+	if store == nil {
+		return nil, fmt.Errorf("usage store cannot be nil")
+	}
+	return &Service{store: store, pricing: pricing}, nil
+}
+
+// Currency returns the currency of costs
+func (s *Service) Currency() string {
+	return s.pricing.Currency
+}
+
+// Record stores the usage of a provider call. Failures are logged, accounting never fails a turn.
+func (s *Service) Record(ctx context.Context, chatID, userID int64, gameID string, u interfaces.Usage) {
+	r := &Record{
+		GameID:           gameID,
+		ChatID:           chatID,
+		UserID:           userID,
+		Provider:         u.Provider,
+		Model:            u.Model,
+		Kind:             u.Kind,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		Cost:             s.pricing.Cost(u),
+	}
+	if err := s.store.Add(ctx, r); err != nil {
+		log.Error().
+			Err(err).
+			Int64("chat_id", chatID).
+			Str("game_id", gameID).
+			Str("model", u.Model).
+			Msg("Failed to record usage")
+	}
+}
+
+// Totals returns the totals of the matching records
+func (s *Service) Totals(ctx context.Context, filter Filter) (Totals, error) {
+	groups, err := s.store.Summarize(ctx, filter, GroupNone)
+	if err != nil {
+		return Totals{}, err
+	}
+	if len(groups) == 0 {
+		return Totals{}, nil
+	}
+	return groups[0].Totals, nil
+}
+
+// ByUser returns the totals per player, most expensive first
+func (s *Service) ByUser(ctx context.Context, filter Filter) ([]Group, error) {
+	groups, err := s.store.Summarize(ctx, filter, GroupUser)
+	if err != nil {
+		return nil, err
+	}
+	sortByCost(groups)
+	return groups, nil
+}
+
+// ByModel returns the totals per model, most expensive first
+func (s *Service) ByModel(ctx context.Context, filter Filter) ([]Group, error) {
+	groups, err := s.store.Summarize(ctx, filter, GroupModel)
+	if err != nil {
+		return nil, err
+	}
+	sortByCost(groups)
+	return groups, nil
+}
+
+// Monthly returns the totals of the last months up to the month of now, oldest first.
+// Months without usage are included with zero totals.
+func (s *Service) Monthly(ctx context.Context, gameID string, months int, now time.Time) ([]Group, error) {
+	if months <= 0 {
+		return nil, nil
+	}
+	until := MonthStart(now).AddDate(0, 1, 0)
+	since := until.AddDate(0, -months, 0)
+
+	groups, err := s.store.Summarize(ctx, Filter{GameID: gameID, Since: since, Until: until}, GroupMonth)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]Totals, len(groups))
+	for _, g := range groups {
+		byKey[g.Key] = g.Totals
+	}
+
+	result := make([]Group, months)
+	for i := range result {
+		key := since.AddDate(0, i, 0).Format(monthLayout)
+		result[i] = Group{Key: key, Totals: byKey[key]}
+	}
+	return result, nil
+}
+
+func sortByCost(groups []Group) {
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Cost != groups[j].Cost {
+			return groups[i].Cost > groups[j].Cost
+		}
+		if groups[i].Tokens() != groups[j].Tokens() {
+			return groups[i].Tokens() > groups[j].Tokens()
+		}
+		return groups[i].Key < groups[j].Key
+	})
+}
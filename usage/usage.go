@@ -0,0 +1,114 @@
+// Package usage accounts the tokens and costs of provider calls per chat, player and game.
+package usage
+
+import (
+	"context"
+	"time"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+// Record is the usage of a single provider call
+type Record struct {
+	ID               string
+	GameID           string
+	ChatID           int64
+	UserID           int64 // 0 for calls not made for a player, such as background memory writes
+	Provider         string
+	Model            string
+	Kind             string // interfaces.UsageChat or interfaces.UsageEmbedding
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+	CreatedAt        time.Time
+}
+
+// Price is the cost of a model per million tokens
+type Price struct {
+	Prompt     float64
+	Completion float64
+}
+
+// Pricing converts tokens into costs. Models without a price cost nothing.
+type Pricing struct {
+	Currency string
+	Models   map[string]Price
+}
+
+// Cost returns the price of a call
+func (p Pricing) Cost(u interfaces.Usage) float64 {
+	price, ok := p.Models[u.Model]
+	if !ok {
+		return 0
+	}
+	return (float64(u.PromptTokens)*price.Prompt + float64(u.CompletionTokens)*price.Completion) / 1e6
+}
+
+// Totals sums up records
+type Totals struct {
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+}
+
+// Tokens returns prompt and completion tokens together
+func (t Totals) Tokens() int {
+	return t.PromptTokens + t.CompletionTokens
+}
+
+func (t *Totals) add(r *Record) {
+	t.Requests++
+	t.PromptTokens += r.PromptTokens
+	t.CompletionTokens += r.CompletionTokens
+	t.Cost += r.Cost
+}
+
+// Grouping selects how a summary is split
+type Grouping uint8
+
+const (
+	GroupNone  Grouping = iota
+	GroupUser           // Key is the user ID
+	GroupMonth          // Key is the UTC month, e.g. 2026-10
+	GroupModel          // Key is the model name
+)
+
+// Group is the totals of one key of a summary
+type Group struct {
+	Key string
+	Totals
+}
+
+// Filter selects records. Zero fields match everything, Until is exclusive.
+type Filter struct {
+	GameID string
+	ChatID int64
+	UserID int64
+	Since  time.Time
+	Until  time.Time
+}
+
+func (f Filter) matches(r *Record) bool {
+	return (f.GameID == "" || r.GameID == f.GameID) &&
+		(f.ChatID == 0 || r.ChatID == f.ChatID) &&
+		(f.UserID == 0 || r.UserID == f.UserID) &&
+		(f.Since.IsZero() || !r.CreatedAt.Before(f.Since)) &&
+		(f.Until.IsZero() || r.CreatedAt.Before(f.Until))
+}
+
+// Store persists usage records
+type Store interface {
+	// Add stores a record and fills its ID and CreatedAt
+	Add(ctx context.Context, r *Record) error
+	// Summarize returns the totals of the matching records per group, in no particular order
+	Summarize(ctx context.Context, filter Filter, by Grouping) ([]Group, error)
+}
+
+// MonthStart returns the first moment of the UTC month of t
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+const monthLayout = "2006-01"
@@ -0,0 +1,140 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-llm-rpggamemaster/interfaces"
+)
+
+func TestPricing_Cost(t *testing.T) {
+	pricing := Pricing{Models: map[string]Price{"gpt-4o-mini": {Prompt: 0.15, Completion: 0.60}}}
+
+	cost := pricing.Cost(interfaces.Usage{Model: "gpt-4o-mini", PromptTokens: 2_000_000, CompletionTokens: 500_000})
+	if cost < 0.5999 || cost > 0.6001 {
+		t.Errorf("expected 0.60, got %f", cost)
+	}
+	if cost := pricing.Cost(interfaces.Usage{Model: "unknown", PromptTokens: 1000}); cost != 0 {
+		t.Errorf("expected models without a price to be free, got %f", cost)
+	}
+}
+
+func TestMonthStart(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	got := MonthStart(time.Date(2026, 11, 1, 1, 0, 0, 0, moscow))
+	if want := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected the UTC month %v, got %v", want, got)
+	}
+}
+
+func newTestService(t *testing.T) (*Service, *MemoryStore) {
+	t.Helper()
+	store := NewMemoryStore()
+	s, err := NewService(store, Pricing{Currency: "USD", Models: map[string]Price{"big": {Prompt: 10, Completion: 30}}})
+	if err != nil {
+		t.Fatalf("creating service: %v", err)
+	}
+	return s, store
+}
+
+// add stores a record created at a fixed time
+func add(t *testing.T, store *MemoryStore, r Record) {
+	t.Helper()
+	if err := store.Add(context.Background(), &r); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("nil store", func(t *testing.T) {
+		if _, err := NewService(nil, Pricing{}); err == nil {
+			t.Error("expected error for nil store")
+		}
+	})
+
+	t.Run("record prices the call", func(t *testing.T) {
+		s, _ := newTestService(t)
+		s.Record(ctx, 1, 10, "game", interfaces.Usage{Model: "big", Kind: interfaces.UsageChat, PromptTokens: 1000, CompletionTokens: 100})
+		s.Record(ctx, 1, 10, "other", interfaces.Usage{Model: "big", Kind: interfaces.UsageChat, PromptTokens: 5})
+
+		totals, err := s.Totals(ctx, Filter{GameID: "game"})
+		if err != nil {
+			t.Fatalf("totals: %v", err)
+		}
+		if totals.Requests != 1 || totals.Tokens() != 1100 {
+			t.Errorf("unexpected totals %+v", totals)
+		}
+		if totals.Cost < 0.0129 || totals.Cost > 0.0131 {
+			t.Errorf("expected cost 0.013, got %f", totals.Cost)
+		}
+	})
+
+	t.Run("empty totals", func(t *testing.T) {
+		s, _ := newTestService(t)
+		totals, err := s.Totals(ctx, Filter{GameID: "game"})
+		if err != nil || totals.Requests != 0 {
+			t.Errorf("expected zero totals, got %+v, %v", totals, err)
+		}
+	})
+
+	t.Run("by user, most expensive first", func(t *testing.T) {
+		s, store := newTestService(t)
+		add(t, store, Record{GameID: "game", UserID: 10, PromptTokens: 10, Cost: 0.1})
+		add(t, store, Record{GameID: "game", UserID: 20, PromptTokens: 10, Cost: 0.5})
+		add(t, store, Record{GameID: "game", UserID: 20, PromptTokens: 10, Cost: 0.5})
+		add(t, store, Record{GameID: "other", UserID: 30, PromptTokens: 10, Cost: 9})
+
+		groups, err := s.ByUser(ctx, Filter{GameID: "game"})
+		if err != nil {
+			t.Fatalf("by user: %v", err)
+		}
+		if len(groups) != 2 || groups[0].Key != "20" || groups[0].Requests != 2 || groups[1].Key != "10" {
+			t.Errorf("unexpected groups %+v", groups)
+		}
+	})
+
+	t.Run("time filter", func(t *testing.T) {
+		s, store := newTestService(t)
+		october := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+		add(t, store, Record{GameID: "game", PromptTokens: 1, CreatedAt: october.AddDate(0, -1, 0)})
+		add(t, store, Record{GameID: "game", PromptTokens: 2, CreatedAt: october})
+
+		totals, _ := s.Totals(ctx, Filter{GameID: "game", Since: MonthStart(october)})
+		if totals.PromptTokens != 2 {
+			t.Errorf("expected only the current month, got %+v", totals)
+		}
+		totals, _ = s.Totals(ctx, Filter{GameID: "game", Until: MonthStart(october)})
+		if totals.PromptTokens != 1 {
+			t.Errorf("expected Until to be exclusive, got %+v", totals)
+		}
+	})
+
+	t.Run("monthly includes empty months", func(t *testing.T) {
+		s, store := newTestService(t)
+		now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+		add(t, store, Record{GameID: "game", PromptTokens: 1, CreatedAt: time.Date(2026, 8, 3, 0, 0, 0, 0, time.UTC)})
+		add(t, store, Record{GameID: "game", PromptTokens: 2, CreatedAt: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)})
+		add(t, store, Record{GameID: "game", PromptTokens: 4, CreatedAt: time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)})
+		add(t, store, Record{GameID: "game", PromptTokens: 8, CreatedAt: time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC)})
+
+		months, err := s.Monthly(ctx, "game", 3, now)
+		if err != nil {
+			t.Fatalf("monthly: %v", err)
+		}
+		want := []struct {
+			key    string
+			tokens int
+		}{{"2026-08", 1}, {"2026-09", 0}, {"2026-10", 6}}
+		if len(months) != len(want) {
+			t.Fatalf("expected %d months, got %+v", len(want), months)
+		}
+		for i, w := range want {
+			if months[i].Key != w.key || months[i].PromptTokens != w.tokens {
+				t.Errorf("month %d: expected %s with %d tokens, got %+v", i, w.key, w.tokens, months[i])
+			}
+		}
+	})
+}
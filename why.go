@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go-llm-rpggamemaster/session"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+var skipReasons = map[session.SkipReason]string{
+	session.SkipBelowThreshold: "ниже порога",
+	session.SkipBudget:         "не хватило места",
+	session.SkipEmpty:          "пустой",
+}
+
+// whyHandler shows chat admins what campaign memory returned for the last turn and why
+func whyHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	if retriever == nil {
+		reply(ctx, b, chatID, "Память кампании не подключена")
+		return
+	}
+	if !isChatAdmin(ctx, b, update.Message.Chat, update.Message.From.ID) {
+		reply(ctx, b, chatID, "Разбор поиска по памяти доступен только администраторам чата")
+		return
+	}
+	retrieval := sessions.LastRetrieval(chatID)
+	if retrieval == nil {
+		reply(ctx, b, chatID, "С запуска бота в этом чате ещё не было ходов")
+		return
+	}
+	reply(ctx, b, chatID, formatRetrieval(retrieval))
+}
+
+func formatRetrieval(retrieval *session.Retrieval) string {
+	var text strings.Builder
+	text.WriteString("🧠 Поиск по памяти кампании для последнего хода\n")
+	fmt.Fprintf(&text, "Запрос: %s\n", preview(retrieval.Query))
+	fmt.Fprintf(&text, "Время: %s, %d мс", retrieval.At.Format("15:04:05"), retrieval.Duration.Milliseconds())
+
+	if retrieval.Err != nil {
+		fmt.Fprintf(&text, "\n\n⚠️ Поиск не удался: %v", retrieval.Err)
+		return text.String()
+	}
+	if retrieval.Reranker != "" {
+		fmt.Fprintf(&text, "\nПереранжирование: %s", retrieval.Reranker)
+	}
+	if retrieval.RerankErr != nil {
+		fmt.Fprintf(&text, "\n⚠️ Переранжирование не удалось, порядок поиска сохранён: %v", retrieval.RerankErr)
+	}
+	if len(retrieval.Documents) == 0 {
+		text.WriteString("\n\nНичего не найдено")
+		return text.String()
+	}
+
+	for i, doc := range retrieval.Documents {
+		mark := "✅"
+		var details []string
+		if !doc.Used {
+			mark = "▫️"
+			details = append(details, skipReasons[doc.Skipped])
+		}
+		if retrieval.Scored {
+			details = append(details, doc.Source, fmt.Sprintf("оценка %.4f", doc.Score),
+				"смысл "+rankLabel(doc.SemanticRank), "слова "+rankLabel(doc.KeywordRank))
+		}
+		if doc.ID != "" {
+			details = append(details, "id "+doc.ID)
+		}
+		fmt.Fprintf(&text, "\n\n%d. %s %s\n%s", i+1, mark, strings.Join(details, ", "), preview(doc.PageContent))
+	}
+	return text.String()
+}
+
+// rankLabel formats a per-channel rank, which is 0 when the channel did not find the document
+func rankLabel(rank int) string {
+	if rank == 0 {
+		return "—"
+	}
+	return fmt.Sprintf("#%d", rank)
+}
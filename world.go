@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-llm-rpggamemaster/world"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+)
+
+const mapUsage = `Использование:
+/where — где находится группа
+/go <выход> — перейти через выход
+/map — карта игры
+/map add <название> | <описание>
+/map link <откуда> | <выход> | <куда> | <обратный выход>`
+
+// newWorldService keeps the world map next to the game sessions
+func newWorldService() (*world.Service, error) {
+	if dbPool == nil {
+		return world.NewService(world.NewMemoryStore())
+	}
+	store, err := world.NewPostgresStore(dbPool)
+	if err != nil {
+		return nil, err
+	}
+	return world.NewService(store)
+}
+
+// whereHandler shows the party location and its exits
+func whereHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	gameID, ok := chatGame(ctx, b, chatID)
+	if !ok {
+		return
+	}
+
+	l, exits, err := worldMap.Where(ctx, gameID)
+	if errors.Is(err, world.ErrNoPosition) {
+		reply(ctx, b, chatID, "Карта пока пуста. Добавьте локацию командой /map add <название> | <описание>")
+		return
+	}
+	if err != nil {
+		log.Err(err).Int64("chat_id", chatID).Msg("failed to load party location")
+		reply(ctx, b, chatID, "Не удалось загрузить карту, попробуйте позже")
+		return
+	}
+	reply(ctx, b, chatID, world.Describe(l, exits))
+}
+
+// goHandler moves the party through an exit and records the move in the campaign history
+func goHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	exitName := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/go"))
+	if exitName == "" {
+		reply(ctx, b, chatID, "Укажите выход: /go <выход>. Список выходов — /where")
+		return
+	}
+
+	gameID, ok := chatGame(ctx, b, chatID)
+	if !ok {
+		return
+	}
+
+	from, to, err := worldMap.Move(ctx, gameID, exitName)
+	if errors.Is(err, world.ErrNoPosition) {
+		reply(ctx, b, chatID, "Карта пока пуста. Добавьте локацию командой /map add <название> | <описание>")
+		return
+	}
+	if err != nil {
+		reply(ctx, b, chatID, fmt.Sprintf("Туда не пройти: %s. Список выходов — /where", err.Error()))
+		return
+	}
+
+	note := fmt.Sprintf("Группа перешла из локации «%s» в локацию «%s».", from.Name, to.Name)
+	if err := sessions.AppendNote(ctx, chatID, note); err != nil {
+		log.Err(err).Int64("chat_id", chatID).Msg("failed to record move")
+	}
+
+	_, exits, err := worldMap.Where(ctx, gameID)
+	if err != nil {
+		log.Err(err).Int64("chat_id", chatID).Msg("failed to load party location")
+	}
+	reply(ctx, b, chatID, world.Describe(to, exits))
+}
+
+// mapHandler shows or edits the world map
+func mapHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	gameID, ok := chatGame(ctx, b, chatID)
+	if !ok {
+		return
+	}
+
+	args := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/map"))
+	action, rest, _ := strings.Cut(args, " ")
+	parts := strings.Split(rest, "|")
+	for len(parts) < 4 {
+		parts = append(parts, "")
+	}
+
+	var err error
+	switch strings.ToLower(action) {
+	case "":
+		showMap(ctx, b, chatID, gameID)
+		return
+	case "add":
+		var l *world.Location
+		if l, err = worldMap.AddLocation(ctx, gameID, parts[0], parts[1]); err == nil {
+			reply(ctx, b, chatID, fmt.Sprintf("Локация «%s» добавлена", l.Name))
+			return
+		}
+	case "link":
+		if err = worldMap.Connect(ctx, gameID, parts[0], parts[1], parts[2], parts[3]); err == nil {
+			reply(ctx, b, chatID, "Выход добавлен")
+			return
+		}
+	default:
+		reply(ctx, b, chatID, mapUsage)
+		return
+	}
+
+	if errors.Is(err, world.ErrExists) {
+		reply(ctx, b, chatID, "Такая локация или выход уже есть")
+		return
+	}
+	reply(ctx, b, chatID, fmt.Sprintf("Не удалось изменить карту: %s\n\n%s", err.Error(), mapUsage))
+}
+
+func showMap(ctx context.Context, b *bot.Bot, chatID int64, gameID string) {
+	locations, exits, err := worldMap.Locations(ctx, gameID)
+	if err != nil {
+		log.Err(err).Int64("chat_id", chatID).Msg("failed to load map")
+		reply(ctx, b, chatID, "Не удалось загрузить карту, попробуйте позже")
+		return
+	}
+	if len(locations) == 0 {
+		reply(ctx, b, chatID, "Карта пока пуста.\n\n"+mapUsage)
+		return
+	}
+
+	current := worldMap.LocationID(ctx, gameID)
+	var text strings.Builder
+	text.WriteString("🗺 Карта:")
+	for _, l := range locations {
+		marker := ""
+		if l.ID == current {
+			marker = " (группа здесь)"
+		}
+		fmt.Fprintf(&text, "\n\n📍 %s%s", l.Name, marker)
+		for _, exit := range exits[l.ID] {
+			fmt.Fprintf(&text, "\n- %s → %s", exit.Name, exit.ToName)
+		}
+	}
+	reply(ctx, b, chatID, text.String())
+}
+
+// chatGame returns the game of a chat, replying with an error when it cannot be loaded
+func chatGame(ctx context.Context, b *bot.Bot, chatID int64) (string, bool) {
+	gameID, err := sessions.GameID(ctx, chatID)
+	if err != nil {
+		log.Err(err).Int64("chat_id", chatID).Msg("failed to load game")
+		reply(ctx, b, chatID, "Не удалось загрузить игру, попробуйте позже")
+		return "", false
+	}
+	return gameID, true
+}
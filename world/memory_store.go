@@ -0,0 +1,134 @@
+package world
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go-llm-rpggamemaster/internal/uuid"
+)
+
+// MemoryStore keeps the world map in process memory. Maps are lost on restart.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	locations []*Location
+	exits     map[string][]Exit
+	positions map[string]string
+}
+
+// Compile-time interface check
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty in-memory world store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		exits:     make(map[string][]Exit),
+		positions: make(map[string]string),
+	}
+}
+
+// CreateLocation stores a copy of the location
+func (s *MemoryStore) CreateLocation(ctx context.Context, l *Location) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.find(l.GameID, l.Name) != nil {
+		return ErrExists
+	}
+	l.ID = uuid.New()
+	l.CreatedAt = time.Now()
+	copied := *l
+	s.locations = append(s.locations, &copied)
+	return nil
+}
+
+// FindLocation returns a copy of the location with the given name
+func (s *MemoryStore) FindLocation(ctx context.Context, gameID, name string) (*Location, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored := s.find(gameID, name)
+	if stored == nil {
+		return nil, ErrNotFound
+	}
+	copied := *stored
+	return &copied, nil
+}
+
+// GetLocation returns a copy of the location with the given ID
+func (s *MemoryStore) GetLocation(ctx context.Context, id string) (*Location, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, l := range s.locations {
+		if l.ID == id {
+			copied := *l
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ListLocations returns copies of the locations of a game
+func (s *MemoryStore) ListLocations(ctx context.Context, gameID string) ([]*Location, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var locations []*Location
+	for _, l := range s.locations {
+		if l.GameID == gameID {
+			copied := *l
+			locations = append(locations, &copied)
+		}
+	}
+	return locations, nil
+}
+
+// AddExit stores the exit
+func (s *MemoryStore) AddExit(ctx context.Context, exit Exit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.exits[exit.FromID] {
+		if strings.EqualFold(existing.Name, exit.Name) {
+			return ErrExists
+		}
+	}
+	s.exits[exit.FromID] = append(s.exits[exit.FromID], exit)
+	return nil
+}
+
+// Exits returns a copy of the exits of a location
+func (s *MemoryStore) Exits(ctx context.Context, locationID string) ([]Exit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]Exit(nil), s.exits[locationID]...), nil
+}
+
+// Position returns the party location of a game
+func (s *MemoryStore) Position(ctx context.Context, gameID string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.positions[gameID], nil
+}
+
+// SetPosition moves the party of a game
+func (s *MemoryStore) SetPosition(ctx context.Context, gameID, locationID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.positions[gameID] = locationID
+	return nil
+}
+
+func (s *MemoryStore) find(gameID, name string) *Location {
+	for _, l := range s.locations {
+		if l.GameID == gameID && strings.EqualFold(l.Name, name) {
+			return l
+		}
+	}
+	return nil
+}
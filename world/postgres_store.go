@@ -0,0 +1,174 @@
+package world
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// uniqueViolation is the PostgreSQL error code of a unique index conflict
+const uniqueViolation = "23505"
+
+// PostgresStore persists the world map in the locations and location_exits tables
+// and the party position in games.current_location_id
+type PostgresStore struct {
+	db *pgxpool.Pool
+}
+
+// Compile-time interface check
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore creates a world store backed by PostgreSQL
+func NewPostgresStore(db *pgxpool.Pool) (*PostgresStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database pool cannot be nil")
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// CreateLocation inserts a location
+func (s *PostgresStore) CreateLocation(ctx context.Context, l *Location) error {
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO locations (game_id, name, description)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, l.GameID, l.Name, l.Description).Scan(&l.ID, &l.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrExists
+	}
+	if err != nil {
+		return fmt.Errorf("creating location: %w", err)
+	}
+	return nil
+}
+
+// FindLocation reads a location by case-insensitive name
+func (s *PostgresStore) FindLocation(ctx context.Context, gameID, name string) (*Location, error) {
+	return s.scanLocation(s.db.QueryRow(ctx, `
+		SELECT id, game_id, name, COALESCE(description, ''), created_at
+		FROM locations
+		WHERE game_id = $1 AND lower(name) = lower($2)
+	`, gameID, name))
+}
+
+// GetLocation reads a location by ID
+func (s *PostgresStore) GetLocation(ctx context.Context, id string) (*Location, error) {
+	return s.scanLocation(s.db.QueryRow(ctx, `
+		SELECT id, game_id, name, COALESCE(description, ''), created_at
+		FROM locations
+		WHERE id = $1
+	`, id))
+}
+
+// ListLocations reads the locations of a game
+func (s *PostgresStore) ListLocations(ctx context.Context, gameID string) ([]*Location, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, game_id, name, COALESCE(description, ''), created_at
+		FROM locations
+		WHERE game_id = $1
+		ORDER BY created_at
+	`, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("listing locations: %w", err)
+	}
+	defer rows.Close()
+
+	var locations []*Location
+	for rows.Next() {
+		l := &Location{}
+		if err := rows.Scan(&l.ID, &l.GameID, &l.Name, &l.Description, &l.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning location: %w", err)
+		}
+		locations = append(locations, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing locations: %w", err)
+	}
+	return locations, nil
+}
+
+// AddExit inserts an exit
+func (s *PostgresStore) AddExit(ctx context.Context, exit Exit) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO location_exits (from_location_id, to_location_id, name)
+		VALUES ($1, $2, $3)
+	`, exit.FromID, exit.ToID, exit.Name)
+	if isUniqueViolation(err) {
+		return ErrExists
+	}
+	if err != nil {
+		return fmt.Errorf("creating exit: %w", err)
+	}
+	return nil
+}
+
+// Exits reads the exits of a location with the names of their destinations
+func (s *PostgresStore) Exits(ctx context.Context, locationID string) ([]Exit, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT e.from_location_id, e.to_location_id, l.name, e.name
+		FROM location_exits e
+		JOIN locations l ON l.id = e.to_location_id
+		WHERE e.from_location_id = $1
+		ORDER BY e.created_at
+	`, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("loading exits: %w", err)
+	}
+	defer rows.Close()
+
+	var exits []Exit
+	for rows.Next() {
+		var exit Exit
+		if err := rows.Scan(&exit.FromID, &exit.ToID, &exit.ToName, &exit.Name); err != nil {
+			return nil, fmt.Errorf("scanning exit: %w", err)
+		}
+		exits = append(exits, exit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("loading exits: %w", err)
+	}
+	return exits, nil
+}
+
+// Position reads the party location of a game
+func (s *PostgresStore) Position(ctx context.Context, gameID string) (string, error) {
+	var id *string
+	err := s.db.QueryRow(ctx, `SELECT current_location_id FROM games WHERE id = $1`, gameID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && id == nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("loading party position: %w", err)
+	}
+	return *id, nil
+}
+
+// SetPosition updates the party location of a game
+func (s *PostgresStore) SetPosition(ctx context.Context, gameID, locationID string) error {
+	_, err := s.db.Exec(ctx, `UPDATE games SET current_location_id = $1 WHERE id = $2`, locationID, gameID)
+	if err != nil {
+		return fmt.Errorf("saving party position: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) scanLocation(row pgx.Row) (*Location, error) {
+	l := &Location{}
+	err := row.Scan(&l.ID, &l.GameID, &l.Name, &l.Description, &l.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading location: %w", err)
+	}
+	return l, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
@@ -0,0 +1,207 @@
+package world
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Service edits the world map and moves the party along it
+type Service struct {
+	store Store
+}
+
+// NewService creates a world service
+func NewService(store Store) (*Service, error) {
+	if store == nil {
+		return nil, fmt.Errorf("world store cannot be nil")
+	}
+	return &Service{store: store}, nil
+}
+
+// AddLocation adds a location to the map. The first location of a game becomes the party position.
+func (s *Service) AddLocation(ctx context.Context, gameID, name, description string) (*Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("location name is required")
+	}
+
+	l := &Location{GameID: gameID, Name: name, Description: strings.TrimSpace(description)}
+	if err := s.store.CreateLocation(ctx, l); err != nil {
+		return nil, err
+	}
+
+	position, err := s.store.Position(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if position == "" {
+		if err := s.store.SetPosition(ctx, gameID, l.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info().
+		Str("game_id", gameID).
+		Str("location_id", l.ID).
+		Str("location", l.Name).
+		Msg("Location created")
+
+	return l, nil
+}
+
+// Connect adds an exit between two locations of a game.
+// A non-empty back name also adds the exit leading the other way.
+func (s *Service) Connect(ctx context.Context, gameID, fromName, exitName, toName, backName string) error {
+	exitName, backName = strings.TrimSpace(exitName), strings.TrimSpace(backName)
+	if exitName == "" {
+		return fmt.Errorf("exit name is required")
+	}
+
+	from, err := s.store.FindLocation(ctx, gameID, strings.TrimSpace(fromName))
+	if err != nil {
+		return fmt.Errorf("location %q: %w", fromName, err)
+	}
+	to, err := s.store.FindLocation(ctx, gameID, strings.TrimSpace(toName))
+	if err != nil {
+		return fmt.Errorf("location %q: %w", toName, err)
+	}
+	if from.ID == to.ID {
+		return fmt.Errorf("a location cannot lead to itself")
+	}
+
+	if err := s.store.AddExit(ctx, Exit{FromID: from.ID, ToID: to.ID, ToName: to.Name, Name: exitName}); err != nil {
+		return err
+	}
+	if backName != "" {
+		if err := s.store.AddExit(ctx, Exit{FromID: to.ID, ToID: from.ID, ToName: from.Name, Name: backName}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Where returns the party location with its exits
+func (s *Service) Where(ctx context.Context, gameID string) (*Location, []Exit, error) {
+	id, err := s.store.Position(ctx, gameID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if id == "" {
+		return nil, nil, ErrNoPosition
+	}
+
+	l, err := s.store.GetLocation(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	exits, err := s.store.Exits(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, exits, nil
+}
+
+// Move takes the party through an exit of its current location.
+// The exit is matched by its name or by the name of the location it leads to.
+func (s *Service) Move(ctx context.Context, gameID, exitName string) (from, to *Location, err error) {
+	from, exits, err := s.Where(ctx, gameID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exitName = strings.TrimSpace(exitName)
+	exit, ok := findExit(exits, exitName)
+	if !ok {
+		return nil, nil, fmt.Errorf("no exit %q from %q", exitName, from.Name)
+	}
+
+	to, err = s.store.GetLocation(ctx, exit.ToID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.store.SetPosition(ctx, gameID, to.ID); err != nil {
+		return nil, nil, err
+	}
+
+	log.Info().
+		Str("game_id", gameID).
+		Str("from", from.Name).
+		Str("to", to.Name).
+		Msg("Party moved")
+
+	return from, to, nil
+}
+
+// Locations returns the map of a game: every location with its exits
+func (s *Service) Locations(ctx context.Context, gameID string) ([]*Location, map[string][]Exit, error) {
+	locations, err := s.store.ListLocations(ctx, gameID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exits := make(map[string][]Exit, len(locations))
+	for _, l := range locations {
+		if exits[l.ID], err = s.store.Exits(ctx, l.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+	return locations, exits, nil
+}
+
+// LocationID returns the party location of a game or an empty string
+func (s *Service) LocationID(ctx context.Context, gameID string) string {
+	id, err := s.store.Position(ctx, gameID)
+	if err != nil {
+		log.Warn().Err(err).Str("game_id", gameID).Msg("Failed to load party position")
+		return ""
+	}
+	return id
+}
+
+// TurnContext tells the game master where the party is and where it can go
+func (s *Service) TurnContext(ctx context.Context, gameID string, userID int64) (string, error) {
+	l, exits, err := s.Where(ctx, gameID)
+	if errors.Is(err, ErrNoPosition) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return "Группа находится здесь. Не перемещай её в места, куда нет выхода.\n" + Describe(l, exits), nil
+}
+
+// Describe renders a location with its exits
+func Describe(l *Location, exits []Exit) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📍 %s", l.Name)
+	if l.Description != "" {
+		fmt.Fprintf(&b, "\n%s", l.Description)
+	}
+	if len(exits) == 0 {
+		b.WriteString("\nВыходов нет.")
+		return b.String()
+	}
+	b.WriteString("\nВыходы:")
+	for _, exit := range exits {
+		fmt.Fprintf(&b, "\n- %s → %s", exit.Name, exit.ToName)
+	}
+	return b.String()
+}
+
+func findExit(exits []Exit, name string) (Exit, bool) {
+	for _, exit := range exits {
+		if strings.EqualFold(exit.Name, name) {
+			return exit, true
+		}
+	}
+	for _, exit := range exits {
+		if strings.EqualFold(exit.ToName, name) {
+			return exit, true
+		}
+	}
+	return Exit{}, false
+}
@@ -0,0 +1,64 @@
+// Package world models the map of a game as a graph of locations joined by named exits
+// and tracks where the party currently is.
+package world
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned by a Store when a location does not exist
+	ErrNotFound = errors.New("location not found")
+
+	// ErrExists is returned by a Store when a location or exit name is already taken
+	ErrExists = errors.New("location or exit already exists")
+
+	// ErrNoPosition is returned when the party of a game has not been placed on the map yet
+	ErrNoPosition = errors.New("party has no location")
+)
+
+// Location is a place of the game world
+type Location struct {
+	ID          string
+	GameID      string
+	Name        string
+	Description string
+	CreatedAt   time.Time
+}
+
+// Exit is a one-way connection between two locations. Two-way paths are two exits.
+type Exit struct {
+	FromID string
+	ToID   string
+	ToName string
+	Name   string // How players refer to the exit, e.g. "север" or "дверь в подвал"
+}
+
+// Store persists the locations, exits and party position of games
+type Store interface {
+	// CreateLocation inserts a location and assigns its ID, or returns ErrExists
+	CreateLocation(ctx context.Context, l *Location) error
+
+	// FindLocation returns a location by case-insensitive name or ErrNotFound
+	FindLocation(ctx context.Context, gameID, name string) (*Location, error)
+
+	// GetLocation returns a location by ID or ErrNotFound
+	GetLocation(ctx context.Context, id string) (*Location, error)
+
+	// ListLocations returns the locations of a game, oldest first
+	ListLocations(ctx context.Context, gameID string) ([]*Location, error)
+
+	// AddExit connects two locations, or returns ErrExists when the exit name is taken
+	AddExit(ctx context.Context, exit Exit) error
+
+	// Exits returns the exits leading out of a location
+	Exits(ctx context.Context, locationID string) ([]Exit, error)
+
+	// Position returns the location ID of the party or an empty string
+	Position(ctx context.Context, gameID string) (string, error)
+
+	// SetPosition moves the party of a game
+	SetPosition(ctx context.Context, gameID, locationID string) error
+}
@@ -0,0 +1,118 @@
+package world
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newTestWorld builds a village with a two-way road to the forest and a one-way drop into a cave
+func newTestWorld(t *testing.T) *Service {
+	t.Helper()
+	ctx := context.Background()
+
+	s, err := NewService(NewMemoryStore())
+	if err != nil {
+		t.Fatalf("creating service: %v", err)
+	}
+	for _, name := range []string{"Деревня", "Лес", "Пещера"} {
+		if _, err := s.AddLocation(ctx, "game", name, "Описание: "+name); err != nil {
+			t.Fatalf("adding %s: %v", name, err)
+		}
+	}
+	if err := s.Connect(ctx, "game", "Деревня", "север", "Лес", "юг"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := s.Connect(ctx, "game", "лес", "обрыв", "Пещера", ""); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	return s
+}
+
+func TestService_AddLocation(t *testing.T) {
+	ctx := context.Background()
+	s := newTestWorld(t)
+
+	l, _, err := s.Where(ctx, "game")
+	if err != nil {
+		t.Fatalf("where: %v", err)
+	}
+	if l.Name != "Деревня" {
+		t.Errorf("expected the first location to be the start, got %q", l.Name)
+	}
+
+	if _, err := s.AddLocation(ctx, "game", "деревня", ""); !errors.Is(err, ErrExists) {
+		t.Errorf("expected ErrExists, got %v", err)
+	}
+	if _, err := s.AddLocation(ctx, "game", " ", ""); err == nil {
+		t.Error("expected error for an empty name")
+	}
+	if _, _, err := s.Where(ctx, "other"); !errors.Is(err, ErrNoPosition) {
+		t.Errorf("expected ErrNoPosition for a game without a map, got %v", err)
+	}
+}
+
+func TestService_Connect(t *testing.T) {
+	ctx := context.Background()
+	s := newTestWorld(t)
+
+	if err := s.Connect(ctx, "game", "Деревня", "СЕВЕР", "Пещера", ""); !errors.Is(err, ErrExists) {
+		t.Errorf("expected ErrExists for a taken exit name, got %v", err)
+	}
+	if err := s.Connect(ctx, "game", "Деревня", "вниз", "Болото", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown location, got %v", err)
+	}
+	if err := s.Connect(ctx, "game", "Лес", "кругом", "Лес", ""); err == nil {
+		t.Error("expected error for a loop")
+	}
+}
+
+func TestService_Move(t *testing.T) {
+	ctx := context.Background()
+	s := newTestWorld(t)
+
+	from, to, err := s.Move(ctx, "game", "Север")
+	if err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if from.Name != "Деревня" || to.Name != "Лес" {
+		t.Errorf("unexpected move %s -> %s", from.Name, to.Name)
+	}
+
+	if _, to, err = s.Move(ctx, "game", "пещера"); err != nil || to.Name != "Пещера" {
+		t.Fatalf("expected to move by destination name, got %v", err)
+	}
+
+	if _, _, err := s.Move(ctx, "game", "юг"); err == nil {
+		t.Error("expected the one-way drop to have no way back")
+	}
+	l, _, _ := s.Where(ctx, "game")
+	if l.Name != "Пещера" {
+		t.Errorf("expected a failed move to keep the position, got %q", l.Name)
+	}
+}
+
+func TestService_TurnContext(t *testing.T) {
+	ctx := context.Background()
+	s := newTestWorld(t)
+
+	text, err := s.TurnContext(ctx, "game", 1)
+	if err != nil {
+		t.Fatalf("turn context: %v", err)
+	}
+	for _, want := range []string{"Деревня", "Описание: Деревня", "север → Лес"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in context:\n%s", want, text)
+		}
+	}
+
+	if text, _ := s.TurnContext(ctx, "other", 1); text != "" {
+		t.Errorf("expected no context without a map, got %q", text)
+	}
+
+	l, _, _ := s.Where(ctx, "game")
+	if id := s.LocationID(ctx, "game"); id != l.ID {
+		t.Errorf("expected location ID %q, got %q", l.ID, id)
+	}
+}